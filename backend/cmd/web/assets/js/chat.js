@@ -0,0 +1,56 @@
+// Receives the live updates of the chat page as Server-Sent Events whose
+// data are HTML fragments rendered by the server.
+(function () {
+  "use strict";
+
+  const messages = document.getElementById("messages");
+  const online = document.getElementById("online");
+  const source = new EventSource(messages.dataset.events);
+
+  function scrollToBottom() {
+    messages.scrollTop = messages.scrollHeight;
+  }
+
+  function fragment(html) {
+    const template = document.createElement("template");
+    template.innerHTML = html;
+    return template.content.firstElementChild;
+  }
+
+  source.addEventListener("message", function (e) {
+    const item = fragment(e.data);
+    if (item && !document.getElementById(item.id)) {
+      messages.appendChild(item);
+      scrollToBottom();
+    }
+  });
+
+  source.addEventListener("message_updated", function (e) {
+    const item = fragment(e.data);
+    const current = item && document.getElementById(item.id);
+    if (current) {
+      current.replaceWith(item);
+    }
+  });
+
+  source.addEventListener("online_users", function (e) {
+    online.innerHTML = e.data;
+  });
+
+  // The server ends the stream when the viewer is removed from the
+  // conversation; reconnecting would be refused.
+  source.addEventListener("removed", function (e) {
+    messages.appendChild(fragment(e.data));
+    scrollToBottom();
+    source.close();
+  });
+
+  // Slash command replies are swapped into the list by htmx.
+  document.body.addEventListener("htmx:afterSwap", function (e) {
+    if (e.detail.target === messages) {
+      scrollToBottom();
+    }
+  });
+
+  scrollToBottom();
+})();
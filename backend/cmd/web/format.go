@@ -0,0 +1,56 @@
+package web
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"backend/internal/domain/conversation"
+)
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04")
+}
+
+// formatID formats an optional id, or "-" when it is nil.
+func formatID(id *int64) string {
+	if id == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*id, 10)
+}
+
+// sortedKeys returns the keys of m in order, so maps render stably.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func banURL(userID int64) string {
+	return "/admin/users/" + strconv.FormatInt(userID, 10) + "/ban"
+}
+
+func resolveURL(flagID int64) string {
+	return "/admin/queue/" + strconv.FormatInt(flagID, 10) + "/resolve"
+}
+
+func chatURL(conversationID int64) string {
+	return "/chat/" + strconv.FormatInt(conversationID, 10)
+}
+
+func messageElementID(messageID int64) string {
+	return "message-" + strconv.FormatInt(messageID, 10)
+}
+
+// conversationName names a conversation in lists; direct conversations may
+// have no name.
+func conversationName(c conversation.Conversation) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return "Conversation " + strconv.FormatInt(c.ID, 10)
+}
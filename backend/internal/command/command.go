@@ -0,0 +1,166 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/webhook"
+)
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrCommandFailed  = errors.New("command failed")
+)
+
+var (
+	commandPattern = regexp.MustCompile(`(?s)^/([a-z][a-z0-9_-]{0,31})(?:\s+(.*))?$`)
+	namePattern    = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+)
+
+// ValidName reports whether name can be used as a command name.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Parse splits content of the form "/name args" into the command name and
+// its arguments. Content starting with "//" is an escaped message, not a
+// command; see Unescape.
+func Parse(content string) (name, args string, ok bool) {
+	m := commandPattern.FindStringSubmatch(strings.TrimSpace(content))
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], strings.TrimSpace(m[2]), true
+}
+
+// Unescape turns a leading "//" into "/", so users can send messages that
+// start with a slash.
+func Unescape(content string) string {
+	if strings.HasPrefix(content, "//") {
+		return content[1:]
+	}
+	return content
+}
+
+// Invocation is a command typed by a user in a conversation.
+type Invocation struct {
+	Name           string `json:"command"`
+	Args           string `json:"text"`
+	UserID         int64  `json:"user_id"`
+	Username       string `json:"username"`
+	ConversationID int64  `json:"conversation_id"`
+}
+
+// Response is the result of a command. It is shown only to the issuer
+// unless InChannel is set, in which case Text is posted to the conversation
+// as a message from the issuer.
+type Response struct {
+	Text      string
+	InChannel bool
+}
+
+// Reply returns an ephemeral response.
+func Reply(format string, args ...interface{}) *Response {
+	return &Response{Text: fmt.Sprintf(format, args...)}
+}
+
+type Handler interface {
+	Run(ctx context.Context, inv *Invocation) (*Response, error)
+}
+
+type HandlerFunc func(ctx context.Context, inv *Invocation) (*Response, error)
+
+func (f HandlerFunc) Run(ctx context.Context, inv *Invocation) (*Response, error) {
+	return f(ctx, inv)
+}
+
+type entry struct {
+	usage   string
+	handler Handler
+}
+
+// Registry holds the built-in commands.
+type Registry struct {
+	commands map[string]entry
+}
+
+func NewRegistry() *Registry {
+	return &Registry{commands: map[string]entry{}}
+}
+
+// Register adds a command. usage is shown by /help.
+func (r *Registry) Register(name, usage string, h Handler) {
+	r.commands[name] = entry{usage: usage, handler: h}
+}
+
+// Lookup returns the handler of a built-in command.
+func (r *Registry) Lookup(name string) (Handler, bool) {
+	e, ok := r.commands[name]
+	return e.handler, ok
+}
+
+// Usage lists the usage lines of all commands, sorted by name.
+func (r *Registry) Usage() []string {
+	lines := make([]string, 0, len(r.commands))
+	for _, e := range r.commands {
+		lines = append(lines, e.usage)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// External runs a command by POSTing the invocation as JSON to an HTTP
+// endpoint, signed like outgoing webhooks. The endpoint answers with
+// {"text": "...", "response_type": "ephemeral" | "in_channel"}.
+type External struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+type externalResponse struct {
+	Text         string `json:"text"`
+	ResponseType string `json:"response_type"`
+}
+
+func (e *External) Run(ctx context.Context, inv *Invocation) (*Response, error) {
+	body, err := json.Marshal(inv)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ChatVui-Command/1.0")
+	req.Header.Set(webhook.HeaderTimestamp, timestamp)
+	req.Header.Set(webhook.HeaderSignature, "sha256="+webhook.Sign(e.Secret, timestamp, body))
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCommandFailed, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%w: endpoint responded with status %d", ErrCommandFailed, resp.StatusCode)
+	}
+
+	var out externalResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrCommandFailed, err)
+	}
+	return &Response{Text: out.Text, InChannel: out.ResponseType == "in_channel"}, nil
+}
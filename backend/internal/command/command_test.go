@@ -0,0 +1,121 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/internal/webhook"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		content    string
+		name, args string
+		ok         bool
+	}{
+		{"/me waves", "me", "waves", true},
+		{"/topic", "topic", "", true},
+		{"  /invite @an @binh  ", "invite", "@an @binh", true},
+		{"/topic line one\nline two", "topic", "line one\nline two", true},
+		{"hello /me", "", "", false},
+		{"//me is not a command", "", "", false},
+		{"/usr/bin is a path", "", "", false},
+		{"/ spaced", "", "", false},
+		{"/Me shouting", "", "", false},
+	}
+	for _, tt := range tests {
+		name, args, ok := Parse(tt.content)
+		if name != tt.name || args != tt.args || ok != tt.ok {
+			t.Errorf("Parse(%q): got %q, %q, %v want %q, %q, %v", tt.content, name, args, ok, tt.name, tt.args, tt.ok)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := map[string]string{
+		"//me":      "/me",
+		"/me":       "/me",
+		"plain":     "plain",
+		"///triple": "//triple",
+	}
+	for in, want := range tests {
+		if got := Unescape(in); got != want {
+			t.Errorf("Unescape(%q): got %q want %q", in, got, want)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Register("me", "/me <action>", HandlerFunc(func(ctx context.Context, inv *Invocation) (*Response, error) {
+		return &Response{Text: inv.Username + " " + inv.Args, InChannel: true}, nil
+	}))
+	r.Register("help", "/help", HandlerFunc(func(ctx context.Context, inv *Invocation) (*Response, error) {
+		return Reply("help"), nil
+	}))
+
+	h, ok := r.Lookup("me")
+	if !ok {
+		t.Fatal("Lookup(me): not found")
+	}
+	resp, err := h.Run(context.Background(), &Invocation{Name: "me", Args: "waves", Username: "an"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "an waves" || !resp.InChannel {
+		t.Errorf("response: got %+v", resp)
+	}
+	if _, ok := r.Lookup("topic"); ok {
+		t.Error("Lookup(topic): got found want not found")
+	}
+	if got := strings.Join(r.Usage(), ","); got != "/help,/me <action>" {
+		t.Errorf("usage: got %q", got)
+	}
+}
+
+func TestExternal(t *testing.T) {
+	var got Invocation
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading body: %v", err)
+		}
+		want := "sha256=" + webhook.Sign("secret", r.Header.Get(webhook.HeaderTimestamp), body)
+		if sig := r.Header.Get(webhook.HeaderSignature); sig != want {
+			t.Errorf("signature: got %q want %q", sig, want)
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("decoding invocation: %v", err)
+		}
+		if got.Args == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"text": "deployed", "response_type": "in_channel"}`))
+	}))
+	defer srv.Close()
+
+	e := &External{URL: srv.URL, Secret: "secret", Client: srv.Client()}
+	inv := &Invocation{Name: "deploy", Args: "prod", UserID: 7, Username: "an", ConversationID: 3}
+	resp, err := e.Run(context.Background(), inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != *inv {
+		t.Errorf("invocation: got %+v want %+v", got, *inv)
+	}
+	if resp.Text != "deployed" || !resp.InChannel {
+		t.Errorf("response: got %+v", resp)
+	}
+
+	inv.Args = "fail"
+	if _, err := e.Run(context.Background(), inv); !errors.Is(err, ErrCommandFailed) {
+		t.Errorf("failing endpoint: got %v want %v", err, ErrCommandFailed)
+	}
+}
@@ -26,6 +26,12 @@ type Service interface {
 
 	// GetRedisClient returns the Redis client associated with the service.
 	GetRedisClient() *redis.Client
+
+	// GetDB returns the MySQL connection pool associated with the service.
+	GetDB() *sql.DB
+
+	// Migrate applies any pending schema migrations.
+	Migrate(ctx context.Context) error
 }
 
 type service struct {
@@ -49,7 +55,7 @@ func New() Service {
 	}
 
 	// MySQL Connection
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", username, password, host, port, dbname))
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", username, password, host, port, dbname))
 	if err != nil {
 		// This will not be a connection error, but a DSN parse error or
 		// another initialization error.
@@ -140,3 +146,8 @@ func (s *service) Close() error {
 func (s *service) GetRedisClient() *redis.Client {
 	return s.redis
 }
+
+// GetDB returns the MySQL connection pool associated with the service.
+func (s *service) GetDB() *sql.DB {
+	return s.db
+}
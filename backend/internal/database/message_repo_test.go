@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"backend/internal/domain/message"
+	"backend/internal/infratructure/repositories"
+)
+
+// migratedDB returns the test database with the schema applied.
+func migratedDB(t *testing.T) *sql.DB {
+	t.Helper()
+	srv := New()
+	if err := srv.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return srv.GetDB()
+}
+
+// insert runs an INSERT and returns the id of the new row.
+func insert(t *testing.T, db *sql.DB, query string, args ...interface{}) int64 {
+	t.Helper()
+	res, err := db.ExecContext(context.Background(), query, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestMessageRepoShadowedReply(t *testing.T) {
+	ctx := context.Background()
+	db := migratedDB(t)
+	userID := insert(t, db, "INSERT INTO users (username, password_hash) VALUES ('shadowed-reply', 'x')")
+	conversationID := insert(t, db, "INSERT INTO conversations (name, is_group) VALUES ('threads', TRUE)")
+
+	repo := repositories.NewMySQLMessageRepo(db)
+	parent := &message.Message{SenderID: userID, ConversationID: conversationID, Content: "parent"}
+	if err := repo.Create(ctx, parent, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	reply := &message.Message{SenderID: userID, ConversationID: conversationID, Content: "reply", ParentID: &parent.ID, Shadowed: true}
+	if err := repo.Create(ctx, reply, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.Get(ctx, parent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ReplyCount != 0 || got.LastReplyAt != nil {
+		t.Errorf("shadowed reply updated the parent: reply_count %d, last_reply_at %v", got.ReplyCount, got.LastReplyAt)
+	}
+
+	reply = &message.Message{SenderID: userID, ConversationID: conversationID, Content: "reply", ParentID: &parent.ID}
+	if err := repo.Create(ctx, reply, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = repo.Get(ctx, parent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.ReplyCount != 1 || got.LastReplyAt == nil {
+		t.Errorf("reply did not update the parent: reply_count %d, last_reply_at %v", got.ReplyCount, got.LastReplyAt)
+	}
+}
+
+func TestMessageRepoHidesBlockedSenders(t *testing.T) {
+	ctx := context.Background()
+	db := migratedDB(t)
+	viewer := insert(t, db, "INSERT INTO users (username, password_hash) VALUES ('blocks-viewer', 'x')")
+	blocked := insert(t, db, "INSERT INTO users (username, password_hash) VALUES ('blocks-blocked', 'x')")
+	group := insert(t, db, "INSERT INTO conversations (name, is_group) VALUES ('blocks', TRUE)")
+	direct := insert(t, db, "INSERT INTO conversations (is_group) VALUES (FALSE)")
+	insert(t, db, "INSERT INTO user_blocks (blocker_id, blocked_id) VALUES (?, ?)", viewer, blocked)
+
+	repo := repositories.NewMySQLMessageRepo(db)
+	for _, m := range []*message.Message{
+		{SenderID: viewer, ConversationID: group, Content: "mine"},
+		{SenderID: blocked, ConversationID: group, Content: "blocked"},
+		{SenderID: blocked, ConversationID: direct, Content: "blocked direct"},
+	} {
+		if err := repo.Create(ctx, m, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	contents := func(conversationID, viewerID int64) []string {
+		t.Helper()
+		messages, err := repo.ListByConversation(ctx, conversationID, viewerID, 50, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, m := range messages {
+			out = append(out, m.Content)
+		}
+		return out
+	}
+
+	if got := contents(group, viewer); len(got) != 1 || got[0] != "mine" {
+		t.Errorf("group as blocker: got %q want only their own message", got)
+	}
+	if got := contents(group, blocked); len(got) != 2 {
+		t.Errorf("group as blocked user: got %q want both messages", got)
+	}
+	// Blocking stops new direct messages but keeps the history.
+	if got := contents(direct, viewer); len(got) != 1 {
+		t.Errorf("direct conversation: got %q want the earlier message", got)
+	}
+}
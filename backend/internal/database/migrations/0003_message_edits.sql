@@ -0,0 +1,12 @@
+ALTER TABLE messages
+  ADD COLUMN edited_at TIMESTAMP NULL,
+  ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE TABLE IF NOT EXISTS message_edits (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  message_id INT NOT NULL,
+  content TEXT NOT NULL,
+  edited_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (message_id) REFERENCES messages(id),
+  INDEX idx_message_edits_message (message_id)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS message_reactions (
+  message_id INT NOT NULL,
+  user_id INT NOT NULL,
+  emoji VARCHAR(64) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (message_id, user_id, emoji),
+  FOREIGN KEY (message_id) REFERENCES messages(id),
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
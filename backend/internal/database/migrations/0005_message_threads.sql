@@ -0,0 +1,6 @@
+ALTER TABLE messages
+  ADD COLUMN parent_id INT NULL,
+  ADD COLUMN reply_count INT NOT NULL DEFAULT 0,
+  ADD COLUMN last_reply_at TIMESTAMP NULL,
+  ADD CONSTRAINT fk_messages_parent FOREIGN KEY (parent_id) REFERENCES messages(id),
+  ADD INDEX idx_messages_parent_created (parent_id, created_at);
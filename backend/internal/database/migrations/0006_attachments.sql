@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS attachments (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  message_id INT NULL,
+  uploader_id INT NOT NULL,
+  storage_key VARCHAR(255) NOT NULL UNIQUE,
+  filename VARCHAR(255) NOT NULL,
+  content_type VARCHAR(100) NOT NULL,
+  size BIGINT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (message_id) REFERENCES messages(id),
+  FOREIGN KEY (uploader_id) REFERENCES users(id),
+  INDEX idx_attachments_message (message_id)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE attachments
+  ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'ready',
+  ADD COLUMN width INT NULL,
+  ADD COLUMN height INT NULL,
+  ADD COLUMN blurhash VARCHAR(64) NULL,
+  ADD COLUMN thumbnails JSON NULL;
@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN link_previews JSON NULL;
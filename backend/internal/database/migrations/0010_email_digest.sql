@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS email_preferences (
+  user_id INT PRIMARY KEY,
+  digest_frequency VARCHAR(10) NOT NULL DEFAULT 'daily',
+  last_digest_at TIMESTAMP NULL,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id)
+);
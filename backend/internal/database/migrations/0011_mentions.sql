@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS mentions (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  message_id INT NOT NULL,
+  conversation_id INT NOT NULL,
+  user_id INT NOT NULL,
+  sender_id INT NOT NULL,
+  kind VARCHAR(10) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uq_mentions_message_user (message_id, user_id),
+  FOREIGN KEY (message_id) REFERENCES messages(id),
+  FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+  FOREIGN KEY (user_id) REFERENCES users(id),
+  INDEX idx_mentions_user_created (user_id, created_at)
+);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  owner_id INT NOT NULL,
+  conversation_id INT NULL,
+  url VARCHAR(2048) NOT NULL,
+  secret VARCHAR(64) NOT NULL,
+  events VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (owner_id) REFERENCES users(id),
+  FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+  INDEX idx_webhooks_conversation (conversation_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  webhook_id INT NOT NULL,
+  event VARCHAR(50) NOT NULL,
+  payload JSON NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  last_status INT NULL,
+  last_error VARCHAR(255) NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  delivered_at TIMESTAMP NULL,
+  FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+  INDEX idx_webhook_deliveries_due (status, next_attempt_at),
+  INDEX idx_webhook_deliveries_webhook (webhook_id, created_at)
+);
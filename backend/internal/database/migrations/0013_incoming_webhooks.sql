@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE messages ADD COLUMN embeds JSON NULL;
+
+CREATE TABLE IF NOT EXISTS incoming_webhooks (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  conversation_id INT NOT NULL,
+  creator_id INT NOT NULL,
+  bot_user_id INT NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  revoked_at TIMESTAMP NULL,
+  FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+  FOREIGN KEY (creator_id) REFERENCES users(id),
+  FOREIGN KEY (bot_user_id) REFERENCES users(id)
+);
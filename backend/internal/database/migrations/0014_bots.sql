@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS bots (
+  user_id INT PRIMARY KEY,
+  owner_id INT NOT NULL,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  events VARCHAR(512) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id),
+  FOREIGN KEY (owner_id) REFERENCES users(id)
+);
+
+ALTER TABLE messages ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE messages m JOIN users u ON u.id = m.sender_id SET m.is_bot = u.is_bot WHERE u.is_bot;
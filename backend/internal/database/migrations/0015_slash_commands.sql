@@ -0,0 +1,14 @@
+ALTER TABLE conversations ADD COLUMN topic VARCHAR(250) NULL;
+
+CREATE TABLE IF NOT EXISTS slash_commands (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  conversation_id INT NOT NULL,
+  creator_id INT NOT NULL,
+  name VARCHAR(32) NOT NULL,
+  url VARCHAR(2048) NOT NULL,
+  secret VARCHAR(64) NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+  FOREIGN KEY (creator_id) REFERENCES users(id),
+  UNIQUE KEY uq_slash_commands_name (conversation_id, name)
+);
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS conversation_filters (
+  conversation_id INT NOT NULL,
+  filter VARCHAR(20) NOT NULL,
+  action VARCHAR(10) NOT NULL,
+  PRIMARY KEY (conversation_id, filter),
+  FOREIGN KEY (conversation_id) REFERENCES conversations(id)
+);
+
+CREATE TABLE IF NOT EXISTS moderation_flags (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  conversation_id INT NULL,
+  message_id INT NULL,
+  user_id INT NOT NULL,
+  source VARCHAR(20) NOT NULL,
+  reason VARCHAR(255) NOT NULL,
+  status VARCHAR(10) NOT NULL DEFAULT 'pending',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  resolved_by INT NULL,
+  resolved_at TIMESTAMP NULL,
+  FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+  FOREIGN KEY (message_id) REFERENCES messages(id),
+  FOREIGN KEY (user_id) REFERENCES users(id),
+  FOREIGN KEY (resolved_by) REFERENCES users(id),
+  INDEX idx_moderation_flags_status (status, created_at)
+);
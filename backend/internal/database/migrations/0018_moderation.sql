@@ -0,0 +1,34 @@
+ALTER TABLE moderation_flags
+  ADD COLUMN reporter_id INT NULL AFTER user_id,
+  ADD CONSTRAINT fk_moderation_flags_reporter FOREIGN KEY (reporter_id) REFERENCES users(id),
+  ADD INDEX idx_moderation_flags_conversation (conversation_id, status);
+
+CREATE TABLE IF NOT EXISTS conversation_sanctions (
+  conversation_id INT NOT NULL,
+  user_id INT NOT NULL,
+  kind VARCHAR(10) NOT NULL,
+  reason VARCHAR(255) NOT NULL DEFAULT '',
+  expires_at TIMESTAMP NULL,
+  created_by INT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (conversation_id, user_id, kind),
+  FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+  FOREIGN KEY (user_id) REFERENCES users(id),
+  FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS moderation_audit_log (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  conversation_id INT NULL,
+  actor_id INT NOT NULL,
+  action VARCHAR(20) NOT NULL,
+  target_user_id INT NULL,
+  message_id INT NULL,
+  flag_id INT NULL,
+  reason VARCHAR(255) NOT NULL DEFAULT '',
+  expires_at TIMESTAMP NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+  FOREIGN KEY (actor_id) REFERENCES users(id),
+  INDEX idx_moderation_audit_log_conversation (conversation_id, created_at)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE conversations
+  ADD COLUMN slow_mode_seconds INT NOT NULL DEFAULT 0,
+  ADD COLUMN announcement_only BOOLEAN NOT NULL DEFAULT FALSE;
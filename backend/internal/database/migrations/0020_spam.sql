@@ -0,0 +1,3 @@
+ALTER TABLE messages ADD COLUMN shadowed BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE moderation_flags ADD INDEX idx_moderation_flags_source (source, status);
@@ -0,0 +1,3 @@
+ALTER TABLE conversation_users ADD COLUMN last_read_at TIMESTAMP NULL;
+
+ALTER TABLE email_preferences ALTER COLUMN digest_frequency SET DEFAULT 'off';
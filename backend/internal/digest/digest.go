@@ -0,0 +1,221 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/domain/digest"
+	"backend/internal/mail"
+)
+
+// Options configures the digest job.
+type Options struct {
+	// Delay is how old an unread message must be before it is included, so
+	// messages the user reads right away are not emailed.
+	Delay time.Duration
+	// MaxItems caps the number of messages in one email.
+	MaxItems int
+	// PublicURL is the base URL of the API, used for unsubscribe links.
+	PublicURL string
+	// AppURL links to the chat client; optional.
+	AppURL string
+	// SigningKey signs unsubscribe links.
+	SigningKey []byte
+}
+
+// Service periodically emails users a digest of their unread messages.
+type Service struct {
+	repo   digest.Repository
+	mailer mail.Mailer
+	opts   Options
+	now    func() time.Time
+}
+
+func NewService(repo digest.Repository, mailer mail.Mailer, opts Options) *Service {
+	if opts.MaxItems <= 0 {
+		opts.MaxItems = 50
+	}
+	return &Service{repo: repo, mailer: mailer, opts: opts, now: time.Now}
+}
+
+// Run sends due digests every interval until ctx is cancelled.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RunOnce(ctx); err != nil {
+			log.Printf("error sending email digests: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends a digest to every recipient that is due.
+func (s *Service) RunOnce(ctx context.Context) error {
+	recipients, err := s.repo.Recipients(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	for i := range recipients {
+		r := &recipients[i]
+		if !r.Due(now) {
+			continue
+		}
+		if err := s.send(ctx, r, now); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("error sending email digest to user %d: %v", r.UserID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) send(ctx context.Context, r *digest.Recipient, now time.Time) error {
+	cutoff := now.Add(-s.opts.Delay)
+	since := cutoff.Add(-r.Frequency.Interval())
+	if r.LastCutoff != nil {
+		since = *r.LastCutoff
+	}
+
+	// Fetch one more than fits so the email can say how many were left out.
+	items, err := s.repo.Unread(ctx, r.UserID, since, cutoff, s.opts.MaxItems+1)
+	if err != nil {
+		return err
+	}
+	if len(items) > 0 {
+		d := s.build(r, items)
+		var html bytes.Buffer
+		if err := Email(d).Render(ctx, &html); err != nil {
+			return err
+		}
+		err := s.mailer.Send(ctx, &mail.Message{
+			To:      r.Email,
+			Subject: d.Subject(),
+			HTML:    html.String(),
+			Text:    d.Text(),
+			Headers: map[string]string{
+				"List-Unsubscribe":      "<" + d.UnsubscribeURL + ">",
+				"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return s.repo.MarkSent(ctx, r.UserID, cutoff)
+}
+
+// Digest is the content of one digest email.
+type Digest struct {
+	Username      string
+	Frequency     digest.Frequency
+	Conversations []Group
+	Total         int
+	// Truncated is set when more unread messages exist than were listed.
+	Truncated      bool
+	AppURL         string
+	UnsubscribeURL string
+}
+
+// Group lists the unread messages of one conversation.
+type Group struct {
+	Name  string
+	Items []digest.Item
+}
+
+func (d *Digest) Subject() string {
+	switch {
+	case d.Truncated:
+		return fmt.Sprintf("You have %d+ unread messages", d.Total)
+	case d.Total == 1:
+		return "You have 1 unread message"
+	}
+	return fmt.Sprintf("You have %d unread messages", d.Total)
+}
+
+// Text renders the plain text alternative of the email.
+func (d *Digest) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\n%s while you were away.\n", d.Username, d.Subject())
+	for _, g := range d.Conversations {
+		fmt.Fprintf(&b, "\n%s\n", g.Name)
+		for _, it := range g.Items {
+			fmt.Fprintf(&b, "  %s: %s (%s)\n", it.SenderName, it.Content, it.CreatedAt.Format("Jan 2 15:04"))
+		}
+	}
+	if d.Truncated {
+		b.WriteString("\nMore messages are waiting for you.\n")
+	}
+	if d.AppURL != "" {
+		fmt.Fprintf(&b, "\nOpen chat: %s\n", d.AppURL)
+	}
+	fmt.Fprintf(&b, "\nUnsubscribe: %s\n", d.UnsubscribeURL)
+	return b.String()
+}
+
+func (s *Service) build(r *digest.Recipient, items []digest.Item) *Digest {
+	d := &Digest{
+		Username:       r.Username,
+		Frequency:      r.Frequency,
+		AppURL:         s.opts.AppURL,
+		UnsubscribeURL: s.UnsubscribeURL(r.UserID),
+	}
+
+	if len(items) > s.opts.MaxItems {
+		items = items[:s.opts.MaxItems]
+		d.Truncated = true
+	}
+	d.Total = len(items)
+
+	index := map[int64]int{}
+	for _, it := range items {
+		i, ok := index[it.ConversationID]
+		if !ok {
+			name := it.ConversationName
+			if name == "" {
+				name = it.SenderName
+			}
+			i = len(d.Conversations)
+			index[it.ConversationID] = i
+			d.Conversations = append(d.Conversations, Group{Name: name})
+		}
+		d.Conversations[i].Items = append(d.Conversations[i].Items, it)
+	}
+	return d
+}
+
+// UnsubscribeURL returns a signed link that turns off digests for the user
+// without requiring a login.
+func (s *Service) UnsubscribeURL(userID int64) string {
+	q := url.Values{}
+	q.Set("uid", strconv.FormatInt(userID, 10))
+	q.Set("token", s.unsubscribeToken(userID))
+	return strings.TrimRight(s.opts.PublicURL, "/") + "/api/digest/unsubscribe?" + q.Encode()
+}
+
+// VerifyUnsubscribe reports whether token was issued for userID.
+func (s *Service) VerifyUnsubscribe(userID int64, token string) bool {
+	return hmac.Equal([]byte(token), []byte(s.unsubscribeToken(userID)))
+}
+
+func (s *Service) unsubscribeToken(userID int64) string {
+	mac := hmac.New(sha256.New, s.opts.SigningKey)
+	fmt.Fprintf(mac, "unsubscribe:%d", userID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,157 @@
+package digest
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/domain/digest"
+	"backend/internal/mail"
+)
+
+type fakeRepo struct {
+	recipients []digest.Recipient
+	items      map[int64][]digest.Item
+	queries    map[int64][2]time.Time
+	sent       map[int64]time.Time
+}
+
+func (f *fakeRepo) Frequency(ctx context.Context, userID int64) (digest.Frequency, error) {
+	return digest.DefaultFrequency, nil
+}
+
+func (f *fakeRepo) SetFrequency(ctx context.Context, userID int64, fr digest.Frequency) error {
+	return nil
+}
+
+func (f *fakeRepo) Recipients(ctx context.Context) ([]digest.Recipient, error) {
+	return f.recipients, nil
+}
+
+func (f *fakeRepo) Unread(ctx context.Context, userID int64, since, until time.Time, limit int) ([]digest.Item, error) {
+	f.queries[userID] = [2]time.Time{since, until}
+	items := f.items[userID]
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+func (f *fakeRepo) MarkSent(ctx context.Context, userID int64, cutoff time.Time) error {
+	f.sent[userID] = cutoff
+	return nil
+}
+
+type fakeMailer struct {
+	messages []*mail.Message
+}
+
+func (f *fakeMailer) Send(ctx context.Context, msg *mail.Message) error {
+	f.messages = append(f.messages, msg)
+	return nil
+}
+
+func TestRunOnce(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-10 * time.Minute)
+	stale := now.Add(-2 * time.Hour)
+
+	repo := &fakeRepo{
+		recipients: []digest.Recipient{
+			{UserID: 1, Username: "an", Email: "an@example.com", Frequency: digest.Hourly, LastCutoff: &stale},
+			{UserID: 2, Username: "binh", Email: "binh@example.com", Frequency: digest.Hourly, LastCutoff: &recent},
+			{UserID: 3, Username: "chi", Email: "chi@example.com", Frequency: digest.Daily},
+		},
+		items: map[int64][]digest.Item{
+			1: {
+				{MessageID: 1, ConversationID: 5, ConversationName: "team", SenderName: "binh", Content: "<b>hi</b>", CreatedAt: stale.Add(time.Minute)},
+				{MessageID: 2, ConversationID: 6, SenderName: "chi", Content: "lunch?", CreatedAt: stale.Add(2 * time.Minute)},
+				{MessageID: 3, ConversationID: 5, ConversationName: "team", SenderName: "chi", Content: "ok", CreatedAt: stale.Add(3 * time.Minute)},
+			},
+		},
+		queries: map[int64][2]time.Time{},
+		sent:    map[int64]time.Time{},
+	}
+	mailer := &fakeMailer{}
+	svc := NewService(repo, mailer, Options{
+		Delay:      30 * time.Minute,
+		MaxItems:   2,
+		PublicURL:  "https://api.example.com/",
+		SigningKey: []byte("secret"),
+	})
+	svc.now = func() time.Time { return now }
+
+	if err := svc.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	cutoff := now.Add(-30 * time.Minute)
+	if q := repo.queries[1]; !q[0].Equal(stale) || !q[1].Equal(cutoff) {
+		t.Errorf("user 1 window: got %v want [%v %v]", q, stale, cutoff)
+	}
+	if _, ok := repo.queries[2]; ok {
+		t.Errorf("user 2 is not due but was queried")
+	}
+	if q := repo.queries[3]; !q[0].Equal(cutoff.Add(-24 * time.Hour)) {
+		t.Errorf("user 3 first digest starts at %v want %v", q[0], cutoff.Add(-24*time.Hour))
+	}
+	// User 3 had nothing unread: no email, but the window still advances.
+	if got := repo.sent[3]; !got.Equal(cutoff) {
+		t.Errorf("user 3 cutoff: got %v want %v", got, cutoff)
+	}
+
+	if len(mailer.messages) != 1 {
+		t.Fatalf("emails sent: got %d want 1", len(mailer.messages))
+	}
+	msg := mailer.messages[0]
+	if msg.To != "an@example.com" {
+		t.Errorf("To: got %q", msg.To)
+	}
+	if msg.Subject != "You have 2+ unread messages" {
+		t.Errorf("Subject: got %q", msg.Subject)
+	}
+	if !strings.Contains(msg.HTML, "&lt;b&gt;hi&lt;/b&gt;") || !strings.Contains(msg.HTML, "lunch?") {
+		t.Errorf("HTML does not list the escaped messages: %s", msg.HTML)
+	}
+	if strings.Contains(msg.HTML, ">ok<") || strings.Contains(msg.Text, ": ok") {
+		t.Errorf("message beyond MaxItems was included")
+	}
+	if !strings.Contains(msg.Text, "team\n  binh: <b>hi</b>") {
+		t.Errorf("Text: got %q", msg.Text)
+	}
+
+	unsubscribe := strings.Trim(msg.Headers["List-Unsubscribe"], "<>")
+	if !strings.HasPrefix(unsubscribe, "https://api.example.com/api/digest/unsubscribe?") {
+		t.Fatalf("List-Unsubscribe: got %q", unsubscribe)
+	}
+	u, _ := url.Parse(unsubscribe)
+	uid, _ := strconv.ParseInt(u.Query().Get("uid"), 10, 64)
+	if uid != 1 || !svc.VerifyUnsubscribe(uid, u.Query().Get("token")) {
+		t.Errorf("unsubscribe link does not verify: %s", unsubscribe)
+	}
+	if svc.VerifyUnsubscribe(2, u.Query().Get("token")) {
+		t.Errorf("unsubscribe token verified for another user")
+	}
+}
+
+func TestRecipientDue(t *testing.T) {
+	now := time.Now()
+	last := now.Add(-23 * time.Hour)
+	tests := []struct {
+		r    digest.Recipient
+		want bool
+	}{
+		{digest.Recipient{Frequency: digest.Daily}, true},
+		{digest.Recipient{Frequency: digest.Daily, LastCutoff: &last}, false},
+		{digest.Recipient{Frequency: digest.Hourly, LastCutoff: &last}, true},
+		{digest.Recipient{Frequency: digest.Off}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Due(now); got != tt.want {
+			t.Errorf("Due(%s, %v): got %v want %v", tt.r.Frequency, tt.r.LastCutoff, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend/internal/domain/user"
+)
+
+// TokenPrefix marks bot API tokens so they can be told apart from user
+// session tokens.
+const TokenPrefix = "bot_"
+
+var (
+	ErrNotFound        = errors.New("bot not found")
+	ErrInvalidToken    = errors.New("invalid bot token")
+	ErrInvalidUsername = errors.New("bot username must be 3-50 letters, digits or underscores")
+	ErrUsernameTaken   = errors.New("username is already taken")
+)
+
+// ValidUsername reports whether name can be used as a bot's username. Bots
+// follow the same rules as users.
+func ValidUsername(name string) bool {
+	return user.ValidUsername(name)
+}
+
+// Bot is a user account driven by a program through an API token. Bots
+// receive only the websocket events they subscribed to.
+type Bot struct {
+	UserID    int64     `json:"user_id"`
+	OwnerID   int64     `json:"owner_id"`
+	Username  string    `json:"username"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Token is only set when the bot is created or its token is rotated;
+	// just its hash is stored.
+	Token string `json:"token,omitempty"`
+}
+
+type Repository interface {
+	// Create stores a bot and its user account, filling in UserID.
+	Create(ctx context.Context, b *Bot, tokenHash string) error
+
+	// Get returns a bot by its user id, or ErrNotFound.
+	Get(ctx context.Context, userID int64) (*Bot, error)
+
+	// GetByTokenHash returns the bot whose token hashes to tokenHash, or
+	// ErrInvalidToken.
+	GetByTokenHash(ctx context.Context, tokenHash string) (*Bot, error)
+
+	ListByOwner(ctx context.Context, ownerID int64) ([]Bot, error)
+
+	// SetEvents replaces the bot's event subscriptions.
+	SetEvents(ctx context.Context, userID int64, events []string) error
+
+	// RotateToken replaces the bot's token, invalidating the old one.
+	RotateToken(ctx context.Context, userID int64, tokenHash string) error
+
+	// Delete revokes the bot's token. Its user account and messages stay.
+	Delete(ctx context.Context, userID int64) error
+}
@@ -0,0 +1,42 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("command not found")
+	ErrNameTaken   = errors.New("command name is already in use")
+	ErrInvalidName = errors.New("command name must be 1-32 lowercase letters, digits, '-' or '_', starting with a letter")
+	ErrInvalidURL  = errors.New("command url must be an absolute http(s) url")
+)
+
+// Command is an external slash command of a conversation, served by an
+// HTTP endpoint.
+type Command struct {
+	ID             int64     `json:"id"`
+	ConversationID int64     `json:"conversation_id"`
+	CreatorID      int64     `json:"creator_id"`
+	Name           string    `json:"name"`
+	URL            string    `json:"url"`
+	Secret         string    `json:"secret,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type Repository interface {
+	// Create stores a command, returning ErrNameTaken if the conversation
+	// already has one with the same name.
+	Create(ctx context.Context, c *Command) error
+
+	// Get returns the named command of a conversation including its secret,
+	// or ErrNotFound.
+	Get(ctx context.Context, conversationID int64, name string) (*Command, error)
+
+	// ListByConversation returns a conversation's commands without secrets.
+	ListByConversation(ctx context.Context, conversationID int64) ([]Command, error)
+
+	// Delete removes a command of the conversation, or returns ErrNotFound.
+	Delete(ctx context.Context, conversationID, id int64) error
+}
@@ -0,0 +1,82 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("conversation not found")
+	ErrNotMember     = errors.New("not a member of this conversation")
+	ErrNotAdmin      = errors.New("only conversation admins can do this")
+	ErrAlreadyMember = errors.New("user is already a member of this conversation")
+	ErrUnknownUser   = errors.New("user not found")
+	ErrTopicTooLong  = errors.New("topic must be at most 250 characters")
+
+	ErrInvalidSlowMode  = errors.New("slow mode must be between 0 and 21600 seconds")
+	ErrAnnouncementOnly = errors.New("only admins can post in this conversation")
+	ErrSlowMode         = errors.New("slow mode is on")
+)
+
+// MaxTopicLength is the maximum length of a topic in characters.
+const MaxTopicLength = 250
+
+// MaxSlowMode is the longest interval slow mode can impose between messages.
+const MaxSlowMode = 6 * time.Hour
+
+type Conversation struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	IsGroup   bool      `json:"is_group"`
+	Topic     string    `json:"topic,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// SlowModeSeconds is the minimum interval between the messages of each
+	// member, or 0 when slow mode is off. Admins are exempt.
+	SlowModeSeconds int `json:"slow_mode_seconds"`
+	// AnnouncementOnly restricts posting to admins.
+	AnnouncementOnly bool `json:"announcement_only"`
+}
+
+// Summary is a conversation with its number of members, as listed.
+type Summary struct {
+	Conversation
+	MemberCount int `json:"member_count"`
+}
+
+// Query selects conversations to list. Zero values mean "no filter".
+type Query struct {
+	// Name matches conversations whose name contains it.
+	Name string
+	// MemberID limits the list to the conversations of a user.
+	MemberID int64
+	Limit    int
+	Offset   int
+}
+
+// SlowMode returns the minimum interval between messages of a member.
+func (c *Conversation) SlowMode() time.Duration {
+	return time.Duration(c.SlowModeSeconds) * time.Second
+}
+
+// ValidSlowMode reports whether seconds is an allowed slow mode interval.
+func ValidSlowMode(seconds int) bool {
+	return seconds >= 0 && time.Duration(seconds)*time.Second <= MaxSlowMode
+}
+
+// SlowModeError is returned for a message sent before the sender's slow mode
+// interval elapsed. It matches ErrSlowMode.
+type SlowModeError struct {
+	RetryAfter time.Duration
+}
+
+func (e *SlowModeError) Error() string {
+	return fmt.Sprintf("slow mode is on, you can post again in %ds", int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+func (e *SlowModeError) Is(target error) bool {
+	return target == ErrSlowMode
+}
@@ -0,0 +1,36 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestValidSlowMode(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    bool
+	}{
+		{0, true},
+		{30, true},
+		{21600, true},
+		{21601, false},
+		{-1, false},
+	}
+	for _, tt := range tests {
+		if got := ValidSlowMode(tt.seconds); got != tt.want {
+			t.Errorf("ValidSlowMode(%d): got %v want %v", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestSlowModeError(t *testing.T) {
+	err := fmt.Errorf("sending: %w", &SlowModeError{RetryAfter: 1500 * time.Millisecond})
+	if !errors.Is(err, ErrSlowMode) {
+		t.Errorf("errors.Is(%v, ErrSlowMode) = false", err)
+	}
+	if got, want := err.Error(), "sending: slow mode is on, you can post again in 2s"; got != want {
+		t.Errorf("message: got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package conversation
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// Get returns a conversation, or ErrNotFound.
+	Get(ctx context.Context, id int64) (*Conversation, error)
+
+	// List returns the conversations matching q, most recently updated
+	// first.
+	List(ctx context.Context, q Query) ([]Summary, error)
+
+	// IsMember reports whether userID belongs to the conversation.
+	IsMember(ctx context.Context, conversationID, userID int64) (bool, error)
+
+	// IsAdmin reports whether userID is an admin of the conversation.
+	IsAdmin(ctx context.Context, conversationID, userID int64) (bool, error)
+
+	// AddMember adds userID to the conversation, returning ErrAlreadyMember
+	// if they already belong to it.
+	AddMember(ctx context.Context, conversationID, userID int64) error
+
+	// RemoveMember removes userID from the conversation, returning
+	// ErrNotMember if they do not belong to it.
+	RemoveMember(ctx context.Context, conversationID, userID int64) error
+
+	// SetTopic sets the topic of the conversation; an empty topic clears it.
+	SetTopic(ctx context.Context, conversationID int64, topic string) error
+
+	// SetPostingRules sets the slow mode interval and announcement-only flag
+	// of the conversation.
+	SetPostingRules(ctx context.Context, conversationID int64, slowModeSeconds int, announcementOnly bool) error
+
+	// MemberIDs returns the ids of all users in the conversation.
+	MemberIDs(ctx context.Context, conversationID int64) ([]int64, error)
+
+	// MemberIDsByUsername returns the ids of the members with the given
+	// usernames; names that are not members are ignored.
+	MemberIDsByUsername(ctx context.Context, conversationID int64, usernames []string) ([]int64, error)
+
+	// SetMutedUntil mutes notifications of the conversation for userID until
+	// the given time, or unmutes it when until is nil.
+	SetMutedUntil(ctx context.Context, conversationID, userID int64, until *time.Time) error
+
+	// MutedUserIDs returns the members that have the conversation muted at now.
+	MutedUserIDs(ctx context.Context, conversationID int64, now time.Time) ([]int64, error)
+
+	// MarkRead records that userID has read the conversation up to at.
+	MarkRead(ctx context.Context, conversationID, userID int64, at time.Time) error
+
+	// MarkAllRead records that userID has read all their conversations up
+	// to at.
+	MarkAllRead(ctx context.Context, userID int64, at time.Time) error
+}
@@ -0,0 +1,89 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrInvalidFrequency = errors.New("invalid digest frequency")
+
+// Frequency is how often a user wants to receive the digest email.
+type Frequency string
+
+const (
+	Off    Frequency = "off"
+	Hourly Frequency = "hourly"
+	Daily  Frequency = "daily"
+	Weekly Frequency = "weekly"
+)
+
+// DefaultFrequency applies to users that never changed their preference:
+// digests are opt-in.
+const DefaultFrequency = Off
+
+// Interval returns the time between two digests, or zero for Off.
+func (f Frequency) Interval() time.Duration {
+	switch f {
+	case Hourly:
+		return time.Hour
+	case Daily:
+		return 24 * time.Hour
+	case Weekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+func (f Frequency) Valid() bool {
+	return f == Off || f.Interval() > 0
+}
+
+// Recipient is a user with an email address and digests enabled.
+type Recipient struct {
+	UserID    int64
+	Username  string
+	Email     string
+	Frequency Frequency
+	// LastCutoff is the creation time up to which messages were covered by
+	// the previous digest.
+	LastCutoff *time.Time
+}
+
+// Due reports whether the next digest should be sent at now.
+func (r *Recipient) Due(now time.Time) bool {
+	interval := r.Frequency.Interval()
+	if interval == 0 {
+		return false
+	}
+	return r.LastCutoff == nil || !now.Before(r.LastCutoff.Add(interval))
+}
+
+// Item is an unread message listed in a digest.
+type Item struct {
+	MessageID        int64
+	ConversationID   int64
+	ConversationName string
+	SenderName       string
+	Content          string
+	CreatedAt        time.Time
+}
+
+type Repository interface {
+	// Frequency returns the user's preference, DefaultFrequency if unset.
+	Frequency(ctx context.Context, userID int64) (Frequency, error)
+	SetFrequency(ctx context.Context, userID int64, f Frequency) error
+
+	// Recipients returns the users with an email address whose frequency is
+	// not Off.
+	Recipients(ctx context.Context) ([]Recipient, error)
+
+	// Unread returns up to limit messages from others created in
+	// (since, until] and after the user last read their conversation, oldest
+	// first. Muted conversations are left out except for messages that
+	// mention the user.
+	Unread(ctx context.Context, userID int64, since, until time.Time, limit int) ([]Item, error)
+
+	// MarkSent records that messages up to cutoff have been covered.
+	MarkSent(ctx context.Context, userID int64, cutoff time.Time) error
+}
@@ -0,0 +1,83 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrAttachmentNotFound  = errors.New("attachment not found")
+	ErrAttachmentNotOwned  = errors.New("attachment belongs to another user or message")
+	ErrUnsupportedFileType = errors.New("unsupported file type")
+	ErrFileTooLarge        = errors.New("file is too large")
+)
+
+// AllowedContentTypes are the MIME types accepted for uploads, detected from
+// the file content rather than trusted from the client. Only image formats
+// whose metadata the server can strip are accepted.
+var AllowedContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"application/pdf": true,
+	"text/plain":      true,
+	"video/mp4":       true,
+	"audio/mpeg":      true,
+	"audio/wave":      true,
+	"application/zip": true,
+}
+
+// Attachment processing states. Images are processed in the background and
+// have no download URL until they are ready.
+const (
+	AttachmentReady      = "ready"
+	AttachmentProcessing = "processing"
+	AttachmentFailed     = "failed"
+)
+
+// Thumbnail is a scaled down rendition of an image attachment.
+type Thumbnail struct {
+	Name   string `json:"name"`
+	Key    string `json:"-"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	URL    string `json:"url,omitempty"`
+}
+
+// Attachment is an uploaded file. It is unlinked (MessageID is nil) until
+// the uploader sends a message referencing it.
+type Attachment struct {
+	ID          int64     `json:"id"`
+	MessageID   *int64    `json:"message_id,omitempty"`
+	UploaderID  int64     `json:"uploader_id"`
+	Key         string    `json:"-"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+	Status      string    `json:"status"`
+
+	// Image metadata, filled in once processing has finished.
+	Width      int         `json:"width,omitempty"`
+	Height     int         `json:"height,omitempty"`
+	Blurhash   string      `json:"blurhash,omitempty"`
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty"`
+
+	// URL is a signed download URL generated per response.
+	URL string `json:"url,omitempty"`
+}
+
+type AttachmentRepository interface {
+	// Create stores the metadata of a new, unlinked attachment.
+	Create(ctx context.Context, a *Attachment) error
+
+	Get(ctx context.Context, id int64) (*Attachment, error)
+
+	// UpdateImage stores the processing status and image metadata of a.
+	UpdateImage(ctx context.Context, a *Attachment) error
+
+	// ListByMessages returns the attachments of the given messages, keyed by
+	// message id.
+	ListByMessages(ctx context.Context, messageIDs []int64) (map[int64][]Attachment, error)
+}
@@ -0,0 +1,65 @@
+package message
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"unicode/utf8"
+)
+
+const (
+	MaxEmbeds     = 5
+	maxEmbedTitle = 256
+	maxEmbedText  = 2000
+	maxEmbedURL   = 2048
+)
+
+var (
+	ErrTooManyEmbeds = errors.New("too many attachments")
+	ErrInvalidEmbed  = errors.New("invalid attachment")
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Embed is a simple rich attachment posted by integrations, such as a build
+// result with a title, a link and a colored bar.
+type Embed struct {
+	Title    string `json:"title,omitempty"`
+	Text     string `json:"text,omitempty"`
+	URL      string `json:"url,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+	Color    string `json:"color,omitempty"`
+}
+
+// ValidateEmbeds checks the number, sizes and URLs of embeds.
+func ValidateEmbeds(embeds []Embed) error {
+	if len(embeds) > MaxEmbeds {
+		return ErrTooManyEmbeds
+	}
+	for _, e := range embeds {
+		if e.Title == "" && e.Text == "" && e.ImageURL == "" {
+			return ErrInvalidEmbed
+		}
+		if utf8.RuneCountInString(e.Title) > maxEmbedTitle || utf8.RuneCountInString(e.Text) > maxEmbedText {
+			return ErrInvalidEmbed
+		}
+		if !validEmbedURL(e.URL) || !validEmbedURL(e.ImageURL) {
+			return ErrInvalidEmbed
+		}
+		if e.Color != "" && !colorPattern.MatchString(e.Color) {
+			return ErrInvalidEmbed
+		}
+	}
+	return nil
+}
+
+func validEmbedURL(raw string) bool {
+	if raw == "" {
+		return true
+	}
+	if len(raw) > maxEmbedURL {
+		return false
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
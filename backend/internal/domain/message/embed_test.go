@@ -0,0 +1,29 @@
+package message
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateEmbeds(t *testing.T) {
+	tests := []struct {
+		embeds []Embed
+		want   error
+	}{
+		{nil, nil},
+		{[]Embed{{Title: "Build #12 passed", URL: "https://ci.example.com/12", Color: "#2eb886"}}, nil},
+		{[]Embed{{ImageURL: "https://example.com/chart.png"}}, nil},
+		{[]Embed{{}}, ErrInvalidEmbed},
+		{[]Embed{{Title: "x", URL: "javascript:alert(1)"}}, ErrInvalidEmbed},
+		{[]Embed{{Title: "x", ImageURL: "/relative.png"}}, ErrInvalidEmbed},
+		{[]Embed{{Title: "x", Color: "red"}}, ErrInvalidEmbed},
+		{[]Embed{{Text: strings.Repeat("a", maxEmbedText+1)}}, ErrInvalidEmbed},
+		{make([]Embed, MaxEmbeds+1), ErrTooManyEmbeds},
+	}
+
+	for _, tt := range tests {
+		if got := ValidateEmbeds(tt.embeds); got != tt.want {
+			t.Errorf("ValidateEmbeds(%+v): got %v want %v", tt.embeds, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package message
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	HighlightPre  = "<mark>"
+	HighlightPost = "</mark>"
+)
+
+// Terms splits a search query into the words to match, dropping the
+// punctuation and boolean operators used by full-text engines.
+func Terms(query string) []string {
+	fields := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool, len(fields))
+	terms := make([]string, 0, len(fields))
+	for _, f := range fields {
+		lower := strings.ToLower(f)
+		if seen[lower] {
+			continue
+		}
+		seen[lower] = true
+		terms = append(terms, f)
+	}
+	return terms
+}
+
+// Highlight HTML-escapes content and wraps every case-insensitive occurrence
+// of the given terms in <mark> tags.
+func Highlight(content string, terms []string) string {
+	if len(terms) == 0 {
+		return html.EscapeString(content)
+	}
+
+	lower := strings.ToLower(content)
+	if len(lower) != len(content) {
+		// Case folding changed byte offsets; fall back to no highlighting.
+		return html.EscapeString(content)
+	}
+
+	marked := make([]bool, len(content))
+	for _, term := range terms {
+		t := strings.ToLower(term)
+		if t == "" {
+			continue
+		}
+		for start := 0; ; {
+			i := strings.Index(lower[start:], t)
+			if i < 0 {
+				break
+			}
+			for j := start + i; j < start+i+len(t); j++ {
+				marked[j] = true
+			}
+			start += i + len(t)
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(content); {
+		j := i + 1
+		for j < len(content) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString(HighlightPre)
+			b.WriteString(html.EscapeString(content[i:j]))
+			b.WriteString(HighlightPost)
+		} else {
+			b.WriteString(html.EscapeString(content[i:j]))
+		}
+		i = j
+	}
+	return b.String()
+}
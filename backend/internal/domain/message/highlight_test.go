@@ -0,0 +1,35 @@
+package message
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	got := Terms(`+xin "chào" -bạn* chào`)
+	want := []string{"xin", "chào", "bạn"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Terms returned unexpected terms: got %v want %v", got, want)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		content string
+		terms   []string
+		want    string
+	}{
+		{"Hello world", []string{"world"}, "Hello <mark>world</mark>"},
+		{"Hello World, world", []string{"WORLD"}, "Hello <mark>World</mark>, <mark>world</mark>"},
+		{"Xin chào các bạn", []string{"chào", "bạn"}, "Xin <mark>chào</mark> các <mark>bạn</mark>"},
+		{"<b>bold</b>", []string{"bold"}, "&lt;b&gt;<mark>bold</mark>&lt;/b&gt;"},
+		{"no match here", []string{"xyz"}, "no match here"},
+		{"overlapping", []string{"over", "lapping"}, "<mark>overlapping</mark>"},
+	}
+
+	for _, tt := range tests {
+		if got := Highlight(tt.content, tt.terms); got != tt.want {
+			t.Errorf("Highlight(%q, %v): got %q want %q", tt.content, tt.terms, got, tt.want)
+		}
+	}
+}
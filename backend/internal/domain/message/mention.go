@@ -0,0 +1,80 @@
+package message
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Mention kinds. A user mentioned both by name and through @here or @all
+// gets a single mention of the most specific kind.
+const (
+	MentionUser = "user"
+	MentionHere = "here"
+	MentionAll  = "all"
+)
+
+// MaxMentions caps the number of distinct usernames resolved per message.
+const MaxMentions = 50
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([\p{L}\p{N}_.\-]+)`)
+
+// Mention records that a message mentioned a user.
+type Mention struct {
+	ID             int64     `json:"id"`
+	MessageID      int64     `json:"message_id"`
+	ConversationID int64     `json:"conversation_id"`
+	UserID         int64     `json:"user_id"`
+	SenderID       int64     `json:"sender_id"`
+	Kind           string    `json:"kind"`
+	CreatedAt      time.Time `json:"created_at"`
+	Message        *Message  `json:"message,omitempty"`
+}
+
+// Mentions is the result of parsing a message for mentions.
+type Mentions struct {
+	Usernames []string
+	Here      bool
+	All       bool
+}
+
+func (m Mentions) IsEmpty() bool {
+	return len(m.Usernames) == 0 && !m.Here && !m.All
+}
+
+// ParseMentions finds @username, @here and @all in content. Usernames are
+// returned once each, in order of appearance; an "@" inside a word, as in
+// an email address, is not a mention.
+func ParseMentions(content string) Mentions {
+	var (
+		m    Mentions
+		seen = map[string]bool{}
+	)
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.TrimRight(match[1], ".-")
+		if name == "" {
+			continue
+		}
+		switch key := strings.ToLower(name); {
+		case key == "here":
+			m.Here = true
+		case key == "all":
+			m.All = true
+		case !seen[key] && len(m.Usernames) < MaxMentions:
+			seen[key] = true
+			m.Usernames = append(m.Usernames, name)
+		}
+	}
+	return m
+}
+
+type MentionRepository interface {
+	// Create stores mentions. A user is mentioned at most once per message.
+	Create(ctx context.Context, mentions []Mention) error
+
+	// ListForUser returns a page of the mentions of a user in conversations
+	// they are still a member of, newest first, with their messages.
+	// Mentions in deleted messages are left out.
+	ListForUser(ctx context.Context, userID int64, limit, offset int) ([]Mention, error)
+}
@@ -0,0 +1,28 @@
+package message
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		content string
+		want    Mentions
+	}{
+		{"no mentions here", Mentions{}},
+		{"@an hi", Mentions{Usernames: []string{"an"}}},
+		{"hi @an, @binh_2 and @An.", Mentions{Usernames: []string{"an", "binh_2"}}},
+		{"chào @Đức!", Mentions{Usernames: []string{"Đức"}}},
+		{"mail me at an@example.com", Mentions{}},
+		{"@here meeting now @ALL", Mentions{Here: true, All: true}},
+		{"@@an @", Mentions{}},
+		{"(@an)", Mentions{Usernames: []string{"an"}}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMentions(%q): got %+v want %+v", tt.content, got, tt.want)
+		}
+	}
+}
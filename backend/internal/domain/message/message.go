@@ -0,0 +1,61 @@
+package message
+
+import "time"
+
+type Message struct {
+	ID             int64      `json:"id"`
+	SenderID       int64      `json:"sender_id"`
+	ConversationID int64      `json:"conversation_id"`
+	Content        string     `json:"content"`
+	MediaURL       string     `json:"media_url,omitempty"`
+	IsRead         bool       `json:"is_read"`
+	IsBot          bool       `json:"is_bot,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	EditedAt       *time.Time `json:"edited_at,omitempty"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+
+	// Thread fields. ParentID is set on replies; ReplyCount and LastReplyAt
+	// are maintained on the parent.
+	ParentID    *int64     `json:"parent_id,omitempty"`
+	ReplyCount  int        `json:"reply_count,omitempty"`
+	LastReplyAt *time.Time `json:"last_reply_at,omitempty"`
+
+	Reactions    []ReactionCount `json:"reactions,omitempty"`
+	Attachments  []Attachment    `json:"attachments,omitempty"`
+	LinkPreviews []LinkPreview   `json:"link_previews,omitempty"`
+	Embeds       []Embed         `json:"embeds,omitempty"`
+
+	// Shadowed messages come from a shadow-muted sender and are only shown
+	// to the sender.
+	Shadowed bool `json:"-"`
+}
+
+// Edit is a previous version of a message's content.
+type Edit struct {
+	ID        int64     `json:"id"`
+	MessageID int64     `json:"message_id"`
+	Content   string    `json:"content"`
+	EditedAt  time.Time `json:"edited_at"`
+}
+
+// IsReply reports whether the message belongs to a thread.
+func (m *Message) IsReply() bool {
+	return m.ParentID != nil
+}
+
+// IsDeleted reports whether the message has been soft-deleted.
+func (m *Message) IsDeleted() bool {
+	return m.DeletedAt != nil
+}
+
+// Tombstone strips the content of a deleted message so only its place in
+// the history remains visible.
+func (m *Message) Tombstone() {
+	if m.IsDeleted() {
+		m.Content = ""
+		m.MediaURL = ""
+		m.Attachments = nil
+		m.LinkPreviews = nil
+		m.Embeds = nil
+	}
+}
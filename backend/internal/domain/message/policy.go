@@ -0,0 +1,43 @@
+package message
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound          = errors.New("message not found")
+	ErrNotAuthor         = errors.New("only the author can change this message")
+	ErrDeleted           = errors.New("message has been deleted")
+	ErrEditWindowExpired = errors.New("edit window has expired")
+)
+
+// Policy holds the server-side rules for changing existing messages.
+// A zero window means there is no time limit.
+type Policy struct {
+	EditWindow   time.Duration
+	DeleteWindow time.Duration
+}
+
+// CanEdit reports whether userID may edit m at the given time.
+func (p Policy) CanEdit(m *Message, userID int64, now time.Time) error {
+	return p.check(m, userID, now, p.EditWindow)
+}
+
+// CanDelete reports whether userID may delete m at the given time.
+func (p Policy) CanDelete(m *Message, userID int64, now time.Time) error {
+	return p.check(m, userID, now, p.DeleteWindow)
+}
+
+func (p Policy) check(m *Message, userID int64, now time.Time, window time.Duration) error {
+	if m.SenderID != userID {
+		return ErrNotAuthor
+	}
+	if m.IsDeleted() {
+		return ErrDeleted
+	}
+	if window > 0 && now.Sub(m.CreatedAt) > window {
+		return ErrEditWindowExpired
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package message
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicy(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	deletedAt := now.Add(-time.Minute)
+	policy := Policy{EditWindow: 15 * time.Minute}
+
+	tests := []struct {
+		name    string
+		msg     Message
+		userID  int64
+		wantErr error
+	}{
+		{"author within window", Message{SenderID: 1, CreatedAt: now.Add(-time.Minute)}, 1, nil},
+		{"other user", Message{SenderID: 1, CreatedAt: now.Add(-time.Minute)}, 2, ErrNotAuthor},
+		{"window expired", Message{SenderID: 1, CreatedAt: now.Add(-time.Hour)}, 1, ErrEditWindowExpired},
+		{"deleted", Message{SenderID: 1, CreatedAt: now.Add(-time.Minute), DeletedAt: &deletedAt}, 1, ErrDeleted},
+	}
+
+	for _, tt := range tests {
+		if err := policy.CanEdit(&tt.msg, tt.userID, now); err != tt.wantErr {
+			t.Errorf("%s: CanEdit returned %v want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// Deletion has no window configured.
+	old := Message{SenderID: 1, CreatedAt: now.Add(-24 * time.Hour)}
+	if err := policy.CanDelete(&old, 1, now); err != nil {
+		t.Errorf("CanDelete returned %v want nil", err)
+	}
+}
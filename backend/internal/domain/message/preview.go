@@ -0,0 +1,27 @@
+package message
+
+import (
+	"context"
+	"time"
+)
+
+// LinkPreview is the OpenGraph/Twitter card summary of a URL in a message.
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// IsEmpty reports whether the preview has nothing worth showing.
+func (p *LinkPreview) IsEmpty() bool {
+	return p.Title == "" && p.Description == "" && p.Image == ""
+}
+
+// LinkPreviewCache caches previews by URL. A cached nil preview records that
+// the URL could not be unfurled, so it is not fetched again until it expires.
+type LinkPreviewCache interface {
+	Get(ctx context.Context, url string) (preview *LinkPreview, found bool, err error)
+	Set(ctx context.Context, url string, preview *LinkPreview, ttl time.Duration) error
+}
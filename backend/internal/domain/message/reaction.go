@@ -0,0 +1,154 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxEmojiBytes = 64
+
+var (
+	ErrInvalidEmoji    = errors.New("invalid emoji")
+	ErrAlreadyReacted  = errors.New("reaction already exists")
+	ErrReactionMissing = errors.New("reaction not found")
+)
+
+type Reaction struct {
+	MessageID int64     `json:"message_id"`
+	UserID    int64     `json:"user_id"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReactionCount is the aggregated number of reactions with one emoji.
+// Reacted tells whether the requesting user is among them.
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+type ReactionRepository interface {
+	// Add stores a reaction. It returns ErrAlreadyReacted if the user has
+	// already reacted to the message with the same emoji.
+	Add(ctx context.Context, r Reaction) error
+
+	// Remove deletes a reaction, returning ErrReactionMissing if none exists.
+	Remove(ctx context.Context, r Reaction) error
+
+	// Counts aggregates the reactions of the given messages, keyed by
+	// message id, from the point of view of userID.
+	Counts(ctx context.Context, messageIDs []int64, userID int64) (map[int64][]ReactionCount, error)
+}
+
+// shortcodePattern matches named emoji such as ":thumbsup:".
+var shortcodePattern = regexp.MustCompile(`^:[a-z0-9_+-]{1,32}:$`)
+
+// pictographic approximates the Extended_Pictographic property of Unicode:
+// the characters that start an emoji. Regional indicators and skin tone
+// modifiers are left out as they only combine with others.
+var pictographic = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00a9, Hi: 0x00a9, Stride: 1},
+		{Lo: 0x00ae, Hi: 0x00ae, Stride: 1},
+		{Lo: 0x203c, Hi: 0x203c, Stride: 1},
+		{Lo: 0x2049, Hi: 0x2049, Stride: 1},
+		{Lo: 0x2122, Hi: 0x2122, Stride: 1},
+		{Lo: 0x2139, Hi: 0x2139, Stride: 1},
+		{Lo: 0x2194, Hi: 0x2199, Stride: 1},
+		{Lo: 0x21a9, Hi: 0x21aa, Stride: 1},
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x2328, Hi: 0x2328, Stride: 1},
+		{Lo: 0x23cf, Hi: 0x23cf, Stride: 1},
+		{Lo: 0x23e9, Hi: 0x23f3, Stride: 1},
+		{Lo: 0x23f8, Hi: 0x23fa, Stride: 1},
+		{Lo: 0x24c2, Hi: 0x24c2, Stride: 1},
+		{Lo: 0x25aa, Hi: 0x25ab, Stride: 1},
+		{Lo: 0x25b6, Hi: 0x25b6, Stride: 1},
+		{Lo: 0x25c0, Hi: 0x25c0, Stride: 1},
+		{Lo: 0x25fb, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2600, Hi: 0x27bf, Stride: 1},
+		{Lo: 0x2934, Hi: 0x2935, Stride: 1},
+		{Lo: 0x2b05, Hi: 0x2b07, Stride: 1},
+		{Lo: 0x2b1b, Hi: 0x2b1c, Stride: 1},
+		{Lo: 0x2b50, Hi: 0x2b50, Stride: 1},
+		{Lo: 0x2b55, Hi: 0x2b55, Stride: 1},
+		{Lo: 0x3030, Hi: 0x3030, Stride: 1},
+		{Lo: 0x303d, Hi: 0x303d, Stride: 1},
+		{Lo: 0x3297, Hi: 0x3297, Stride: 1},
+		{Lo: 0x3299, Hi: 0x3299, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f000, Hi: 0x1f1e5, Stride: 1},
+		{Lo: 0x1f200, Hi: 0x1f3fa, Stride: 1},
+		{Lo: 0x1f400, Hi: 0x1faff, Stride: 1},
+	},
+	LatinOffset: 2,
+}
+
+const (
+	zeroWidthJoiner   = 0x200d
+	variationSelector = 0xfe0f
+	combiningKeycap   = 0x20e3
+	cancelTag         = 0xe007f
+)
+
+// ValidEmoji reports whether s is acceptable as a reaction: a single emoji,
+// possibly a flag, keycap, modified or joined sequence, or a shortcode
+// such as ":thumbsup:".
+func ValidEmoji(s string) bool {
+	if s == "" || len(s) > maxEmojiBytes || !utf8.ValidString(s) {
+		return false
+	}
+	if shortcodePattern.MatchString(s) {
+		return true
+	}
+
+	rs := []rune(s)
+	switch {
+	case len(rs) == 2 && isRegionalIndicator(rs[0]) && isRegionalIndicator(rs[1]):
+		return true
+	case isKeycapBase(rs[0]):
+		return len(rs) == 2 && rs[1] == combiningKeycap ||
+			len(rs) == 3 && rs[1] == variationSelector && rs[2] == combiningKeycap
+	}
+
+	// A pictograph with optional modifiers or tags, joined to further ones
+	// by zero width joiners.
+	for i := 0; ; i++ {
+		if i == len(rs) || !unicode.Is(pictographic, rs[i]) {
+			return false
+		}
+		for i+1 < len(rs) && (rs[i+1] == variationSelector || isSkinTone(rs[i+1])) {
+			i++
+		}
+		if i+1 < len(rs) && isTag(rs[i+1]) {
+			for i+1 < len(rs) && isTag(rs[i+1]) {
+				i++
+			}
+			if i+1 == len(rs) || rs[i+1] != cancelTag {
+				return false
+			}
+			i++
+		}
+		if i+1 == len(rs) {
+			return true
+		}
+		if rs[i+1] != zeroWidthJoiner {
+			return false
+		}
+		i++
+	}
+}
+
+func isRegionalIndicator(r rune) bool { return r >= 0x1f1e6 && r <= 0x1f1ff }
+
+func isSkinTone(r rune) bool { return r >= 0x1f3fb && r <= 0x1f3ff }
+
+func isTag(r rune) bool { return r >= 0xe0020 && r <= 0xe007e }
+
+func isKeycapBase(r rune) bool { return r == '#' || r == '*' || (r >= '0' && r <= '9') }
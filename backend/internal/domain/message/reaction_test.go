@@ -0,0 +1,46 @@
+package message
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidEmoji(t *testing.T) {
+	tests := []struct {
+		emoji string
+		want  bool
+	}{
+		{"👍", true},
+		{"👨‍👩‍👧", true},
+		{":thumbsup:", true},
+		{":+1:", true},
+		{"❤️", true},
+		{"👍🏽", true},
+		{"🇻🇳", true},
+		{"1️⃣", true},
+		{"🏴󠁧󠁢󠁳󠁣󠁴󠁿", true},
+		{"🏳️‍🌈", true},
+		{"lol", false},
+		{"a", false},
+		{"1", false},
+		{"👍👍", false},
+		{"👍lol", false},
+		{"‍👍", false},
+		{"👍‍", false},
+		{"🇻", false},
+		{":Thumbs Up:", false},
+		{"::", false},
+		{"<script>", false},
+		{"", false},
+		{"a b", false},
+		{"\x00", false},
+		{string([]byte{0xff}), false},
+		{strings.Repeat("👍", 20), false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidEmoji(tt.emoji); got != tt.want {
+			t.Errorf("ValidEmoji(%q): got %v want %v", tt.emoji, got, tt.want)
+		}
+	}
+}
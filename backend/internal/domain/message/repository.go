@@ -0,0 +1,41 @@
+package message
+
+import "context"
+
+type Repository interface {
+	// Create stores a new message and fills in its id and creation time.
+	// Replies also update the reply count and last reply time of their parent.
+	// The given unlinked attachments of the sender are linked to the message
+	// in the same transaction; it returns ErrAttachmentNotOwned unless every
+	// one of them was.
+	Create(ctx context.Context, m *Message, attachmentIDs []int64) error
+
+	// Get returns the message with the given id, including soft-deleted ones.
+	Get(ctx context.Context, id int64) (*Message, error)
+
+	// ListByConversation returns a page of a conversation's top-level
+	// history as viewerID sees it, newest first. Deleted messages are
+	// returned as tombstones.
+	ListByConversation(ctx context.Context, conversationID, viewerID int64, limit, offset int) ([]Message, error)
+
+	// UpdateContent replaces the content of a message and records the
+	// previous content in the edit history.
+	UpdateContent(ctx context.Context, id int64, content string) (*Message, error)
+
+	// SetLinkPreviews stores the link previews of a message.
+	SetLinkPreviews(ctx context.Context, id int64, previews []LinkPreview) error
+
+	// SoftDelete marks a message as deleted.
+	SoftDelete(ctx context.Context, id int64) (*Message, error)
+
+	// Thread returns a page of the replies to a message as viewerID sees
+	// them, oldest first.
+	Thread(ctx context.Context, parentID, viewerID int64, limit, offset int) ([]Message, error)
+
+	// ThreadParticipantIDs returns the ids of the parent's author and of
+	// everyone who replied to it.
+	ThreadParticipantIDs(ctx context.Context, parentID int64) ([]int64, error)
+
+	// Edits returns the edit history of a message, oldest first.
+	Edits(ctx context.Context, id int64) ([]Edit, error)
+}
@@ -0,0 +1,38 @@
+package message
+
+import (
+	"context"
+	"time"
+)
+
+// SearchQuery describes a full-text search over the messages that UserID is
+// allowed to see. Zero values for the optional filters mean "no filter".
+type SearchQuery struct {
+	UserID         int64
+	Text           string
+	ConversationID int64
+	SenderID       int64
+	From           time.Time
+	To             time.Time
+	Limit          int
+	Offset         int
+}
+
+type SearchHit struct {
+	Message
+	Highlight string `json:"highlight"`
+}
+
+type SearchResult struct {
+	Hits   []SearchHit `json:"hits"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// Searcher is implemented by full-text search engines. The MySQL FULLTEXT
+// implementation lives in the repositories package; other engines only need
+// to satisfy this interface to be plugged into the server.
+type Searcher interface {
+	Search(ctx context.Context, q SearchQuery) (*SearchResult, error)
+}
@@ -0,0 +1,61 @@
+package message
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxContentLength is the maximum number of characters in a message.
+	MaxContentLength = 4000
+
+	// MaxAttachments is the maximum number of attachments on a message.
+	MaxAttachments = 10
+)
+
+var (
+	ErrEmptyContent       = errors.New("content is required")
+	ErrContentTooLong     = errors.New("content is too long")
+	ErrInvalidCharacters  = errors.New("content contains invalid characters")
+	ErrInvalidParent      = errors.New("invalid parent message")
+	ErrTooManyAttachments = errors.New("too many attachments")
+)
+
+// ValidateContent checks the content of a new or edited message.
+func ValidateContent(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return ErrEmptyContent
+	}
+	if utf8.RuneCountInString(content) > MaxContentLength {
+		return ErrContentTooLong
+	}
+	if !ValidText(content) {
+		return ErrInvalidCharacters
+	}
+	return nil
+}
+
+// ValidText reports whether s is valid UTF-8 without control characters
+// other than tabs and line breaks.
+func ValidText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r != '\n' && r != '\r' && r != '\t' && unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateParent checks that parent can receive replies in conversationID.
+// Threads are one level deep, so replies cannot be replied to.
+func ValidateParent(parent *Message, conversationID int64) error {
+	if parent.ConversationID != conversationID || parent.IsDeleted() || parent.IsReply() {
+		return ErrInvalidParent
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package message
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateContent(t *testing.T) {
+	tests := []struct {
+		content string
+		want    error
+	}{
+		{"xin chào", nil},
+		{"   ", ErrEmptyContent},
+		{strings.Repeat("ă", MaxContentLength), nil},
+		{strings.Repeat("ă", MaxContentLength+1), ErrContentTooLong},
+		{"dòng 1\ndòng 2\tcột", nil},
+		{"bell\a", ErrInvalidCharacters},
+		{"nul\x00", ErrInvalidCharacters},
+		{"c1\u0085", ErrInvalidCharacters},
+		{"bad \xff utf-8", ErrInvalidCharacters},
+	}
+
+	for _, tt := range tests {
+		if got := ValidateContent(tt.content); got != tt.want {
+			t.Errorf("ValidateContent(%.10q): got %v want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestValidateParent(t *testing.T) {
+	parentID := int64(1)
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name   string
+		parent Message
+		want   error
+	}{
+		{"top-level message", Message{ID: 1, ConversationID: 3}, nil},
+		{"other conversation", Message{ID: 1, ConversationID: 4}, ErrInvalidParent},
+		{"deleted", Message{ID: 1, ConversationID: 3, DeletedAt: &deletedAt}, ErrInvalidParent},
+		{"reply", Message{ID: 2, ConversationID: 3, ParentID: &parentID}, ErrInvalidParent},
+	}
+
+	for _, tt := range tests {
+		if got := ValidateParent(&tt.parent, 3); got != tt.want {
+			t.Errorf("%s: got %v want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package moderation
+
+import (
+	"context"
+	"time"
+)
+
+// AuditAction is a moderation action recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditReport        AuditAction = "report"
+	AuditResolveFlag   AuditAction = "resolve_flag"
+	AuditMute          AuditAction = "mute"
+	AuditUnmute        AuditAction = "unmute"
+	AuditKick          AuditAction = "kick"
+	AuditBan           AuditAction = "ban"
+	AuditUnban         AuditAction = "unban"
+	AuditDeleteMessage AuditAction = "delete_message"
+
+	AuditLiftRestrictions AuditAction = "lift_restrictions"
+	AuditBanAccount       AuditAction = "ban_account"
+	AuditUnbanAccount     AuditAction = "unban_account"
+)
+
+// AuditEntry records who took a moderation action, on whom and why.
+type AuditEntry struct {
+	ID             int64       `json:"id"`
+	ConversationID *int64      `json:"conversation_id,omitempty"`
+	ActorID        int64       `json:"actor_id"`
+	Action         AuditAction `json:"action"`
+	TargetUserID   *int64      `json:"target_user_id,omitempty"`
+	MessageID      *int64      `json:"message_id,omitempty"`
+	FlagID         *int64      `json:"flag_id,omitempty"`
+	Reason         string      `json:"reason,omitempty"`
+	ExpiresAt      *time.Time  `json:"expires_at,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+type AuditRepository interface {
+	// Record appends an entry to the audit log.
+	Record(ctx context.Context, e *AuditEntry) error
+
+	// AuditLog returns the entries of a conversation, newest first.
+	AuditLog(ctx context.Context, conversationID int64, limit, offset int) ([]AuditEntry, error)
+}
@@ -0,0 +1,102 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrMessageRejected = errors.New("message was rejected by the content filter")
+	ErrFlagNotFound    = errors.New("flag not found")
+	ErrInvalidReason   = errors.New("reason must be at most 255 characters")
+)
+
+// MaxReasonLength is the maximum length of a report or action reason.
+const MaxReasonLength = 255
+
+// Action is what happens to a message that a content filter matches.
+type Action string
+
+const (
+	ActionOff    Action = "off"
+	ActionMask   Action = "mask"
+	ActionReject Action = "reject"
+	ActionFlag   Action = "flag"
+)
+
+// Valid reports whether a is a known action.
+func (a Action) Valid() bool {
+	switch a {
+	case ActionOff, ActionMask, ActionReject, ActionFlag:
+		return true
+	}
+	return false
+}
+
+// Sources of flags in the moderation queue.
+const (
+	SourceFilter = "filter"
+	SourceReport = "report"
+	SourceSpam   = "spam"
+)
+
+// Flag statuses.
+const (
+	FlagPending  = "pending"
+	FlagResolved = "resolved"
+)
+
+// Flag is an entry of the moderation queue: a message or user that needs a
+// moderator's review. UserID is the user whose behaviour was flagged and
+// ReporterID the member who reported it, if any.
+type Flag struct {
+	ID             int64      `json:"id"`
+	ConversationID *int64     `json:"conversation_id,omitempty"`
+	MessageID      *int64     `json:"message_id,omitempty"`
+	UserID         int64      `json:"user_id"`
+	ReporterID     *int64     `json:"reporter_id,omitempty"`
+	Source         string     `json:"source"`
+	Reason         string     `json:"reason"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ResolvedBy     *int64     `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+// FlagQuery selects entries of the moderation queue. Zero values mean "no
+// filter".
+type FlagQuery struct {
+	ConversationID int64
+	Status         string
+	Source         string
+	Limit          int
+	Offset         int
+}
+
+type FlagRepository interface {
+	// Create adds a pending flag to the queue.
+	Create(ctx context.Context, f *Flag) error
+
+	// GetFlag returns a flag, or ErrFlagNotFound.
+	GetFlag(ctx context.Context, id int64) (*Flag, error)
+
+	// ListFlags returns the flags matching q, oldest first.
+	ListFlags(ctx context.Context, q FlagQuery) ([]Flag, error)
+
+	// ResolveFlag marks a flag as resolved by resolvedBy.
+	ResolveFlag(ctx context.Context, id, resolvedBy int64) error
+}
+
+// FilterSettings are the actions a conversation takes for each content
+// filter, by filter name. Filters without an entry use their default.
+type FilterSettings map[string]Action
+
+type FilterSettingsRepository interface {
+	// FilterSettings returns the filter actions configured for a
+	// conversation.
+	FilterSettings(ctx context.Context, conversationID int64) (FilterSettings, error)
+
+	// SetFilterAction sets the action of one filter in a conversation.
+	SetFilterAction(ctx context.Context, conversationID int64, filter string, action Action) error
+}
@@ -0,0 +1,50 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var ErrThrottled = errors.New("you are sending messages too fast")
+
+// Restriction is an account-wide penalty applied automatically to suspected
+// spammers until it expires or a moderator lifts it.
+type Restriction string
+
+const (
+	// RestrictThrottle limits the account to one message per throttle
+	// interval.
+	RestrictThrottle Restriction = "throttle"
+	// RestrictShadowMute keeps the account's messages visible to the
+	// account only.
+	RestrictShadowMute Restriction = "shadow_mute"
+)
+
+// ThrottledError is returned for a message from a throttled account sent
+// before its interval elapsed. It matches ErrThrottled.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%v, you can post again in %ds", ErrThrottled, int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrThrottled
+}
+
+type RestrictionRepository interface {
+	// Restrict applies a restriction to the user for d, replacing the
+	// expiry of a running one.
+	Restrict(ctx context.Context, userID int64, r Restriction, d time.Duration) error
+
+	// Restricted reports whether the restriction is in effect on the user.
+	Restricted(ctx context.Context, userID int64, r Restriction) (bool, error)
+
+	// Lift removes every restriction of the user.
+	Lift(ctx context.Context, userID int64) error
+}
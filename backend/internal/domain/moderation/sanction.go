@@ -0,0 +1,68 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrMuted         = errors.New("you are muted in this conversation")
+	ErrBanned        = errors.New("user is banned from this conversation")
+	ErrNotSanctioned = errors.New("user is not muted or banned")
+	ErrInvalidTarget = errors.New("conversation admins cannot be moderated")
+	ErrInvalidPeriod = errors.New("duration must be a positive duration such as 30m or 24h")
+)
+
+// SanctionKind is a restriction a conversation admin puts on a member.
+type SanctionKind string
+
+const (
+	// SanctionMute stops a member from posting until it expires.
+	SanctionMute SanctionKind = "mute"
+	// SanctionBan removes a user from the conversation and stops them from
+	// being added back until it expires.
+	SanctionBan SanctionKind = "ban"
+)
+
+// Sanction is a mute or ban of a user in a conversation. A nil ExpiresAt
+// never expires.
+type Sanction struct {
+	ConversationID int64        `json:"conversation_id"`
+	UserID         int64        `json:"user_id"`
+	Kind           SanctionKind `json:"kind"`
+	Reason         string       `json:"reason,omitempty"`
+	ExpiresAt      *time.Time   `json:"expires_at,omitempty"`
+	CreatedBy      int64        `json:"created_by"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// Active reports whether the sanction is in effect at now.
+func (s *Sanction) Active(now time.Time) bool {
+	return s.ExpiresAt == nil || now.Before(*s.ExpiresAt)
+}
+
+// Expiry returns the expiry of a sanction of duration d starting at now; a
+// sanction without a positive duration never expires.
+func Expiry(now time.Time, d time.Duration) *time.Time {
+	if d <= 0 {
+		return nil
+	}
+	t := now.Add(d)
+	return &t
+}
+
+type SanctionRepository interface {
+	// Sanction adds s, replacing a sanction of the same kind on the user.
+	Sanction(ctx context.Context, s *Sanction) error
+
+	// Lift removes a sanction, or returns ErrNotSanctioned.
+	Lift(ctx context.Context, conversationID, userID int64, kind SanctionKind) error
+
+	// ActiveSanction returns the sanction of the given kind in effect on
+	// the user at now, or nil.
+	ActiveSanction(ctx context.Context, conversationID, userID int64, kind SanctionKind, now time.Time) (*Sanction, error)
+
+	// ListSanctions returns the sanctions in effect in a conversation at now.
+	ListSanctions(ctx context.Context, conversationID int64, now time.Time) ([]Sanction, error)
+}
@@ -0,0 +1,27 @@
+package moderation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSanctionActive(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		d    time.Duration
+		at   time.Time
+		want bool
+	}{
+		{"permanent", 0, now.Add(24 * 365 * time.Hour), true},
+		{"running", time.Hour, now.Add(59 * time.Minute), true},
+		{"expired", time.Hour, now.Add(time.Hour), false},
+	}
+	for _, tt := range tests {
+		s := Sanction{Kind: SanctionMute, ExpiresAt: Expiry(now, tt.d)}
+		if got := s.Active(tt.at); got != tt.want {
+			t.Errorf("%s: got %v want %v", tt.name, got, tt.want)
+		}
+	}
+}
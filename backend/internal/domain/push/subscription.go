@@ -0,0 +1,45 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("push subscription not found")
+	ErrEndpointTaken        = errors.New("push endpoint is registered by another user")
+)
+
+// Subscription is a browser PushSubscription registered by a user.
+type Subscription struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Endpoint  string     `json:"endpoint"`
+	P256dh    string     `json:"-"`
+	Auth      string     `json:"-"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsExpired reports whether the browser-provided expiration time has passed.
+func (s *Subscription) IsExpired(now time.Time) bool {
+	return s.ExpiresAt != nil && now.After(*s.ExpiresAt)
+}
+
+type SubscriptionRepository interface {
+	// Save stores a subscription. Endpoints are unique, so registering an
+	// existing endpoint again updates its keys; it returns ErrEndpointTaken
+	// if the endpoint belongs to another user.
+	Save(ctx context.Context, s *Subscription) error
+
+	// ListByUsers returns the subscriptions of the given users.
+	ListByUsers(ctx context.Context, userIDs []int64) ([]Subscription, error)
+
+	// Delete removes a subscription, e.g. after the push service reported it
+	// as gone.
+	Delete(ctx context.Context, id int64) error
+
+	// DeleteForUser removes a subscription owned by userID.
+	DeleteForUser(ctx context.Context, id, userID int64) error
+}
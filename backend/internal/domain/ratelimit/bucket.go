@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rate configures a token bucket: it holds up to Burst tokens and refills
+// at PerSecond tokens per second. Each request takes one token.
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Enabled reports whether the rate limits anything.
+func (r Rate) Enabled() bool {
+	return r.PerSecond > 0 && r.Burst > 0
+}
+
+// retryAfter returns how long it takes to refill the missing tokens.
+func (r Rate) retryAfter(tokens float64) time.Duration {
+	return time.Duration(math.Ceil((1 - tokens) / r.PerSecond * float64(time.Second)))
+}
+
+// Buckets is a set of token buckets shared across server instances.
+type Buckets interface {
+	// Take removes a token from the bucket of key, reporting whether one
+	// was available.
+	Take(ctx context.Context, key string, rate Rate) (Result, error)
+}
+
+// TokenBucket is a token bucket local to one process, such as the bucket of
+// a single connection.
+type TokenBucket struct {
+	rate Rate
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a full bucket.
+func NewTokenBucket(rate Rate) *TokenBucket {
+	return &TokenBucket{rate: rate, tokens: float64(rate.Burst)}
+}
+
+// Take removes a token at now, reporting whether one was available.
+func (b *TokenBucket) Take(now time.Time) Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens = math.Min(float64(b.rate.Burst), b.tokens+now.Sub(b.last).Seconds()*b.rate.PerSecond)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return Result{RetryAfter: b.rate.retryAfter(b.tokens)}
+	}
+	b.tokens--
+	return Result{Allowed: true}
+}
@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := NewTokenBucket(Rate{PerSecond: 2, Burst: 3})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if res := b.Take(now); !res.Allowed {
+			t.Fatalf("take %d: got refused want allowed", i)
+		}
+	}
+	res := b.Take(now)
+	if res.Allowed {
+		t.Fatal("take beyond burst: got allowed want refused")
+	}
+	if res.RetryAfter != 500*time.Millisecond {
+		t.Errorf("retry after: got %v want %v", res.RetryAfter, 500*time.Millisecond)
+	}
+
+	// Half a second refills one token at 2/s.
+	if res := b.Take(now.Add(500 * time.Millisecond)); !res.Allowed {
+		t.Error("take after refill: got refused want allowed")
+	}
+	if res := b.Take(now.Add(500 * time.Millisecond)); res.Allowed {
+		t.Error("second take after refill: got allowed want refused")
+	}
+
+	// Refills stop at the burst size.
+	later := now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if res := b.Take(later); !res.Allowed {
+			t.Fatalf("take %d after an hour: got refused want allowed", i)
+		}
+	}
+	if res := b.Take(later); res.Allowed {
+		t.Error("take beyond burst after an hour: got allowed want refused")
+	}
+}
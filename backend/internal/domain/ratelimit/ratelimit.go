@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Result is the outcome of a rate limit check.
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long to wait before the next request can succeed;
+	// it is only set when the request was refused.
+	RetryAfter time.Duration
+}
+
+// Limiter counts requests per key across server instances.
+type Limiter interface {
+	// Allow records a request for key and reports whether it is within
+	// limit requests per window.
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// Cooldowns enforce a minimum interval between actions per key across server
+// instances.
+type Cooldowns interface {
+	// Start starts a cooldown of d on key and allows the action, unless a
+	// cooldown is already running on key.
+	Start(ctx context.Context, key string, d time.Duration) (Result, error)
+}
+
+// Windows count events in sliding windows across server instances.
+type Windows interface {
+	// Add records member in the window of key and returns the number of
+	// distinct members recorded in the last window, including it.
+	Add(ctx context.Context, key, member string, window time.Duration) (int, error)
+}
@@ -0,0 +1,27 @@
+package user
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrBlocked    = errors.New("you cannot message this user")
+	ErrBlockSelf  = errors.New("you cannot block yourself")
+	ErrNotBlocked = errors.New("user is not blocked")
+)
+
+type BlockRepository interface {
+	// Block records that blockerID blocked blockedID. Blocking twice is not
+	// an error; an unknown blockedID returns ErrNotFound.
+	Block(ctx context.Context, blockerID, blockedID int64) error
+
+	// Unblock removes a block, or returns ErrNotBlocked.
+	Unblock(ctx context.Context, blockerID, blockedID int64) error
+
+	// BlockedIDs returns the users blockerID blocked.
+	BlockedIDs(ctx context.Context, blockerID int64) ([]int64, error)
+
+	// BlockerIDs returns the users among userIDs that blocked blockedID.
+	BlockerIDs(ctx context.Context, blockedID int64, userIDs []int64) ([]int64, error)
+}
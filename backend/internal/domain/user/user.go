@@ -0,0 +1,49 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrNotFound        = errors.New("user not found")
+	ErrInvalidUsername = errors.New("username must be 3-50 letters, digits or underscores")
+	ErrBanned          = errors.New("this account is banned")
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,50}$`)
+
+// ValidUsername reports whether name can be used as a username.
+func ValidUsername(name string) bool {
+	return usernamePattern.MatchString(name)
+}
+
+type User struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	IsBot     bool      `json:"is_bot"`
+	CreatedAt time.Time `json:"created_at"`
+	// BannedAt is set while the account is banned from the server.
+	BannedAt *time.Time `json:"banned_at,omitempty"`
+}
+
+type Repository interface {
+	// Get returns the user with the given id, or ErrNotFound.
+	Get(ctx context.Context, id int64) (*User, error)
+
+	// GetByUsername returns the user with the given username, or ErrNotFound.
+	GetByUsername(ctx context.Context, username string) (*User, error)
+
+	// Search returns up to limit users whose username starts with prefix,
+	// by username.
+	Search(ctx context.Context, prefix string, limit int) ([]User, error)
+
+	// IsBanned reports whether the account is banned from the server.
+	IsBanned(ctx context.Context, id int64) (bool, error)
+
+	// SetBanned bans or unbans an account, returning ErrNotFound for an
+	// unknown id.
+	SetBanned(ctx context.Context, id int64, banned bool) error
+}
@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid or revoked webhook token")
+	ErrInvalidName  = errors.New("webhook name must be 1-50 characters")
+)
+
+// Incoming is a per-conversation URL that external systems POST messages
+// to. Messages are sent by the webhook's bot user, which is added to the
+// conversation when the webhook is created.
+type Incoming struct {
+	ID             int64      `json:"id"`
+	ConversationID int64      `json:"conversation_id"`
+	CreatorID      int64      `json:"creator_id"`
+	BotUserID      int64      `json:"bot_user_id"`
+	Name           string     `json:"name"`
+	CreatedAt      time.Time  `json:"created_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+
+	// Token is only set when the webhook is created; just its hash is
+	// stored.
+	Token string `json:"token,omitempty"`
+}
+
+type IncomingRepository interface {
+	// Create stores a webhook whose token hashes to tokenHash, creating its
+	// bot user and adding it to the conversation.
+	Create(ctx context.Context, w *Incoming, tokenHash string) error
+
+	// GetByTokenHash returns the active webhook with the token hash, or
+	// ErrInvalidToken if there is none or it was revoked.
+	GetByTokenHash(ctx context.Context, tokenHash string) (*Incoming, error)
+
+	// ListByConversation returns a conversation's webhooks, including
+	// revoked ones.
+	ListByConversation(ctx context.Context, conversationID int64) ([]Incoming, error)
+
+	// Revoke disables a webhook of the conversation, returning ErrNotFound
+	// if there is no such active webhook.
+	Revoke(ctx context.Context, conversationID, id int64) error
+}
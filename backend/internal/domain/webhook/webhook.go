@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("webhook not found")
+	ErrInvalidURL   = errors.New("webhook url must be an absolute http(s) url")
+	ErrInvalidEvent = errors.New("unknown webhook event")
+	ErrNoEvents     = errors.New("at least one event is required")
+)
+
+// Event types delivered to webhooks.
+const (
+	EventMessageCreated = "message.created"
+	EventUserJoined     = "user.joined"
+	EventUserStatus     = "user.status"
+)
+
+// Events lists the supported event types.
+var Events = []string{EventMessageCreated, EventUserJoined, EventUserStatus}
+
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook subscribes a URL to events. A webhook with a ConversationID only
+// receives events of that conversation; a global webhook receives events of
+// every conversation as well as user status changes.
+type Webhook struct {
+	ID             int64     `json:"id"`
+	OwnerID        int64     `json:"owner_id"`
+	ConversationID *int64    `json:"conversation_id"`
+	URL            string    `json:"url"`
+	Secret         string    `json:"secret,omitempty"`
+	Events         []string  `json:"events"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether the webhook wants event.
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Delivery is one queued POST of an event payload to a webhook.
+type Delivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     int64           `json:"webhook_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastStatus    int             `json:"last_status,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+type Repository interface {
+	Create(ctx context.Context, w *Webhook) error
+
+	// Get returns a webhook including its secret, or ErrNotFound.
+	Get(ctx context.Context, id int64) (*Webhook, error)
+
+	// ListByOwner returns the webhooks created by a user, without secrets.
+	ListByOwner(ctx context.Context, ownerID int64) ([]Webhook, error)
+
+	Delete(ctx context.Context, id int64) error
+
+	// Subscribers returns the webhooks that receive event: the global ones
+	// and, when conversationID is set, those of that conversation.
+	Subscribers(ctx context.Context, event string, conversationID *int64) ([]Webhook, error)
+}
+
+type DeliveryRepository interface {
+	// Enqueue stores new pending deliveries.
+	Enqueue(ctx context.Context, deliveries []Delivery) error
+
+	// Claim returns up to limit pending deliveries that are due at now and
+	// postpones them by lease, so concurrent workers do not pick them up.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error)
+
+	// Update stores the outcome of a delivery attempt.
+	Update(ctx context.Context, d *Delivery) error
+
+	// ListByWebhook returns a page of a webhook's deliveries, newest first.
+	ListByWebhook(ctx context.Context, webhookID int64, limit, offset int) ([]Delivery, error)
+}
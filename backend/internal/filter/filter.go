@@ -0,0 +1,143 @@
+// Package filter runs messages through a chain of content filters before
+// they are stored and fanned out.
+package filter
+
+import (
+	"sort"
+	"strings"
+
+	"backend/internal/domain/moderation"
+)
+
+// Match is the byte span [Start, End) of content a filter objects to.
+type Match struct {
+	Start, End int
+}
+
+type Filter interface {
+	// Name identifies the filter in per-conversation settings.
+	Name() string
+
+	// Find returns the non-overlapping spans of content the filter matches,
+	// in order.
+	Find(content string) []Match
+}
+
+// Hit records a filter that matched a message and what was done about it.
+type Hit struct {
+	Filter  string
+	Action  moderation.Action
+	Matches []string
+}
+
+// Result is the outcome of running a message through a Chain.
+type Result struct {
+	// Content is the message with masked spans replaced by asterisks.
+	Content  string
+	Rejected bool
+	Hits     []Hit
+}
+
+// Flagged returns the hits whose action is to flag the message for review.
+func (r *Result) Flagged() []Hit {
+	var hits []Hit
+	for _, h := range r.Hits {
+		if h.Action == moderation.ActionFlag {
+			hits = append(hits, h)
+		}
+	}
+	return hits
+}
+
+type link struct {
+	filter Filter
+	def    moderation.Action
+}
+
+// Chain runs filters in the order they were added.
+type Chain struct {
+	links []link
+}
+
+func NewChain() *Chain {
+	return &Chain{}
+}
+
+// Add appends a filter with the action used by conversations that did not
+// configure one.
+func (c *Chain) Add(f Filter, def moderation.Action) *Chain {
+	c.links = append(c.links, link{filter: f, def: def})
+	return c
+}
+
+// Defaults returns the default action of every filter by name.
+func (c *Chain) Defaults() moderation.FilterSettings {
+	settings := moderation.FilterSettings{}
+	for _, l := range c.links {
+		settings[l.filter.Name()] = l.def
+	}
+	return settings
+}
+
+// Has reports whether the chain contains a filter with the given name.
+func (c *Chain) Has(name string) bool {
+	for _, l := range c.links {
+		if l.filter.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run applies the filters to content with the actions of settings. Each
+// filter sees the output of the previous one, and a rejecting filter stops
+// the chain.
+func (c *Chain) Run(content string, settings moderation.FilterSettings) Result {
+	res := Result{Content: content}
+	for _, l := range c.links {
+		action, ok := settings[l.filter.Name()]
+		if !ok {
+			action = l.def
+		}
+		if action == moderation.ActionOff {
+			continue
+		}
+
+		matches := l.filter.Find(res.Content)
+		if len(matches) == 0 {
+			continue
+		}
+		hit := Hit{Filter: l.filter.Name(), Action: action}
+		for _, m := range matches {
+			hit.Matches = append(hit.Matches, res.Content[m.Start:m.End])
+		}
+		res.Hits = append(res.Hits, hit)
+
+		switch action {
+		case moderation.ActionMask:
+			res.Content = mask(res.Content, matches)
+		case moderation.ActionReject:
+			res.Rejected = true
+			return res
+		}
+	}
+	return res
+}
+
+// mask replaces every character of the matched spans with an asterisk.
+func mask(content string, matches []Match) string {
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		if m.Start < last {
+			continue
+		}
+		b.WriteString(content[last:m.Start])
+		b.WriteString(strings.Repeat("*", len([]rune(content[m.Start:m.End]))))
+		last = m.End
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
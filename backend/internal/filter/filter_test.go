@@ -0,0 +1,139 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"backend/internal/domain/moderation"
+)
+
+func TestFold(t *testing.T) {
+	tests := map[string]string{
+		"Địt":        "dit",
+		"ĐỤ MÁ":      "du ma",
+		"Tiếng Việt": "tieng viet",
+		"café":       "cafe",
+		"plain":      "plain",
+	}
+	for in, want := range tests {
+		if got := Fold(in); got != want {
+			t.Errorf("Fold(%q): got %q want %q", in, got, want)
+		}
+	}
+}
+
+func TestWordList(t *testing.T) {
+	entries, err := LoadWordLists(English, Vietnamese)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewWordList(entries)
+
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"what the fuck", []string{"fuck"}},
+		{"What The FUCK!", []string{"FUCK"}},
+		{"scunthorpe and shitake", nil},
+		{"đụ má mày", []string{"đụ má"}},
+		{"du ma may", []string{"du ma"}},
+		{"ĐỤ MÁ", []string{"ĐỤ MÁ"}},
+		{"dit con me no", []string{"dit con me"}},
+		{"đi du lịch, đeo kính, nhà lớn", nil},
+		// Decomposed diacritics are part of the word.
+		{"đụ má", []string{"đụ má"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range f.Find(tt.content) {
+			got = append(got, tt.content[m.Start:m.End])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Find(%q): got %q want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestRegex(t *testing.T) {
+	f, err := NewRegex([]string{`\b\d{9,11}\b`, `buy\s+followers`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := f.Find("BUY  followers at 0912345678")
+	if len(matches) != 2 {
+		t.Fatalf("matches: got %d want 2", len(matches))
+	}
+
+	if _, err := NewRegex([]string{"("}); err == nil {
+		t.Error("invalid pattern: got nil error")
+	}
+
+	patterns, err := ReadPatterns(strings.NewReader("# phone numbers\n\\d{10}\n\n  spam  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`\d{10}`, "spam"}; !reflect.DeepEqual(patterns, want) {
+		t.Errorf("ReadPatterns: got %q want %q", patterns, want)
+	}
+}
+
+func TestLinkBlocklist(t *testing.T) {
+	f := NewLinkBlocklist([]string{"evil.com", " .Scam.vn "})
+
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"see https://evil.com/x?y=1 now", []string{"https://evil.com/x?y=1"}},
+		{"go to www.EVIL.com", []string{"www.EVIL.com"}},
+		{"(promo.scam.vn/free)", []string{"promo.scam.vn/free"}},
+		{"notevil.com and evil.com.au are fine", nil},
+		{"mail me@evil.com", nil},
+		{"https://chatvui.com", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range f.Find(tt.content) {
+			got = append(got, tt.content[m.Start:m.End])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Find(%q): got %q want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	links := NewLinkBlocklist([]string{"evil.com"})
+	chain := NewChain().
+		Add(NewWordList([]string{"đụ má"}), moderation.ActionMask).
+		Add(links, moderation.ActionReject)
+
+	res := chain.Run("Đụ má, hay quá", nil)
+	if res.Content != "*****, hay quá" || res.Rejected {
+		t.Errorf("mask: got %q rejected %v", res.Content, res.Rejected)
+	}
+	if len(res.Hits) != 1 || res.Hits[0].Filter != "words" || res.Hits[0].Matches[0] != "Đụ má" {
+		t.Errorf("mask hits: got %+v", res.Hits)
+	}
+
+	res = chain.Run("du ma evil.com", nil)
+	if !res.Rejected || len(res.Hits) != 2 {
+		t.Errorf("reject: got rejected %v hits %+v", res.Rejected, res.Hits)
+	}
+
+	// Conversation settings override the defaults.
+	settings := moderation.FilterSettings{"words": moderation.ActionOff, "links": moderation.ActionFlag}
+	res = chain.Run("du ma evil.com", settings)
+	if res.Rejected || res.Content != "du ma evil.com" {
+		t.Errorf("flag: got %q rejected %v", res.Content, res.Rejected)
+	}
+	if flagged := res.Flagged(); len(flagged) != 1 || flagged[0].Filter != "links" {
+		t.Errorf("flagged: got %+v", flagged)
+	}
+
+	if !chain.Has("links") || chain.Has("regex") {
+		t.Error("Has: wrong filters")
+	}
+}
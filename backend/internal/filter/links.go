@@ -0,0 +1,69 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// linkPattern finds links with or without a scheme, capturing the host.
+var linkPattern = regexp.MustCompile(`(?i)(?:https?://)?((?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,})(?::\d+)?(?:/[^\s<>"']*)?`)
+
+// LinkBlocklist matches links to blocked domains and their subdomains.
+type LinkBlocklist struct {
+	domains map[string]bool
+}
+
+func NewLinkBlocklist(domains []string) *LinkBlocklist {
+	f := &LinkBlocklist{domains: map[string]bool{}}
+	for _, d := range domains {
+		if d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), "."); d != "" {
+			f.domains[d] = true
+		}
+	}
+	return f
+}
+
+func (f *LinkBlocklist) Name() string {
+	return "links"
+}
+
+func (f *LinkBlocklist) Find(content string) []Match {
+	var matches []Match
+	for _, loc := range linkPattern.FindAllStringSubmatchIndex(content, -1) {
+		// Skip hosts glued to a preceding word, such as the domain of an
+		// email address.
+		if loc[0] > 0 && !isLinkBoundary(content[loc[0]-1]) {
+			continue
+		}
+		if !f.blocked(strings.ToLower(content[loc[2]:loc[3]])) {
+			continue
+		}
+		// Trailing punctuation usually belongs to the sentence, not the
+		// link.
+		end := loc[1]
+		for end > loc[3] && strings.IndexByte(".,;:!?)]}", content[end-1]) >= 0 {
+			end--
+		}
+		matches = append(matches, Match{Start: loc[0], End: end})
+	}
+	return matches
+}
+
+// blocked reports whether host is a blocked domain or one of its
+// subdomains.
+func (f *LinkBlocklist) blocked(host string) bool {
+	for {
+		if f.domains[host] {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
+
+func isLinkBoundary(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t' || c == '(' || c == '<' || c == '"' || c == '\''
+}
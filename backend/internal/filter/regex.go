@@ -0,0 +1,57 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Regex matches any of a list of regular expressions.
+type Regex struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRegex compiles patterns, which are matched case-insensitively.
+func NewRegex(patterns []string) (*Regex, error) {
+	f := &Regex{}
+	for _, p := range patterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", p, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	return f, nil
+}
+
+// ReadPatterns reads one pattern per line, skipping blank lines and lines
+// starting with "#".
+func ReadPatterns(r io.Reader) ([]string, error) {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+func (f *Regex) Name() string {
+	return "regex"
+}
+
+func (f *Regex) Find(content string) []Match {
+	var matches []Match
+	for _, re := range f.patterns {
+		for _, loc := range re.FindAllStringIndex(content, -1) {
+			if loc[1] > loc[0] {
+				matches = append(matches, Match{Start: loc[0], End: loc[1]})
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,151 @@
+package filter
+
+import (
+	"bufio"
+	"embed"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:embed words/*.txt
+var wordLists embed.FS
+
+// Built-in word lists.
+const (
+	English    = "en"
+	Vietnamese = "vi"
+)
+
+// Fold lowercases s and strips diacritics, mapping đ to d, so that words
+// typed without Vietnamese accents still match: Fold("Địt") is "dit".
+func Fold(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if r == 'đ' || r == 'Đ' {
+			r = 'd'
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// WordList matches whole words and phrases from a list, ignoring case and
+// diacritics.
+type WordList struct {
+	// phrases are the folded entries split into words, indexed by their
+	// first word.
+	phrases map[string][][]string
+}
+
+// NewWordList builds a filter from entries, which may be phrases of several
+// words.
+func NewWordList(entries []string) *WordList {
+	w := &WordList{phrases: map[string][][]string{}}
+	for _, e := range entries {
+		words := strings.FieldsFunc(Fold(e), notWordRune)
+		if len(words) == 0 {
+			continue
+		}
+		w.phrases[words[0]] = append(w.phrases[words[0]], words)
+	}
+	return w
+}
+
+// LoadWordLists returns the entries of the built-in lists with the given
+// names, such as English and Vietnamese.
+func LoadWordLists(names ...string) ([]string, error) {
+	var entries []string
+	for _, name := range names {
+		f, err := wordLists.Open("words/" + name + ".txt")
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				entries = append(entries, line)
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func (w *WordList) Name() string {
+	return "words"
+}
+
+func (w *WordList) Find(content string) []Match {
+	words := splitWords(content)
+	var matches []Match
+	for i := 0; i < len(words); i++ {
+		n := w.longestPhrase(words[i:])
+		if n == 0 {
+			continue
+		}
+		matches = append(matches, Match{Start: words[i].start, End: words[i+n-1].end})
+		i += n - 1
+	}
+	return matches
+}
+
+// longestPhrase returns the number of words of the longest entry that
+// words starts with, or 0.
+func (w *WordList) longestPhrase(words []word) int {
+	longest := 0
+next:
+	for _, phrase := range w.phrases[words[0].folded] {
+		if len(phrase) > len(words) || len(phrase) <= longest {
+			continue
+		}
+		for j, p := range phrase {
+			if words[j].folded != p {
+				continue next
+			}
+		}
+		longest = len(phrase)
+	}
+	return longest
+}
+
+type word struct {
+	start, end int
+	folded     string
+}
+
+// splitWords returns the runs of letters and digits in s with their byte
+// offsets. Combining marks belong to the word they follow.
+func splitWords(s string) []word {
+	var words []word
+	start := -1
+	for i, r := range s {
+		if notWordRune(r) {
+			if start >= 0 {
+				words = append(words, word{start: start, end: i, folded: Fold(s[start:i])})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, word{start: start, end: len(s), folded: Fold(s[start:])})
+	}
+	return words
+}
+
+func notWordRune(r rune) bool {
+	return r == utf8.RuneError || !(unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r))
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"backend/internal/domain/message"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+const attachmentColumns = `id, message_id, uploader_id, storage_key, filename, content_type, size, created_at,
+	status, width, height, blurhash, thumbnails`
+
+// thumbnailRow is the stored form of a thumbnail, which unlike the API
+// representation includes the storage key.
+type thumbnailRow struct {
+	Name   string `json:"name"`
+	Key    string `json:"key"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type MySQLAttachmentRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLAttachmentRepo(db *sql.DB) *MySQLAttachmentRepo {
+	return &MySQLAttachmentRepo{db: db}
+}
+
+func scanAttachment(row rowScanner) (*message.Attachment, error) {
+	var (
+		a             message.Attachment
+		messageID     sql.NullInt64
+		width, height sql.NullInt64
+		blurhash      sql.NullString
+		thumbnails    []byte
+	)
+	err := row.Scan(&a.ID, &messageID, &a.UploaderID, &a.Key, &a.Filename, &a.ContentType, &a.Size, &a.CreatedAt,
+		&a.Status, &width, &height, &blurhash, &thumbnails)
+	if err != nil {
+		return nil, err
+	}
+	if messageID.Valid {
+		a.MessageID = &messageID.Int64
+	}
+	a.Width, a.Height, a.Blurhash = int(width.Int64), int(height.Int64), blurhash.String
+	if len(thumbnails) > 0 {
+		var stored []thumbnailRow
+		if err := json.Unmarshal(thumbnails, &stored); err != nil {
+			return nil, err
+		}
+		for _, t := range stored {
+			a.Thumbnails = append(a.Thumbnails, message.Thumbnail{Name: t.Name, Key: t.Key, Width: t.Width, Height: t.Height})
+		}
+	}
+	return &a, nil
+}
+
+func (r *MySQLAttachmentRepo) Create(ctx context.Context, a *message.Attachment) error {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO attachments (uploader_id, storage_key, filename, content_type, size, status) VALUES (?, ?, ?, ?, ?, ?)",
+		a.UploaderID, a.Key, a.Filename, a.ContentType, a.Size, a.Status,
+	)
+	if err != nil {
+		return err
+	}
+	if a.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx, "SELECT created_at FROM attachments WHERE id = ?", a.ID).Scan(&a.CreatedAt)
+}
+
+func (r *MySQLAttachmentRepo) Get(ctx context.Context, id int64) (*message.Attachment, error) {
+	a, err := scanAttachment(r.db.QueryRowContext(ctx, "SELECT "+attachmentColumns+" FROM attachments WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, message.ErrAttachmentNotFound
+	}
+	return a, err
+}
+
+func (r *MySQLAttachmentRepo) UpdateImage(ctx context.Context, a *message.Attachment) error {
+	var thumbnails []byte
+	if len(a.Thumbnails) > 0 {
+		stored := make([]thumbnailRow, len(a.Thumbnails))
+		for i, t := range a.Thumbnails {
+			stored[i] = thumbnailRow{Name: t.Name, Key: t.Key, Width: t.Width, Height: t.Height}
+		}
+		var err error
+		if thumbnails, err = json.Marshal(stored); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE attachments SET status = ?, size = ?, width = NULLIF(?, 0), height = NULLIF(?, 0), blurhash = NULLIF(?, ''), thumbnails = ? WHERE id = ?",
+		a.Status, a.Size, a.Width, a.Height, a.Blurhash, thumbnails, a.ID,
+	)
+	return err
+}
+
+func (r *MySQLAttachmentRepo) ListByMessages(ctx context.Context, messageIDs []int64) (map[int64][]message.Attachment, error) {
+	out := make(map[int64][]message.Attachment, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return out, nil
+	}
+
+	args := make([]interface{}, len(messageIDs))
+	for i, id := range messageIDs {
+		args[i] = id
+	}
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+attachmentColumns+" FROM attachments WHERE message_id IN ("+placeholders(len(messageIDs))+") ORDER BY id",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out[*a.MessageID] = append(out[*a.MessageID], *a)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"backend/internal/domain/user"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type MySQLBlockRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLBlockRepo(db *sql.DB) *MySQLBlockRepo {
+	return &MySQLBlockRepo{db: db}
+}
+
+func (r *MySQLBlockRepo) Block(ctx context.Context, blockerID, blockedID int64) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT IGNORE INTO user_blocks (blocker_id, blocked_id) VALUES (?, ?)",
+		blockerID, blockedID,
+	)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlForeignKeyViolation {
+		return user.ErrNotFound
+	}
+	return err
+}
+
+func (r *MySQLBlockRepo) Unblock(ctx context.Context, blockerID, blockedID int64) error {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?",
+		blockerID, blockedID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return user.ErrNotBlocked
+	}
+	return nil
+}
+
+func (r *MySQLBlockRepo) BlockedIDs(ctx context.Context, blockerID int64) ([]int64, error) {
+	return r.ids(ctx, "SELECT blocked_id FROM user_blocks WHERE blocker_id = ? ORDER BY created_at", blockerID)
+}
+
+func (r *MySQLBlockRepo) BlockerIDs(ctx context.Context, blockedID int64, userIDs []int64) ([]int64, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{blockedID}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+	return r.ids(ctx,
+		"SELECT blocker_id FROM user_blocks WHERE blocked_id = ? AND blocker_id IN ("+placeholders(len(userIDs))+")",
+		args...,
+	)
+}
+
+func (r *MySQLBlockRepo) ids(ctx context.Context, query string, args ...interface{}) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
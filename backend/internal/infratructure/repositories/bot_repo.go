@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"backend/internal/domain/bot"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const botColumns = "b.user_id, b.owner_id, u.username, b.events, b.created_at"
+
+type MySQLBotRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLBotRepo(db *sql.DB) *MySQLBotRepo {
+	return &MySQLBotRepo{db: db}
+}
+
+func scanBot(row rowScanner) (*bot.Bot, error) {
+	var (
+		b      bot.Bot
+		events string
+	)
+	if err := row.Scan(&b.UserID, &b.OwnerID, &b.Username, &events, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+	b.Events = []string{}
+	if events != "" {
+		b.Events = strings.Split(events, ",")
+	}
+	return &b, nil
+}
+
+func (r *MySQLBotRepo) Create(ctx context.Context, b *bot.Bot, tokenHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Bots authenticate with their token only, so they get no password.
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO users (username, password_hash, is_bot) VALUES (?, '', TRUE)",
+		b.Username,
+	)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return bot.ErrUsernameTaken
+	}
+	if err != nil {
+		return err
+	}
+	if b.UserID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO bots (user_id, owner_id, token_hash, events) VALUES (?, ?, ?, ?)",
+		b.UserID, b.OwnerID, tokenHash, strings.Join(b.Events, ","),
+	); err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT created_at FROM bots WHERE user_id = ?", b.UserID).Scan(&b.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *MySQLBotRepo) Get(ctx context.Context, userID int64) (*bot.Bot, error) {
+	b, err := scanBot(r.db.QueryRowContext(ctx,
+		"SELECT "+botColumns+" FROM bots b JOIN users u ON u.id = b.user_id WHERE b.user_id = ?", userID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, bot.ErrNotFound
+	}
+	return b, err
+}
+
+func (r *MySQLBotRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*bot.Bot, error) {
+	b, err := scanBot(r.db.QueryRowContext(ctx,
+		"SELECT "+botColumns+" FROM bots b JOIN users u ON u.id = b.user_id WHERE b.token_hash = ?", tokenHash,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, bot.ErrInvalidToken
+	}
+	return b, err
+}
+
+func (r *MySQLBotRepo) ListByOwner(ctx context.Context, ownerID int64) ([]bot.Bot, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+botColumns+" FROM bots b JOIN users u ON u.id = b.user_id WHERE b.owner_id = ? ORDER BY b.user_id",
+		ownerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bots := []bot.Bot{}
+	for rows.Next() {
+		b, err := scanBot(rows)
+		if err != nil {
+			return nil, err
+		}
+		bots = append(bots, *b)
+	}
+	return bots, rows.Err()
+}
+
+func (r *MySQLBotRepo) SetEvents(ctx context.Context, userID int64, events []string) error {
+	// MySQL reports unchanged rows as unaffected, so this cannot use update.
+	_, err := r.db.ExecContext(ctx, "UPDATE bots SET events = ? WHERE user_id = ?", strings.Join(events, ","), userID)
+	return err
+}
+
+func (r *MySQLBotRepo) RotateToken(ctx context.Context, userID int64, tokenHash string) error {
+	return r.update(ctx, "UPDATE bots SET token_hash = ? WHERE user_id = ?", tokenHash, userID)
+}
+
+func (r *MySQLBotRepo) Delete(ctx context.Context, userID int64) error {
+	return r.update(ctx, "DELETE FROM bots WHERE user_id = ?", userID)
+}
+
+// update runs a statement on one bot, returning ErrNotFound if it matched
+// nothing.
+func (r *MySQLBotRepo) update(ctx context.Context, query string, args ...interface{}) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return bot.ErrNotFound
+	}
+	return nil
+}
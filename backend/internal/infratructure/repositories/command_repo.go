@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"backend/internal/domain/command"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const commandColumns = "id, conversation_id, creator_id, name, url, secret, created_at"
+
+type MySQLCommandRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLCommandRepo(db *sql.DB) *MySQLCommandRepo {
+	return &MySQLCommandRepo{db: db}
+}
+
+func scanCommand(row rowScanner) (*command.Command, error) {
+	var c command.Command
+	if err := row.Scan(&c.ID, &c.ConversationID, &c.CreatorID, &c.Name, &c.URL, &c.Secret, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *MySQLCommandRepo) Create(ctx context.Context, c *command.Command) error {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO slash_commands (conversation_id, creator_id, name, url, secret) VALUES (?, ?, ?, ?, ?)",
+		c.ConversationID, c.CreatorID, c.Name, c.URL, c.Secret,
+	)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return command.ErrNameTaken
+	}
+	if err != nil {
+		return err
+	}
+	if c.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx, "SELECT created_at FROM slash_commands WHERE id = ?", c.ID).Scan(&c.CreatedAt)
+}
+
+func (r *MySQLCommandRepo) Get(ctx context.Context, conversationID int64, name string) (*command.Command, error) {
+	c, err := scanCommand(r.db.QueryRowContext(ctx,
+		"SELECT "+commandColumns+" FROM slash_commands WHERE conversation_id = ? AND name = ?",
+		conversationID, name,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, command.ErrNotFound
+	}
+	return c, err
+}
+
+func (r *MySQLCommandRepo) ListByConversation(ctx context.Context, conversationID int64) ([]command.Command, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+commandColumns+" FROM slash_commands WHERE conversation_id = ? ORDER BY name",
+		conversationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var commands []command.Command
+	for rows.Next() {
+		c, err := scanCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		c.Secret = ""
+		commands = append(commands, *c)
+	}
+	return commands, rows.Err()
+}
+
+func (r *MySQLCommandRepo) Delete(ctx context.Context, conversationID, id int64) error {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM slash_commands WHERE id = ? AND conversation_id = ?",
+		id, conversationID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return command.ErrNotFound
+	}
+	return nil
+}
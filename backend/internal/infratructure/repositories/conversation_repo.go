@@ -0,0 +1,225 @@
+package repositories
+
+import (
+	"backend/internal/domain/conversation"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type MySQLConversationRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLConversationRepo(db *sql.DB) *MySQLConversationRepo {
+	return &MySQLConversationRepo{db: db}
+}
+
+const conversationColumns = "c.id, c.name, c.is_group, c.topic, c.created_at, c.updated_at, c.slow_mode_seconds, c.announcement_only"
+
+func scanConversation(row rowScanner, extra ...interface{}) (*conversation.Conversation, error) {
+	var (
+		c     conversation.Conversation
+		name  sql.NullString
+		topic sql.NullString
+	)
+	dest := append([]interface{}{&c.ID, &name, &c.IsGroup, &topic, &c.CreatedAt, &c.UpdatedAt, &c.SlowModeSeconds, &c.AnnouncementOnly}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	c.Name, c.Topic = name.String, topic.String
+	return &c, nil
+}
+
+func (r *MySQLConversationRepo) Get(ctx context.Context, id int64) (*conversation.Conversation, error) {
+	c, err := scanConversation(r.db.QueryRowContext(ctx,
+		"SELECT "+conversationColumns+" FROM conversations c WHERE c.id = ?",
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, conversation.ErrNotFound
+	}
+	return c, err
+}
+
+func (r *MySQLConversationRepo) List(ctx context.Context, q conversation.Query) ([]conversation.Summary, error) {
+	query := "SELECT " + conversationColumns + `,
+		(SELECT COUNT(*) FROM conversation_users cu WHERE cu.conversation_id = c.id)
+		FROM conversations c WHERE 1 = 1`
+	var args []interface{}
+	if q.Name != "" {
+		query += " AND c.name LIKE ?"
+		args = append(args, "%"+escapeLike(q.Name)+"%")
+	}
+	if q.MemberID != 0 {
+		query += " AND EXISTS(SELECT 1 FROM conversation_users cu WHERE cu.conversation_id = c.id AND cu.user_id = ?)"
+		args = append(args, q.MemberID)
+	}
+	query += " ORDER BY c.updated_at DESC, c.id DESC LIMIT ? OFFSET ?"
+	args = append(args, q.Limit, q.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []conversation.Summary{}
+	for rows.Next() {
+		var members int
+		c, err := scanConversation(rows, &members)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, conversation.Summary{Conversation: *c, MemberCount: members})
+	}
+	return list, rows.Err()
+}
+
+func (r *MySQLConversationRepo) IsMember(ctx context.Context, conversationID, userID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM conversation_users WHERE conversation_id = ? AND user_id = ?)",
+		conversationID, userID,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (r *MySQLConversationRepo) IsAdmin(ctx context.Context, conversationID, userID int64) (bool, error) {
+	var admin bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM conversation_users WHERE conversation_id = ? AND user_id = ? AND is_admin)",
+		conversationID, userID,
+	).Scan(&admin)
+	return admin, err
+}
+
+func (r *MySQLConversationRepo) AddMember(ctx context.Context, conversationID, userID int64) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO conversation_users (conversation_id, user_id) VALUES (?, ?)",
+		conversationID, userID,
+	)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlDuplicateEntry:
+			return conversation.ErrAlreadyMember
+		case mysqlForeignKeyViolation:
+			return conversation.ErrUnknownUser
+		}
+	}
+	return err
+}
+
+func (r *MySQLConversationRepo) RemoveMember(ctx context.Context, conversationID, userID int64) error {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM conversation_users WHERE conversation_id = ? AND user_id = ?",
+		conversationID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return conversation.ErrNotMember
+	}
+	return nil
+}
+
+func (r *MySQLConversationRepo) SetTopic(ctx context.Context, conversationID int64, topic string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE conversations SET topic = NULLIF(?, '') WHERE id = ?",
+		topic, conversationID,
+	)
+	return err
+}
+
+func (r *MySQLConversationRepo) SetPostingRules(ctx context.Context, conversationID int64, slowModeSeconds int, announcementOnly bool) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE conversations SET slow_mode_seconds = ?, announcement_only = ? WHERE id = ?",
+		slowModeSeconds, announcementOnly, conversationID,
+	)
+	return err
+}
+
+func (r *MySQLConversationRepo) MemberIDs(ctx context.Context, conversationID int64) ([]int64, error) {
+	return r.userIDs(ctx, "SELECT user_id FROM conversation_users WHERE conversation_id = ?", conversationID)
+}
+
+func (r *MySQLConversationRepo) MemberIDsByUsername(ctx context.Context, conversationID int64, usernames []string) ([]int64, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{conversationID}
+	for _, name := range usernames {
+		args = append(args, name)
+	}
+	return r.userIDs(ctx,
+		`SELECT cu.user_id FROM conversation_users cu JOIN users u ON u.id = cu.user_id
+		WHERE cu.conversation_id = ? AND u.username IN (`+placeholders(len(usernames))+")",
+		args...,
+	)
+}
+
+func (r *MySQLConversationRepo) SetMutedUntil(ctx context.Context, conversationID, userID int64, until *time.Time) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE conversation_users SET muted_until = ? WHERE conversation_id = ? AND user_id = ?",
+		until, conversationID, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return conversation.ErrNotMember
+	}
+	return nil
+}
+
+func (r *MySQLConversationRepo) MarkRead(ctx context.Context, conversationID, userID int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE conversation_users SET last_read_at = GREATEST(COALESCE(last_read_at, ?), ?) WHERE conversation_id = ? AND user_id = ?",
+		at, at, conversationID, userID,
+	)
+	return err
+}
+
+func (r *MySQLConversationRepo) MarkAllRead(ctx context.Context, userID int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE conversation_users SET last_read_at = GREATEST(COALESCE(last_read_at, ?), ?) WHERE user_id = ?",
+		at, at, userID,
+	)
+	return err
+}
+
+func (r *MySQLConversationRepo) MutedUserIDs(ctx context.Context, conversationID int64, now time.Time) ([]int64, error) {
+	return r.userIDs(ctx,
+		"SELECT user_id FROM conversation_users WHERE conversation_id = ? AND muted_until > ?",
+		conversationID, now,
+	)
+}
+
+// userIDs runs a query selecting a single user id column.
+func (r *MySQLConversationRepo) userIDs(ctx context.Context, query string, args ...interface{}) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"backend/internal/domain/digest"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type MySQLDigestRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLDigestRepo(db *sql.DB) *MySQLDigestRepo {
+	return &MySQLDigestRepo{db: db}
+}
+
+func (r *MySQLDigestRepo) Frequency(ctx context.Context, userID int64) (digest.Frequency, error) {
+	var f digest.Frequency
+	err := r.db.QueryRowContext(ctx,
+		"SELECT digest_frequency FROM email_preferences WHERE user_id = ?", userID,
+	).Scan(&f)
+	if errors.Is(err, sql.ErrNoRows) {
+		return digest.DefaultFrequency, nil
+	}
+	return f, err
+}
+
+func (r *MySQLDigestRepo) SetFrequency(ctx context.Context, userID int64, f digest.Frequency) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO email_preferences (user_id, digest_frequency) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE digest_frequency = VALUES(digest_frequency)`,
+		userID, f,
+	)
+	return err
+}
+
+func (r *MySQLDigestRepo) Recipients(ctx context.Context) ([]digest.Recipient, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT u.id, u.username, u.email, COALESCE(p.digest_frequency, ?), p.last_digest_at
+		FROM users u
+		LEFT JOIN email_preferences p ON p.user_id = u.id
+		WHERE u.email IS NOT NULL AND u.email != '' AND COALESCE(p.digest_frequency, ?) != ?`,
+		digest.DefaultFrequency, digest.DefaultFrequency, digest.Off,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []digest.Recipient
+	for rows.Next() {
+		var (
+			rc         digest.Recipient
+			lastCutoff sql.NullTime
+		)
+		if err := rows.Scan(&rc.UserID, &rc.Username, &rc.Email, &rc.Frequency, &lastCutoff); err != nil {
+			return nil, err
+		}
+		if lastCutoff.Valid {
+			rc.LastCutoff = &lastCutoff.Time
+		}
+		recipients = append(recipients, rc)
+	}
+	return recipients, rows.Err()
+}
+
+func (r *MySQLDigestRepo) Unread(ctx context.Context, userID int64, since, until time.Time, limit int) ([]digest.Item, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT m.id, m.conversation_id, COALESCE(c.name, ''), u.username, m.content, m.created_at
+		FROM messages m
+		JOIN conversation_users cu ON cu.conversation_id = m.conversation_id AND cu.user_id = ?
+		JOIN conversations c ON c.id = m.conversation_id
+		JOIN users u ON u.id = m.sender_id
+		WHERE (cu.last_read_at IS NULL OR m.created_at > cu.last_read_at)
+			AND m.deleted_at IS NULL AND m.shadowed = FALSE AND m.sender_id != ?
+			AND m.created_at > ? AND m.created_at <= ?
+			AND (cu.muted_until IS NULL OR cu.muted_until <= ?
+				OR EXISTS(SELECT 1 FROM mentions mn WHERE mn.message_id = m.id AND mn.user_id = cu.user_id))
+		ORDER BY m.created_at ASC
+		LIMIT ?`,
+		userID, userID, since, until, until, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []digest.Item
+	for rows.Next() {
+		var it digest.Item
+		if err := rows.Scan(&it.MessageID, &it.ConversationID, &it.ConversationName, &it.SenderName, &it.Content, &it.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+func (r *MySQLDigestRepo) MarkSent(ctx context.Context, userID int64, cutoff time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO email_preferences (user_id, last_digest_at) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE last_digest_at = VALUES(last_digest_at)`,
+		userID, cutoff,
+	)
+	return err
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"backend/internal/domain/webhook"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+)
+
+const incomingWebhookColumns = "id, conversation_id, creator_id, bot_user_id, name, created_at, revoked_at"
+
+type MySQLIncomingWebhookRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLIncomingWebhookRepo(db *sql.DB) *MySQLIncomingWebhookRepo {
+	return &MySQLIncomingWebhookRepo{db: db}
+}
+
+func scanIncomingWebhook(row rowScanner) (*webhook.Incoming, error) {
+	var (
+		w         webhook.Incoming
+		revokedAt sql.NullTime
+	)
+	if err := row.Scan(&w.ID, &w.ConversationID, &w.CreatorID, &w.BotUserID, &w.Name, &w.CreatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		w.RevokedAt = &revokedAt.Time
+	}
+	return &w, nil
+}
+
+func (r *MySQLIncomingWebhookRepo) Create(ctx context.Context, w *webhook.Incoming, tokenHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Bot users cannot log in: they have no password hash. The random
+	// suffix keeps their usernames unique across webhooks of the same name.
+	var suffix [4]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO users (username, password_hash, is_bot) VALUES (?, '', TRUE)",
+		"hook_"+hex.EncodeToString(suffix[:]),
+	)
+	if err != nil {
+		return err
+	}
+	if w.BotUserID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO conversation_users (conversation_id, user_id) VALUES (?, ?)",
+		w.ConversationID, w.BotUserID,
+	); err != nil {
+		return err
+	}
+
+	res, err = tx.ExecContext(ctx,
+		"INSERT INTO incoming_webhooks (conversation_id, creator_id, bot_user_id, name, token_hash) VALUES (?, ?, ?, ?, ?)",
+		w.ConversationID, w.CreatorID, w.BotUserID, w.Name, tokenHash,
+	)
+	if err != nil {
+		return err
+	}
+	if w.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT created_at FROM incoming_webhooks WHERE id = ?", w.ID).Scan(&w.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *MySQLIncomingWebhookRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*webhook.Incoming, error) {
+	w, err := scanIncomingWebhook(r.db.QueryRowContext(ctx,
+		"SELECT "+incomingWebhookColumns+" FROM incoming_webhooks WHERE token_hash = ? AND revoked_at IS NULL",
+		tokenHash,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, webhook.ErrInvalidToken
+	}
+	return w, err
+}
+
+func (r *MySQLIncomingWebhookRepo) ListByConversation(ctx context.Context, conversationID int64) ([]webhook.Incoming, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+incomingWebhookColumns+" FROM incoming_webhooks WHERE conversation_id = ? ORDER BY id",
+		conversationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []webhook.Incoming{}
+	for rows.Next() {
+		w, err := scanIncomingWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *w)
+	}
+	return hooks, rows.Err()
+}
+
+func (r *MySQLIncomingWebhookRepo) Revoke(ctx context.Context, conversationID, id int64) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE incoming_webhooks SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND conversation_id = ? AND revoked_at IS NULL",
+		id, conversationID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return webhook.ErrNotFound
+	}
+	return nil
+}
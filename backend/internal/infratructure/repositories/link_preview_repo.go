@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"backend/internal/domain/message"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisLinkPreviewCache implements message.LinkPreviewCache. Keys are hashed
+// so arbitrarily long URLs map to fixed size keys.
+type RedisLinkPreviewCache struct {
+	client *redis.Client
+}
+
+func NewRedisLinkPreviewCache(client *redis.Client) *RedisLinkPreviewCache {
+	return &RedisLinkPreviewCache{client: client}
+}
+
+func linkPreviewKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return "link_preview:" + hex.EncodeToString(sum[:])
+}
+
+func (c *RedisLinkPreviewCache) Get(ctx context.Context, url string) (*message.LinkPreview, bool, error) {
+	data, err := c.client.Get(ctx, linkPreviewKey(url)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var preview *message.LinkPreview
+	if err := json.Unmarshal(data, &preview); err != nil {
+		return nil, false, err
+	}
+	return preview, true, nil
+}
+
+func (c *RedisLinkPreviewCache) Set(ctx context.Context, url string, preview *message.LinkPreview, ttl time.Duration) error {
+	data, err := json.Marshal(preview)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, linkPreviewKey(url), data, ttl).Err()
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"backend/internal/domain/message"
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// mentionMessageColumns are the messageColumns of the joined message table.
+const mentionMessageColumns = `m.id, m.sender_id, m.conversation_id, m.content, COALESCE(m.media_url, ''), m.is_read, m.created_at, m.edited_at, m.deleted_at,
+	m.parent_id, m.reply_count, m.last_reply_at, m.link_previews, m.embeds, m.is_bot, m.shadowed`
+
+type MySQLMentionRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLMentionRepo(db *sql.DB) *MySQLMentionRepo {
+	return &MySQLMentionRepo{db: db}
+}
+
+func (r *MySQLMentionRepo) Create(ctx context.Context, mentions []message.Mention) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	rows := make([]string, len(mentions))
+	args := make([]interface{}, 0, len(mentions)*5)
+	for i, m := range mentions {
+		rows[i] = "(?, ?, ?, ?, ?)"
+		args = append(args, m.MessageID, m.ConversationID, m.UserID, m.SenderID, m.Kind)
+	}
+	_, err := r.db.ExecContext(ctx,
+		"INSERT IGNORE INTO mentions (message_id, conversation_id, user_id, sender_id, kind) VALUES "+strings.Join(rows, ", "),
+		args...,
+	)
+	return err
+}
+
+func (r *MySQLMentionRepo) ListForUser(ctx context.Context, userID int64, limit, offset int) ([]message.Mention, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT mn.id, mn.message_id, mn.conversation_id, mn.user_id, mn.sender_id, mn.kind, mn.created_at, `+mentionMessageColumns+`
+		FROM mentions mn
+		JOIN messages m ON m.id = mn.message_id
+		JOIN conversation_users cu ON cu.conversation_id = mn.conversation_id AND cu.user_id = mn.user_id
+		WHERE mn.user_id = ? AND m.deleted_at IS NULL
+		ORDER BY mn.created_at DESC, mn.id DESC
+		LIMIT ? OFFSET ?`,
+		userID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []message.Mention{}
+	for rows.Next() {
+		var mn message.Mention
+		msg, err := scanMessage(prefixScanner{rows, []interface{}{
+			&mn.ID, &mn.MessageID, &mn.ConversationID, &mn.UserID, &mn.SenderID, &mn.Kind, &mn.CreatedAt,
+		}})
+		if err != nil {
+			return nil, err
+		}
+		mn.Message = msg
+		mentions = append(mentions, mn)
+	}
+	return mentions, rows.Err()
+}
+
+// prefixScanner scans leading columns into prefix before handing the rest
+// to the caller's destinations, so scanMessage can read joined rows.
+type prefixScanner struct {
+	row    rowScanner
+	prefix []interface{}
+}
+
+func (p prefixScanner) Scan(dest ...interface{}) error {
+	return p.row.Scan(append(p.prefix, dest...)...)
+}
@@ -0,0 +1,284 @@
+package repositories
+
+import (
+	"backend/internal/domain/message"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+const messageColumns = `id, sender_id, conversation_id, content, COALESCE(media_url, ''), is_read, created_at, edited_at, deleted_at,
+	parent_id, reply_count, last_reply_at, link_previews, embeds, is_bot, shadowed`
+
+// visibleTo leaves out the messages hidden from a viewer: the shadowed
+// messages of other users and, in group conversations, the messages of users
+// the viewer blocked. Direct conversations keep blocked users' messages,
+// since blocking stops new messages there. It takes the viewer's id twice.
+const visibleTo = ` AND (shadowed = FALSE OR sender_id = ?)
+	AND NOT EXISTS (
+		SELECT 1 FROM user_blocks b JOIN conversations c ON c.id = messages.conversation_id
+		WHERE c.is_group AND b.blocker_id = ? AND b.blocked_id = messages.sender_id
+	)`
+
+type MySQLMessageRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLMessageRepo(db *sql.DB) *MySQLMessageRepo {
+	return &MySQLMessageRepo{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMessage(row rowScanner) (*message.Message, error) {
+	var (
+		m           message.Message
+		editedAt    sql.NullTime
+		deletedAt   sql.NullTime
+		parentID    sql.NullInt64
+		lastReplyAt sql.NullTime
+		previews    []byte
+		embeds      []byte
+	)
+	err := row.Scan(&m.ID, &m.SenderID, &m.ConversationID, &m.Content, &m.MediaURL, &m.IsRead, &m.CreatedAt, &editedAt, &deletedAt,
+		&parentID, &m.ReplyCount, &lastReplyAt, &previews, &embeds, &m.IsBot, &m.Shadowed)
+	if err != nil {
+		return nil, err
+	}
+	if len(previews) > 0 {
+		if err := json.Unmarshal(previews, &m.LinkPreviews); err != nil {
+			return nil, err
+		}
+	}
+	if len(embeds) > 0 {
+		if err := json.Unmarshal(embeds, &m.Embeds); err != nil {
+			return nil, err
+		}
+	}
+	if parentID.Valid {
+		m.ParentID = &parentID.Int64
+	}
+	if lastReplyAt.Valid {
+		m.LastReplyAt = &lastReplyAt.Time
+	}
+	if editedAt.Valid {
+		m.EditedAt = &editedAt.Time
+	}
+	if deletedAt.Valid {
+		m.DeletedAt = &deletedAt.Time
+	}
+	return &m, nil
+}
+
+func (r *MySQLMessageRepo) Create(ctx context.Context, m *message.Message, attachmentIDs []int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var parentID sql.NullInt64
+	if m.ParentID != nil {
+		parentID = sql.NullInt64{Int64: *m.ParentID, Valid: true}
+	}
+	var embeds []byte
+	if len(m.Embeds) > 0 {
+		if embeds, err = json.Marshal(m.Embeds); err != nil {
+			return err
+		}
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO messages (sender_id, conversation_id, content, media_url, parent_id, embeds, shadowed, is_bot)
+		SELECT ?, ?, ?, NULLIF(?, ''), ?, ?, ?, is_bot FROM users WHERE id = ?`,
+		m.SenderID, m.ConversationID, m.Content, m.MediaURL, parentID, embeds, m.Shadowed, m.SenderID,
+	)
+	if err != nil {
+		return err
+	}
+	if m.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT created_at, is_bot FROM messages WHERE id = ?", m.ID).Scan(&m.CreatedAt, &m.IsBot); err != nil {
+		return err
+	}
+
+	// A shadowed reply is only shown to its sender, so it must not bump the
+	// thread everyone else sees.
+	if parentID.Valid && !m.Shadowed {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE messages SET reply_count = reply_count + 1, last_reply_at = ? WHERE id = ?",
+			m.CreatedAt, parentID.Int64,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(attachmentIDs) > 0 {
+		args := []interface{}{m.ID, m.SenderID}
+		for _, id := range attachmentIDs {
+			args = append(args, id)
+		}
+		res, err := tx.ExecContext(ctx,
+			"UPDATE attachments SET message_id = ? WHERE uploader_id = ? AND message_id IS NULL AND id IN ("+placeholders(len(attachmentIDs))+")",
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if int(n) != len(attachmentIDs) {
+			return message.ErrAttachmentNotOwned
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *MySQLMessageRepo) Get(ctx context.Context, id int64) (*message.Message, error) {
+	m, err := scanMessage(r.db.QueryRowContext(ctx, "SELECT "+messageColumns+" FROM messages WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, message.ErrNotFound
+	}
+	return m, err
+}
+
+func (r *MySQLMessageRepo) ListByConversation(ctx context.Context, conversationID, viewerID int64, limit, offset int) ([]message.Message, error) {
+	return r.list(ctx,
+		"SELECT "+messageColumns+" FROM messages WHERE conversation_id = ? AND parent_id IS NULL"+visibleTo+
+			" ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?",
+		conversationID, viewerID, viewerID, limit, offset,
+	)
+}
+
+func (r *MySQLMessageRepo) Thread(ctx context.Context, parentID, viewerID int64, limit, offset int) ([]message.Message, error) {
+	return r.list(ctx,
+		"SELECT "+messageColumns+" FROM messages WHERE parent_id = ?"+visibleTo+" ORDER BY created_at, id LIMIT ? OFFSET ?",
+		parentID, viewerID, viewerID, limit, offset,
+	)
+}
+
+func (r *MySQLMessageRepo) ThreadParticipantIDs(ctx context.Context, parentID int64) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT DISTINCT sender_id FROM messages WHERE id = ? OR parent_id = ?",
+		parentID, parentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// list runs a query returning message rows and tombstones deleted ones.
+func (r *MySQLMessageRepo) list(ctx context.Context, query string, args ...interface{}) ([]message.Message, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []message.Message{}
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		m.Tombstone()
+		messages = append(messages, *m)
+	}
+	return messages, rows.Err()
+}
+
+func (r *MySQLMessageRepo) UpdateContent(ctx context.Context, id int64, content string) (*message.Message, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO message_edits (message_id, content) SELECT id, content FROM messages WHERE id = ? AND deleted_at IS NULL",
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, message.ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE messages SET content = ?, edited_at = NOW() WHERE id = ?", content, id); err != nil {
+		return nil, err
+	}
+
+	m, err := scanMessage(tx.QueryRowContext(ctx, "SELECT "+messageColumns+" FROM messages WHERE id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	return m, tx.Commit()
+}
+
+func (r *MySQLMessageRepo) SetLinkPreviews(ctx context.Context, id int64, previews []message.LinkPreview) error {
+	data, err := json.Marshal(previews)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, "UPDATE messages SET link_previews = ? WHERE id = ?", data, id)
+	return err
+}
+
+func (r *MySQLMessageRepo) SoftDelete(ctx context.Context, id int64) (*message.Message, error) {
+	res, err := r.db.ExecContext(ctx, "UPDATE messages SET deleted_at = NOW() WHERE id = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, message.ErrNotFound
+	}
+
+	m, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	m.Tombstone()
+	return m, nil
+}
+
+func (r *MySQLMessageRepo) Edits(ctx context.Context, id int64) ([]message.Edit, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, message_id, content, edited_at FROM message_edits WHERE message_id = ? ORDER BY id",
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []message.Edit{}
+	for rows.Next() {
+		var e message.Edit
+		if err := rows.Scan(&e.ID, &e.MessageID, &e.Content, &e.EditedAt); err != nil {
+			return nil, err
+		}
+		edits = append(edits, e)
+	}
+	return edits, rows.Err()
+}
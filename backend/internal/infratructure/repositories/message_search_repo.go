@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"backend/internal/domain/message"
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// MySQLMessageSearchRepo implements message.Searcher on top of the FULLTEXT
+// index on messages.content.
+type MySQLMessageSearchRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLMessageSearchRepo(db *sql.DB) *MySQLMessageSearchRepo {
+	return &MySQLMessageSearchRepo{db: db}
+}
+
+func (r *MySQLMessageSearchRepo) Search(ctx context.Context, q message.SearchQuery) (*message.SearchResult, error) {
+	terms := message.Terms(q.Text)
+	result := &message.SearchResult{
+		Hits:   []message.SearchHit{},
+		Limit:  q.Limit,
+		Offset: q.Offset,
+	}
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	// Every term is required and matched as a prefix.
+	boolean := make([]string, len(terms))
+	for i, t := range terms {
+		boolean[i] = "+" + t + "*"
+	}
+	against := strings.Join(boolean, " ")
+
+	// Only messages from conversations the caller belongs to are visible,
+	// and shadowed messages only to their sender.
+	where := ` FROM messages m
+		JOIN conversation_users cu ON cu.conversation_id = m.conversation_id AND cu.user_id = ?
+		WHERE m.deleted_at IS NULL AND (m.shadowed = FALSE OR m.sender_id = cu.user_id)
+			AND MATCH(m.content) AGAINST (? IN BOOLEAN MODE)`
+	args := []interface{}{q.UserID, against}
+	if q.ConversationID != 0 {
+		where += " AND m.conversation_id = ?"
+		args = append(args, q.ConversationID)
+	}
+	if q.SenderID != 0 {
+		where += " AND m.sender_id = ?"
+		args = append(args, q.SenderID)
+	}
+	if !q.From.IsZero() {
+		where += " AND m.created_at >= ?"
+		args = append(args, q.From)
+	}
+	if !q.To.IsZero() {
+		where += " AND m.created_at < ?"
+		args = append(args, q.To)
+	}
+
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*)"+where, args...).Scan(&result.Total); err != nil {
+		return nil, err
+	}
+	if result.Total == 0 {
+		return result, nil
+	}
+
+	query := `SELECT m.id, m.sender_id, m.conversation_id, m.content, COALESCE(m.media_url, ''), m.is_read, m.created_at` +
+		where + `
+		ORDER BY MATCH(m.content) AGAINST (? IN BOOLEAN MODE) DESC, m.created_at DESC
+		LIMIT ? OFFSET ?`
+	args = append(args, against, q.Limit, q.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hit message.SearchHit
+		if err := rows.Scan(&hit.ID, &hit.SenderID, &hit.ConversationID, &hit.Content, &hit.MediaURL, &hit.IsRead, &hit.CreatedAt); err != nil {
+			return nil, err
+		}
+		hit.Highlight = message.Highlight(hit.Content, terms)
+		result.Hits = append(result.Hits, hit)
+	}
+
+	return result, rows.Err()
+}
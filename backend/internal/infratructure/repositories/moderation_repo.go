@@ -0,0 +1,303 @@
+package repositories
+
+import (
+	"backend/internal/domain/moderation"
+	"backend/internal/domain/user"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type MySQLModerationRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLModerationRepo(db *sql.DB) *MySQLModerationRepo {
+	return &MySQLModerationRepo{db: db}
+}
+
+func (r *MySQLModerationRepo) Create(ctx context.Context, f *moderation.Flag) error {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO moderation_flags (conversation_id, message_id, user_id, reporter_id, source, reason) VALUES (?, ?, ?, ?, ?, ?)",
+		f.ConversationID, f.MessageID, f.UserID, f.ReporterID, f.Source, f.Reason,
+	)
+	if err != nil {
+		return err
+	}
+	if f.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	f.Status = moderation.FlagPending
+	return r.db.QueryRowContext(ctx, "SELECT created_at FROM moderation_flags WHERE id = ?", f.ID).Scan(&f.CreatedAt)
+}
+
+const flagColumns = "id, conversation_id, message_id, user_id, reporter_id, source, reason, status, created_at, resolved_by, resolved_at"
+
+func scanFlag(row rowScanner) (*moderation.Flag, error) {
+	var (
+		f              moderation.Flag
+		conversationID sql.NullInt64
+		messageID      sql.NullInt64
+		reporterID     sql.NullInt64
+		resolvedBy     sql.NullInt64
+		resolvedAt     sql.NullTime
+	)
+	err := row.Scan(&f.ID, &conversationID, &messageID, &f.UserID, &reporterID, &f.Source, &f.Reason, &f.Status,
+		&f.CreatedAt, &resolvedBy, &resolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	f.ConversationID = nullInt64Ptr(conversationID)
+	f.MessageID = nullInt64Ptr(messageID)
+	f.ReporterID = nullInt64Ptr(reporterID)
+	f.ResolvedBy = nullInt64Ptr(resolvedBy)
+	if resolvedAt.Valid {
+		f.ResolvedAt = &resolvedAt.Time
+	}
+	return &f, nil
+}
+
+func (r *MySQLModerationRepo) GetFlag(ctx context.Context, id int64) (*moderation.Flag, error) {
+	f, err := scanFlag(r.db.QueryRowContext(ctx, "SELECT "+flagColumns+" FROM moderation_flags WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, moderation.ErrFlagNotFound
+	}
+	return f, err
+}
+
+func (r *MySQLModerationRepo) ListFlags(ctx context.Context, q moderation.FlagQuery) ([]moderation.Flag, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if q.ConversationID != 0 {
+		where = append(where, "conversation_id = ?")
+		args = append(args, q.ConversationID)
+	}
+	if q.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, q.Status)
+	}
+	if q.Source != "" {
+		where = append(where, "source = ?")
+		args = append(args, q.Source)
+	}
+	query := "SELECT " + flagColumns + " FROM moderation_flags"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at, id LIMIT ? OFFSET ?"
+	args = append(args, q.Limit, q.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := []moderation.Flag{}
+	for rows.Next() {
+		f, err := scanFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, *f)
+	}
+	return flags, rows.Err()
+}
+
+func (r *MySQLModerationRepo) ResolveFlag(ctx context.Context, id, resolvedBy int64) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE moderation_flags SET status = ?, resolved_by = ?, resolved_at = CURRENT_TIMESTAMP WHERE id = ?",
+		moderation.FlagResolved, resolvedBy, id,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return moderation.ErrFlagNotFound
+	}
+	return nil
+}
+
+func (r *MySQLModerationRepo) Sanction(ctx context.Context, s *moderation.Sanction) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO conversation_sanctions (conversation_id, user_id, kind, reason, expires_at, created_by) VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE reason = VALUES(reason), expires_at = VALUES(expires_at),
+		created_by = VALUES(created_by), created_at = CURRENT_TIMESTAMP`,
+		s.ConversationID, s.UserID, s.Kind, s.Reason, s.ExpiresAt, s.CreatedBy,
+	)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlForeignKeyViolation {
+		return user.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx,
+		"SELECT created_at FROM conversation_sanctions WHERE conversation_id = ? AND user_id = ? AND kind = ?",
+		s.ConversationID, s.UserID, s.Kind,
+	).Scan(&s.CreatedAt)
+}
+
+func (r *MySQLModerationRepo) Lift(ctx context.Context, conversationID, userID int64, kind moderation.SanctionKind) error {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM conversation_sanctions WHERE conversation_id = ? AND user_id = ? AND kind = ?",
+		conversationID, userID, kind,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return moderation.ErrNotSanctioned
+	}
+	return nil
+}
+
+const sanctionColumns = "conversation_id, user_id, kind, reason, expires_at, created_by, created_at"
+
+func scanSanction(row rowScanner) (*moderation.Sanction, error) {
+	var (
+		s         moderation.Sanction
+		expiresAt sql.NullTime
+	)
+	if err := row.Scan(&s.ConversationID, &s.UserID, &s.Kind, &s.Reason, &expiresAt, &s.CreatedBy, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		s.ExpiresAt = &expiresAt.Time
+	}
+	return &s, nil
+}
+
+func (r *MySQLModerationRepo) ActiveSanction(ctx context.Context, conversationID, userID int64, kind moderation.SanctionKind, now time.Time) (*moderation.Sanction, error) {
+	s, err := scanSanction(r.db.QueryRowContext(ctx,
+		"SELECT "+sanctionColumns+` FROM conversation_sanctions
+		WHERE conversation_id = ? AND user_id = ? AND kind = ? AND (expires_at IS NULL OR expires_at > ?)`,
+		conversationID, userID, kind, now,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
+
+func (r *MySQLModerationRepo) ListSanctions(ctx context.Context, conversationID int64, now time.Time) ([]moderation.Sanction, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+sanctionColumns+` FROM conversation_sanctions
+		WHERE conversation_id = ? AND (expires_at IS NULL OR expires_at > ?) ORDER BY created_at`,
+		conversationID, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sanctions := []moderation.Sanction{}
+	for rows.Next() {
+		s, err := scanSanction(rows)
+		if err != nil {
+			return nil, err
+		}
+		sanctions = append(sanctions, *s)
+	}
+	return sanctions, rows.Err()
+}
+
+func (r *MySQLModerationRepo) Record(ctx context.Context, e *moderation.AuditEntry) error {
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO moderation_audit_log (conversation_id, actor_id, action, target_user_id, message_id, flag_id, reason, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ConversationID, e.ActorID, e.Action, e.TargetUserID, e.MessageID, e.FlagID, e.Reason, e.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+	if e.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx, "SELECT created_at FROM moderation_audit_log WHERE id = ?", e.ID).Scan(&e.CreatedAt)
+}
+
+func (r *MySQLModerationRepo) AuditLog(ctx context.Context, conversationID int64, limit, offset int) ([]moderation.AuditEntry, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, conversation_id, actor_id, action, target_user_id, message_id, flag_id, reason, expires_at, created_at
+		FROM moderation_audit_log WHERE conversation_id = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`,
+		conversationID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []moderation.AuditEntry{}
+	for rows.Next() {
+		var (
+			e                                   moderation.AuditEntry
+			convID, targetID, messageID, flagID sql.NullInt64
+			expiresAt                           sql.NullTime
+		)
+		err := rows.Scan(&e.ID, &convID, &e.ActorID, &e.Action, &targetID, &messageID, &flagID, &e.Reason, &expiresAt, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.ConversationID = nullInt64Ptr(convID)
+		e.TargetUserID = nullInt64Ptr(targetID)
+		e.MessageID = nullInt64Ptr(messageID)
+		e.FlagID = nullInt64Ptr(flagID)
+		if expiresAt.Valid {
+			e.ExpiresAt = &expiresAt.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *MySQLModerationRepo) FilterSettings(ctx context.Context, conversationID int64) (moderation.FilterSettings, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT filter, action FROM conversation_filters WHERE conversation_id = ?",
+		conversationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := moderation.FilterSettings{}
+	for rows.Next() {
+		var (
+			filter string
+			action moderation.Action
+		)
+		if err := rows.Scan(&filter, &action); err != nil {
+			return nil, err
+		}
+		settings[filter] = action
+	}
+	return settings, rows.Err()
+}
+
+func (r *MySQLModerationRepo) SetFilterAction(ctx context.Context, conversationID int64, filter string, action moderation.Action) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO conversation_filters (conversation_id, filter, action) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE action = VALUES(action)`,
+		conversationID, filter, action,
+	)
+	return err
+}
+
+// nullInt64Ptr returns a pointer to the value of n, or nil if it is NULL.
+func nullInt64Ptr(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int64
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"backend/internal/domain/push"
+	"context"
+	"database/sql"
+)
+
+type MySQLPushSubscriptionRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLPushSubscriptionRepo(db *sql.DB) *MySQLPushSubscriptionRepo {
+	return &MySQLPushSubscriptionRepo{db: db}
+}
+
+func (r *MySQLPushSubscriptionRepo) Save(ctx context.Context, s *push.Subscription) error {
+	// An endpoint registered by another user is left untouched.
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, expires_at) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			p256dh = IF(user_id = VALUES(user_id), VALUES(p256dh), p256dh),
+			auth = IF(user_id = VALUES(user_id), VALUES(auth), auth),
+			expires_at = IF(user_id = VALUES(user_id), VALUES(expires_at), expires_at)`,
+		s.UserID, s.Endpoint, s.P256dh, s.Auth, s.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+	var owner int64
+	if err := r.db.QueryRowContext(ctx,
+		"SELECT id, user_id, created_at FROM push_subscriptions WHERE endpoint = ?", s.Endpoint,
+	).Scan(&s.ID, &owner, &s.CreatedAt); err != nil {
+		return err
+	}
+	if owner != s.UserID {
+		s.ID = 0
+		return push.ErrEndpointTaken
+	}
+	return nil
+}
+
+func (r *MySQLPushSubscriptionRepo) ListByUsers(ctx context.Context, userIDs []int64) ([]push.Subscription, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		args[i] = id
+	}
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, user_id, endpoint, p256dh, auth, expires_at, created_at FROM push_subscriptions WHERE user_id IN ("+placeholders(len(userIDs))+")",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []push.Subscription
+	for rows.Next() {
+		var (
+			s         push.Subscription
+			expiresAt sql.NullTime
+		)
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Endpoint, &s.P256dh, &s.Auth, &expiresAt, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			s.ExpiresAt = &expiresAt.Time
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+func (r *MySQLPushSubscriptionRepo) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM push_subscriptions WHERE id = ?", id)
+	return err
+}
+
+func (r *MySQLPushSubscriptionRepo) DeleteForUser(ctx context.Context, id, userID int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM push_subscriptions WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return push.ErrSubscriptionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"backend/internal/domain/moderation"
+	"backend/internal/domain/ratelimit"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisRateLimiter is a fixed window counter shared by all server instances.
+type RedisRateLimiter struct {
+	client *redis.Client
+}
+
+func NewRedisRateLimiter(client *redis.Client) *RedisRateLimiter {
+	return &RedisRateLimiter{client: client}
+}
+
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (ratelimit.Result, error) {
+	now := time.Now()
+	start := now.Truncate(window)
+	k := "rate_limit:" + key + ":" + strconv.FormatInt(start.Unix(), 10)
+
+	pipe := l.client.TxPipeline()
+	count := pipe.Incr(ctx, k)
+	pipe.Expire(ctx, k, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	if count.Val() > int64(limit) {
+		return ratelimit.Result{RetryAfter: start.Add(window).Sub(now)}, nil
+	}
+	return ratelimit.Result{Allowed: true}, nil
+}
+
+// takeTokenScript refills and takes from a token bucket stored as a hash of
+// its token count and last update time in milliseconds. It returns whether a
+// token was taken and otherwise the milliseconds until one is available.
+var takeTokenScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) / 1000 * rate)
+  ts = now
+end
+local allowed, wait = 0, 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, wait}
+`)
+
+// RedisTokenBuckets keeps token buckets in Redis so that limits hold across
+// server instances.
+type RedisTokenBuckets struct {
+	client *redis.Client
+}
+
+func NewRedisTokenBuckets(client *redis.Client) *RedisTokenBuckets {
+	return &RedisTokenBuckets{client: client}
+}
+
+func (b *RedisTokenBuckets) Take(ctx context.Context, key string, rate ratelimit.Rate) (ratelimit.Result, error) {
+	res, err := takeTokenScript.Run(ctx, b.client, []string{"token_bucket:" + key},
+		rate.PerSecond, rate.Burst, time.Now().UnixMilli(),
+	).Int64Slice()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	if res[0] == 1 {
+		return ratelimit.Result{Allowed: true}, nil
+	}
+	return ratelimit.Result{RetryAfter: time.Duration(res[1]) * time.Millisecond}, nil
+}
+
+// RedisCooldowns keeps cooldowns as expiring keys.
+type RedisCooldowns struct {
+	client *redis.Client
+}
+
+func NewRedisCooldowns(client *redis.Client) *RedisCooldowns {
+	return &RedisCooldowns{client: client}
+}
+
+func (c *RedisCooldowns) Start(ctx context.Context, key string, d time.Duration) (ratelimit.Result, error) {
+	k := "cooldown:" + key
+	ok, err := c.client.SetNX(ctx, k, 1, d).Result()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	if ok {
+		return ratelimit.Result{Allowed: true}, nil
+	}
+
+	ttl, err := c.client.PTTL(ctx, k).Result()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	if ttl <= 0 {
+		// The cooldown expired in between; the next attempt will succeed.
+		ttl = time.Millisecond
+	}
+	return ratelimit.Result{RetryAfter: ttl}, nil
+}
+
+// RedisWindows keeps sliding windows as sorted sets of members scored by the
+// time they were last recorded.
+type RedisWindows struct {
+	client *redis.Client
+}
+
+func NewRedisWindows(client *redis.Client) *RedisWindows {
+	return &RedisWindows{client: client}
+}
+
+func (w *RedisWindows) Add(ctx context.Context, key, member string, window time.Duration) (int, error) {
+	k := "window:" + key
+	now := time.Now().UnixMilli()
+
+	pipe := w.client.TxPipeline()
+	pipe.ZAdd(ctx, k, redis.Z{Score: float64(now), Member: member})
+	pipe.ZRemRangeByScore(ctx, k, "-inf", strconv.FormatInt(now-window.Milliseconds(), 10))
+	count := pipe.ZCard(ctx, k)
+	pipe.PExpire(ctx, k, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(count.Val()), nil
+}
+
+// RedisRestrictions keeps account restrictions as expiring keys.
+type RedisRestrictions struct {
+	client *redis.Client
+}
+
+func NewRedisRestrictions(client *redis.Client) *RedisRestrictions {
+	return &RedisRestrictions{client: client}
+}
+
+func restrictionKey(userID int64, r moderation.Restriction) string {
+	return "restriction:" + string(r) + ":" + strconv.FormatInt(userID, 10)
+}
+
+func (s *RedisRestrictions) Restrict(ctx context.Context, userID int64, r moderation.Restriction, d time.Duration) error {
+	return s.client.Set(ctx, restrictionKey(userID, r), 1, d).Err()
+}
+
+func (s *RedisRestrictions) Restricted(ctx context.Context, userID int64, r moderation.Restriction) (bool, error) {
+	n, err := s.client.Exists(ctx, restrictionKey(userID, r)).Result()
+	return n > 0, err
+}
+
+func (s *RedisRestrictions) Lift(ctx context.Context, userID int64) error {
+	return s.client.Del(ctx,
+		restrictionKey(userID, moderation.RestrictThrottle),
+		restrictionKey(userID, moderation.RestrictShadowMute),
+	).Err()
+}
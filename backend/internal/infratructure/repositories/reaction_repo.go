@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"backend/internal/domain/message"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type MySQLReactionRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLReactionRepo(db *sql.DB) *MySQLReactionRepo {
+	return &MySQLReactionRepo{db: db}
+}
+
+func (r *MySQLReactionRepo) Add(ctx context.Context, reaction message.Reaction) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO message_reactions (message_id, user_id, emoji) VALUES (?, ?, ?)",
+		reaction.MessageID, reaction.UserID, reaction.Emoji,
+	)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return message.ErrAlreadyReacted
+	}
+	return err
+}
+
+func (r *MySQLReactionRepo) Remove(ctx context.Context, reaction message.Reaction) error {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM message_reactions WHERE message_id = ? AND user_id = ? AND emoji = ?",
+		reaction.MessageID, reaction.UserID, reaction.Emoji,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return message.ErrReactionMissing
+	}
+	return nil
+}
+
+func (r *MySQLReactionRepo) Counts(ctx context.Context, messageIDs []int64, userID int64) (map[int64][]message.ReactionCount, error) {
+	counts := make(map[int64][]message.ReactionCount, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return counts, nil
+	}
+
+	args := []interface{}{userID}
+	for _, id := range messageIDs {
+		args = append(args, id)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT message_id, emoji, COUNT(*), MAX(user_id = ?)
+		FROM message_reactions
+		WHERE message_id IN (`+placeholders(len(messageIDs))+`)
+		GROUP BY message_id, emoji
+		ORDER BY message_id, MIN(created_at)`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			messageID int64
+			count     message.ReactionCount
+		)
+		if err := rows.Scan(&messageID, &count.Emoji, &count.Count, &count.Reacted); err != nil {
+			return nil, err
+		}
+		counts[messageID] = append(counts[messageID], count)
+	}
+	return counts, rows.Err()
+}
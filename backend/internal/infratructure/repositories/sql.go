@@ -0,0 +1,20 @@
+package repositories
+
+import "strings"
+
+// MySQL error numbers for unique key and foreign key violations.
+const (
+	mysqlDuplicateEntry      = 1062
+	mysqlForeignKeyViolation = 1452
+)
+
+// escapeLike escapes the wildcards of a LIKE pattern so s matches
+// literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// placeholders returns n comma separated "?" for use in an IN clause.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"backend/internal/domain/user"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type MySQLUserRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLUserRepo(db *sql.DB) *MySQLUserRepo {
+	return &MySQLUserRepo{db: db}
+}
+
+const userColumns = "id, username, is_bot, created_at, banned_at"
+
+func scanUser(row rowScanner) (*user.User, error) {
+	var (
+		u        user.User
+		bannedAt sql.NullTime
+	)
+	if err := row.Scan(&u.ID, &u.Username, &u.IsBot, &u.CreatedAt, &bannedAt); err != nil {
+		return nil, err
+	}
+	if bannedAt.Valid {
+		u.BannedAt = &bannedAt.Time
+	}
+	return &u, nil
+}
+
+func (r *MySQLUserRepo) Get(ctx context.Context, id int64) (*user.User, error) {
+	return r.get(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", id)
+}
+
+func (r *MySQLUserRepo) GetByUsername(ctx context.Context, username string) (*user.User, error) {
+	return r.get(ctx, "SELECT "+userColumns+" FROM users WHERE username = ?", username)
+}
+
+func (r *MySQLUserRepo) get(ctx context.Context, query string, arg interface{}) (*user.User, error) {
+	u, err := scanUser(r.db.QueryRowContext(ctx, query, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, user.ErrNotFound
+	}
+	return u, err
+}
+
+func (r *MySQLUserRepo) Search(ctx context.Context, prefix string, limit int) ([]user.User, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE username LIKE ? ORDER BY username LIMIT ?",
+		escapeLike(prefix)+"%", limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []user.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+	return users, rows.Err()
+}
+
+func (r *MySQLUserRepo) IsBanned(ctx context.Context, id int64) (bool, error) {
+	var banned bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM users WHERE id = ? AND banned_at IS NOT NULL)",
+		id,
+	).Scan(&banned)
+	return banned, err
+}
+
+func (r *MySQLUserRepo) SetBanned(ctx context.Context, id int64, banned bool) error {
+	query := "UPDATE users SET banned_at = NULL WHERE id = ?"
+	if banned {
+		query = "UPDATE users SET banned_at = COALESCE(banned_at, CURRENT_TIMESTAMP) WHERE id = ?"
+	}
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		// MySQL reports unchanged rows as unaffected, so tell them apart
+		// from unknown users.
+		if _, err := r.Get(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
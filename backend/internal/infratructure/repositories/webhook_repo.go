@@ -0,0 +1,227 @@
+package repositories
+
+import (
+	"backend/internal/domain/webhook"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+const webhookColumns = "id, owner_id, conversation_id, url, secret, events, created_at"
+
+type MySQLWebhookRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLWebhookRepo(db *sql.DB) *MySQLWebhookRepo {
+	return &MySQLWebhookRepo{db: db}
+}
+
+func scanWebhook(row rowScanner) (*webhook.Webhook, error) {
+	var (
+		w              webhook.Webhook
+		conversationID sql.NullInt64
+		events         string
+	)
+	if err := row.Scan(&w.ID, &w.OwnerID, &conversationID, &w.URL, &w.Secret, &events, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	if conversationID.Valid {
+		w.ConversationID = &conversationID.Int64
+	}
+	w.Events = strings.Split(events, ",")
+	return &w, nil
+}
+
+func (r *MySQLWebhookRepo) Create(ctx context.Context, w *webhook.Webhook) error {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO webhooks (owner_id, conversation_id, url, secret, events) VALUES (?, ?, ?, ?, ?)",
+		w.OwnerID, w.ConversationID, w.URL, w.Secret, strings.Join(w.Events, ","),
+	)
+	if err != nil {
+		return err
+	}
+	if w.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx, "SELECT created_at FROM webhooks WHERE id = ?", w.ID).Scan(&w.CreatedAt)
+}
+
+func (r *MySQLWebhookRepo) Get(ctx context.Context, id int64) (*webhook.Webhook, error) {
+	w, err := scanWebhook(r.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, webhook.ErrNotFound
+	}
+	return w, err
+}
+
+func (r *MySQLWebhookRepo) ListByOwner(ctx context.Context, ownerID int64) ([]webhook.Webhook, error) {
+	hooks, err := r.list(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE owner_id = ? ORDER BY id", ownerID)
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, err
+}
+
+func (r *MySQLWebhookRepo) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	return err
+}
+
+func (r *MySQLWebhookRepo) Subscribers(ctx context.Context, event string, conversationID *int64) ([]webhook.Webhook, error) {
+	hooks, err := r.list(ctx,
+		"SELECT "+webhookColumns+" FROM webhooks WHERE FIND_IN_SET(?, events) AND (conversation_id IS NULL OR conversation_id = ?)",
+		event, conversationID,
+	)
+	return hooks, err
+}
+
+func (r *MySQLWebhookRepo) list(ctx context.Context, query string, args ...interface{}) ([]webhook.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []webhook.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *w)
+	}
+	return hooks, rows.Err()
+}
+
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at, last_status, COALESCE(last_error, ''),
+	created_at, delivered_at`
+
+type MySQLWebhookDeliveryRepo struct {
+	db *sql.DB
+}
+
+func NewMySQLWebhookDeliveryRepo(db *sql.DB) *MySQLWebhookDeliveryRepo {
+	return &MySQLWebhookDeliveryRepo{db: db}
+}
+
+func scanDelivery(row rowScanner) (*webhook.Delivery, error) {
+	var (
+		d           webhook.Delivery
+		lastStatus  sql.NullInt64
+		deliveredAt sql.NullTime
+	)
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &lastStatus, &d.LastError,
+		&d.CreatedAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	d.LastStatus = int(lastStatus.Int64)
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return &d, nil
+}
+
+func (r *MySQLWebhookDeliveryRepo) Enqueue(ctx context.Context, deliveries []webhook.Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	rows := make([]string, len(deliveries))
+	args := make([]interface{}, 0, len(deliveries)*4)
+	for i, d := range deliveries {
+		rows[i] = "(?, ?, ?, ?)"
+		args = append(args, d.WebhookID, d.Event, []byte(d.Payload), d.NextAttemptAt)
+	}
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at) VALUES "+strings.Join(rows, ", "),
+		args...,
+	)
+	return err
+}
+
+func (r *MySQLWebhookDeliveryRepo) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhook.Delivery, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED lets several server instances drain the queue together.
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED`,
+		webhook.StatusPending, now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []webhook.Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{now.Add(lease)}
+	for _, d := range deliveries {
+		args = append(args, d.ID)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN ("+placeholders(len(deliveries))+")",
+		args...,
+	); err != nil {
+		return nil, err
+	}
+	return deliveries, tx.Commit()
+}
+
+func (r *MySQLWebhookDeliveryRepo) Update(ctx context.Context, d *webhook.Delivery) error {
+	var lastStatus sql.NullInt64
+	if d.LastStatus != 0 {
+		lastStatus = sql.NullInt64{Int64: int64(d.LastStatus), Valid: true}
+	}
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_status = ?, last_error = NULLIF(?, ''),
+		delivered_at = ? WHERE id = ?`,
+		d.Status, d.Attempts, d.NextAttemptAt, lastStatus, d.LastError, d.DeliveredAt, d.ID,
+	)
+	return err
+}
+
+func (r *MySQLWebhookDeliveryRepo) ListByWebhook(ctx context.Context, webhookID int64, limit, offset int) ([]webhook.Delivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ? OFFSET ?",
+		webhookID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []webhook.Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,143 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// received is a message accepted by the SMTP stand-in.
+type received struct {
+	from, to string
+	data     string
+}
+
+// smtpServer is a minimal local SMTP stand-in that accepts one message per
+// connection.
+func smtpServer(t *testing.T) (addr string, messages <-chan received) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	ch := make(chan received, 1)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, ch)
+		}
+	}()
+	return l.Addr().String(), ch
+}
+
+func serveSMTP(conn net.Conn, ch chan<- received) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+
+	var msg received
+	reply("220 localhost ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.from = strings.Trim(strings.TrimPrefix(cmd, "MAIL FROM:"), "<>")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.to = strings.Trim(strings.TrimPrefix(cmd, "RCPT TO:"), "<>")
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			msg.data = data.String()
+			reply("250 OK")
+			ch <- msg
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPSend(t *testing.T) {
+	addr, messages := smtpServer(t)
+	m := &SMTP{Addr: addr, From: "chat@example.com"}
+
+	err := m.Send(context.Background(), &Message{
+		To:      "an@example.com",
+		Subject: "Tin nhắn mới",
+		Text:    "hello",
+		HTML:    "<p>hello</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := <-messages
+	if got.from != "chat@example.com" || got.to != "an@example.com" {
+		t.Errorf("envelope: got %s -> %s", got.from, got.to)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(got.data))
+	if err != nil {
+		t.Fatalf("parsing message: %v", err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject")); subject != "Tin nhắn mới" {
+		t.Errorf("subject: got %q want %q", subject, "Tin nhắn mới")
+	}
+	if got := parsed.Header.Get("List-Unsubscribe"); got != "<https://example.com/u>" {
+		t.Errorf("List-Unsubscribe: got %q", got)
+	}
+
+	_, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		b, _ := io.ReadAll(p)
+		bodies = append(bodies, string(b))
+	}
+	if len(bodies) != 2 || bodies[0] != "hello" || bodies[1] != "<p>hello</p>" {
+		t.Errorf("bodies: got %q", bodies)
+	}
+}
+
+func TestSMTPSendRejectsInvalidAddress(t *testing.T) {
+	m := &SMTP{Addr: "127.0.0.1:1", From: "chat@example.com"}
+	if err := m.Send(context.Background(), &Message{To: "a@example.com\r\nBcc: x@example.com"}); err != ErrInvalidAddress {
+		t.Errorf("got %v want %v", err, ErrInvalidAddress)
+	}
+}
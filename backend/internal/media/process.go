@@ -0,0 +1,163 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// MaxPixels bounds the decoded size of an image to protect the server
+// against decompression bombs.
+const MaxPixels = 50_000_000
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	ErrImageTooLarge    = errors.New("image dimensions are too large")
+)
+
+// Size is a thumbnail size; the image is scaled to fit within Max x Max.
+type Size struct {
+	Name string
+	Max  int
+}
+
+// DefaultSizes are the thumbnails generated for uploaded images.
+var DefaultSizes = []Size{
+	{Name: "small", Max: 64},
+	{Name: "medium", Max: 320},
+	{Name: "large", Max: 1024},
+}
+
+// Rendition is an encoded version of an image.
+type Rendition struct {
+	Name        string
+	Width       int
+	Height      int
+	ContentType string
+	Data        []byte
+}
+
+type Result struct {
+	Width    int
+	Height   int
+	Blurhash string
+
+	// Original is the re-encoded full size image without metadata such as
+	// EXIF, or nil if the uploaded bytes can be kept as they are.
+	Original *Rendition
+
+	// Thumbnails holds one rendition per size smaller than the image.
+	Thumbnails []Rendition
+}
+
+// ProcessImage decodes a JPEG, PNG or GIF image, strips its metadata and
+// generates thumbnails and a blurhash placeholder.
+func ProcessImage(data []byte, sizes []Size) (*Result, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{}
+	switch format {
+	case "jpeg":
+		img = applyOrientation(img, jpegOrientation(data))
+		if res.Original, err = encode("original", img, "image/jpeg"); err != nil {
+			return nil, err
+		}
+	case "png":
+		if res.Original, err = encode("original", img, "image/png"); err != nil {
+			return nil, err
+		}
+	case "gif":
+		// GIFs carry no EXIF, and re-encoding would drop the animation.
+	default:
+		return nil, ErrUnsupportedImage
+	}
+
+	b := img.Bounds()
+	res.Width, res.Height = b.Dx(), b.Dy()
+
+	thumbType := "image/png"
+	if format == "jpeg" {
+		thumbType = "image/jpeg"
+	}
+	for _, size := range sizes {
+		if size.Max >= res.Width && size.Max >= res.Height {
+			continue
+		}
+		thumb, err := encode(size.Name, resize(img, size.Max), thumbType)
+		if err != nil {
+			return nil, err
+		}
+		res.Thumbnails = append(res.Thumbnails, *thumb)
+	}
+
+	res.Blurhash = Blurhash(resize(img, 32), 4, 3)
+	return res, nil
+}
+
+// resize scales img to fit within max x max, keeping its aspect ratio.
+func resize(img image.Image, max int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= max && h <= max {
+		return img
+	}
+
+	if w >= h {
+		h = h * max / w
+		w = max
+	} else {
+		w = w * max / h
+		h = max
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+func encode(name string, img image.Image, contentType string) (*Rendition, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	default:
+		err = ErrUnsupportedImage
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	return &Rendition{
+		Name:        name,
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+		ContentType: contentType,
+		Data:        buf.Bytes(),
+	}, nil
+}
@@ -0,0 +1,97 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// withOrientation inserts an EXIF APP1 segment with the given orientation
+// right after the SOI marker of a JPEG.
+func withOrientation(jpg []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big endian header, IFD0 at offset 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, // orientation, SHORT
+		0, 0, 0, 0, // no next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	size := len(payload) + 2
+	segment := append([]byte{0xFF, 0xE1, byte(size >> 8), byte(size)}, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+func TestProcessImageJPEG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 800; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := withOrientation(buf.Bytes(), 6)
+	if jpegOrientation(data) != 6 {
+		t.Fatalf("jpegOrientation did not find the EXIF orientation")
+	}
+
+	res, err := ProcessImage(data, DefaultSizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Orientation 6 is a 90° rotation, so the upright image is portrait.
+	if res.Width != 400 || res.Height != 800 {
+		t.Errorf("unexpected dimensions: got %dx%d want 400x800", res.Width, res.Height)
+	}
+	if res.Original == nil || bytes.Contains(res.Original.Data, []byte("Exif")) {
+		t.Errorf("original was not re-encoded without EXIF")
+	}
+
+	want := map[string][2]int{"small": {32, 64}, "medium": {160, 320}}
+	if len(res.Thumbnails) != len(want) {
+		t.Fatalf("unexpected number of thumbnails: got %d want %d", len(res.Thumbnails), len(want))
+	}
+	for _, th := range res.Thumbnails {
+		if dims := want[th.Name]; th.Width != dims[0] || th.Height != dims[1] {
+			t.Errorf("thumbnail %s: got %dx%d want %dx%d", th.Name, th.Width, th.Height, dims[0], dims[1])
+		}
+		if th.ContentType != "image/jpeg" {
+			t.Errorf("thumbnail %s: unexpected content type %s", th.Name, th.ContentType)
+		}
+	}
+
+	if len(res.Blurhash) != 28 {
+		t.Errorf("unexpected blurhash length: got %d want 28", len(res.Blurhash))
+	}
+}
+
+func TestProcessImageRejectsNonImages(t *testing.T) {
+	if _, err := ProcessImage([]byte("not an image"), DefaultSizes); err != ErrUnsupportedImage {
+		t.Errorf("got %v want %v", err, ErrUnsupportedImage)
+	}
+}
+
+func TestBlurhashSolidColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+
+	got := Blurhash(img, 4, 3)
+	if len(got) != 28 {
+		t.Fatalf("unexpected blurhash length: got %d want 28", len(got))
+	}
+	// The first character encodes the 4x3 component count and characters
+	// 2-6 the average color, which must be white.
+	if got[0] != 'L' || got[2:6] != encode83(0xFFFFFF, 4) {
+		t.Errorf("unexpected blurhash %q", got)
+	}
+}
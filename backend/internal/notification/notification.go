@@ -0,0 +1,127 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/internal/domain/push"
+)
+
+// Payload is the JSON document handed to the service worker, which turns it
+// into a visible notification.
+type Payload struct {
+	Title          string `json:"title"`
+	Body           string `json:"body"`
+	ConversationID int64  `json:"conversation_id"`
+	MessageID      int64  `json:"message_id,omitempty"`
+}
+
+// Service sends Web Push notifications to every subscription of a set of
+// users. Failed deliveries are retried with exponential backoff, and
+// subscriptions that expired or that the push service reports as gone are
+// removed.
+type Service struct {
+	subs   push.SubscriptionRepository
+	sender Sender
+
+	// MaxAttempts is the number of tries per subscription.
+	MaxAttempts int
+	// Backoff is the delay before the first retry; it doubles after each
+	// failed attempt.
+	Backoff time.Duration
+
+	now func() time.Time
+}
+
+func NewService(subs push.SubscriptionRepository, sender Sender) *Service {
+	return &Service{
+		subs:        subs,
+		sender:      sender,
+		MaxAttempts: 3,
+		Backoff:     time.Second,
+		now:         time.Now,
+	}
+}
+
+// Notify delivers p to all subscriptions of userIDs. It blocks until every
+// delivery succeeded or gave up, so it should run on a background worker.
+func (s *Service) Notify(ctx context.Context, userIDs []int64, p Payload) error {
+	subs, err := s.subs.ListByUsers(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	for i := range subs {
+		sub := &subs[i]
+		if sub.IsExpired(s.now()) {
+			s.remove(ctx, sub)
+			continue
+		}
+		if err := s.deliver(ctx, sub, data); err != nil {
+			if isGone(err) {
+				s.remove(ctx, sub)
+				continue
+			}
+			log.Printf("error sending push notification to subscription %d: %v", sub.ID, err)
+		}
+	}
+	return nil
+}
+
+// deliver sends data to one subscription, retrying transient failures.
+func (s *Service) deliver(ctx context.Context, sub *push.Subscription, data []byte) error {
+	delay := s.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = s.sender.Send(ctx, sub, data)
+		if err == nil || !isRetryable(err) || attempt >= s.MaxAttempts {
+			return err
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		delay *= 2
+	}
+}
+
+func (s *Service) remove(ctx context.Context, sub *push.Subscription) {
+	if err := s.subs.Delete(ctx, sub.ID); err != nil {
+		log.Printf("error removing push subscription %d: %v", sub.ID, err)
+	}
+}
+
+// isGone reports whether the push service no longer knows the subscription.
+func isGone(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && (se.StatusCode == http.StatusNotFound || se.StatusCode == http.StatusGone)
+}
+
+// isRetryable reports whether a later attempt may succeed: network errors,
+// throttling and server errors are retried, other rejections are final.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500
+	}
+	return true
+}
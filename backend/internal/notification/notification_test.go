@@ -0,0 +1,144 @@
+package notification
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SherClockHolmes/webpush-go"
+
+	"backend/internal/domain/push"
+)
+
+type fakeSubscriptions struct {
+	subs    []push.Subscription
+	deleted []int64
+}
+
+func (f *fakeSubscriptions) Save(ctx context.Context, s *push.Subscription) error { return nil }
+
+func (f *fakeSubscriptions) ListByUsers(ctx context.Context, userIDs []int64) ([]push.Subscription, error) {
+	return f.subs, nil
+}
+
+func (f *fakeSubscriptions) Delete(ctx context.Context, id int64) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+func (f *fakeSubscriptions) DeleteForUser(ctx context.Context, id, userID int64) error { return nil }
+
+// pushServer is a stand-in push service answering each request to a path
+// with the next status in its script.
+type pushServer struct {
+	mu       sync.Mutex
+	statuses map[string][]int
+	hits     map[string]int
+}
+
+func (p *pushServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.hits[r.URL.Path]++
+	status := http.StatusCreated
+	if s := p.statuses[r.URL.Path]; len(s) > 0 {
+		status, p.statuses[r.URL.Path] = s[0], s[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newSubscription(t *testing.T, id int64, endpoint string) push.Subscription {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return push.Subscription{
+		ID:       id,
+		UserID:   1,
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(auth),
+	}
+}
+
+func TestNotify(t *testing.T) {
+	ps := &pushServer{
+		statuses: map[string][]int{
+			"/retry":    {http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			"/gone":     {http.StatusGone},
+			"/rejected": {http.StatusBadRequest},
+		},
+		hits: map[string]int{},
+	}
+	srv := httptest.NewServer(ps)
+	defer srv.Close()
+
+	private, public, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	expired := newSubscription(t, 5, srv.URL+"/expired")
+	expired.ExpiresAt = &past
+
+	repo := &fakeSubscriptions{subs: []push.Subscription{
+		newSubscription(t, 1, srv.URL+"/ok"),
+		newSubscription(t, 2, srv.URL+"/retry"),
+		newSubscription(t, 3, srv.URL+"/gone"),
+		newSubscription(t, 4, srv.URL+"/rejected"),
+		expired,
+	}}
+	svc := NewService(repo, &WebPush{
+		PublicKey:  public,
+		PrivateKey: private,
+		Subject:    "mailto:test@example.com",
+		TTL:        time.Minute,
+	})
+	svc.Backoff = time.Millisecond
+
+	if err := svc.Notify(context.Background(), []int64{1}, Payload{Title: "hi", ConversationID: 7}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	for path, want := range map[string]int{"/ok": 1, "/retry": 3, "/gone": 1, "/rejected": 1, "/expired": 0} {
+		if got := ps.hits[path]; got != want {
+			t.Errorf("requests to %s: got %d want %d", path, got, want)
+		}
+	}
+	if len(repo.deleted) != 2 || repo.deleted[0] != 3 || repo.deleted[1] != 5 {
+		t.Errorf("deleted subscriptions: got %v want [3 5]", repo.deleted)
+	}
+}
+
+func TestDeliverGivesUpAfterMaxAttempts(t *testing.T) {
+	ps := &pushServer{
+		statuses: map[string][]int{"/down": {500, 500, 500, 500}},
+		hits:     map[string]int{},
+	}
+	srv := httptest.NewServer(ps)
+	defer srv.Close()
+
+	private, public, _ := webpush.GenerateVAPIDKeys()
+	svc := NewService(&fakeSubscriptions{}, &WebPush{PublicKey: public, PrivateKey: private, Subject: "mailto:test@example.com"})
+	svc.Backoff = time.Millisecond
+
+	sub := newSubscription(t, 1, srv.URL+"/down")
+	err := svc.deliver(context.Background(), &sub, []byte("{}"))
+	if se, ok := err.(*StatusError); !ok || se.StatusCode != 500 {
+		t.Errorf("deliver error: got %v want status 500", err)
+	}
+	if got := ps.hits["/down"]; got != svc.MaxAttempts {
+		t.Errorf("attempts: got %d want %d", got, svc.MaxAttempts)
+	}
+}
@@ -0,0 +1,66 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/SherClockHolmes/webpush-go"
+
+	"backend/internal/domain/push"
+)
+
+// StatusError is returned by a Sender when the push service rejected a
+// notification.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("push service responded with status %d", e.StatusCode)
+}
+
+// Sender delivers an encrypted payload to one browser subscription.
+type Sender interface {
+	Send(ctx context.Context, sub *push.Subscription, payload []byte) error
+}
+
+// WebPush sends notifications through the browser's push service, signed
+// with the server's VAPID key pair.
+type WebPush struct {
+	PublicKey  string
+	PrivateKey string
+	Subject    string
+	TTL        time.Duration
+	HTTPClient *http.Client
+}
+
+func (w *WebPush) Send(ctx context.Context, sub *push.Subscription, payload []byte) error {
+	opts := &webpush.Options{
+		Subscriber:      w.Subject,
+		VAPIDPublicKey:  w.PublicKey,
+		VAPIDPrivateKey: w.PrivateKey,
+		TTL:             int(w.TTL.Seconds()),
+		Urgency:         webpush.UrgencyHigh,
+	}
+	if w.HTTPClient != nil {
+		opts.HTTPClient = w.HTTPClient
+	}
+
+	resp, err := webpush.SendNotificationWithContext(ctx, payload, &webpush.Subscription{
+		Endpoint: sub.Endpoint,
+		Keys:     webpush.Keys{P256dh: sub.P256dh, Auth: sub.Auth},
+	}, opts)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
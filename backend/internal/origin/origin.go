@@ -0,0 +1,73 @@
+// Package origin decides which browser origins may call the API, for both
+// CORS and websocket upgrades.
+package origin
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Policy is a set of allowed origins. Entries are exact origins such as
+// "https://chatvui.com" or wildcards such as "https://*.chatvui.com", which
+// match any subdomain but not the domain itself.
+type Policy struct {
+	exact     map[string]bool
+	wildcards []wildcard
+}
+
+type wildcard struct {
+	scheme string
+	// suffix is the host after the "*", such as ".chatvui.com", including
+	// the port if one was given.
+	suffix string
+}
+
+// Parse reads a comma separated list of origins.
+func Parse(list string) (*Policy, error) {
+	p := &Policy{exact: map[string]bool{}}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return nil, fmt.Errorf("origin %q: allowing every origin is not supported, list them instead", entry)
+		}
+
+		u, err := url.Parse(entry)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			strings.TrimSuffix(u.Path, "/") != "" || u.RawQuery != "" || u.User != nil {
+			return nil, fmt.Errorf("origin %q: must be scheme://host[:port]", entry)
+		}
+
+		host := strings.ToLower(u.Host)
+		switch {
+		case strings.HasPrefix(host, "*."):
+			p.wildcards = append(p.wildcards, wildcard{scheme: u.Scheme, suffix: host[1:]})
+		case strings.Contains(host, "*"):
+			return nil, fmt.Errorf("origin %q: wildcards are only supported as the first label", entry)
+		default:
+			p.exact[u.Scheme+"://"+host] = true
+		}
+	}
+	return p, nil
+}
+
+// Allowed reports whether the value of an Origin header is allowed.
+func (p *Policy) Allowed(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || u.Path != "" || u.User != nil {
+		return false
+	}
+	scheme, host := strings.ToLower(u.Scheme), strings.ToLower(u.Host)
+	if p.exact[scheme+"://"+host] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if scheme == w.scheme && len(host) > len(w.suffix) && strings.HasSuffix(host, w.suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package origin
+
+import "testing"
+
+func TestPolicy(t *testing.T) {
+	p, err := Parse("https://chatvui.com, https://*.chatvui.com,http://localhost:5173")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"https://chatvui.com":          true,
+		"https://CHATVUI.com":          true,
+		"https://api.chatvui.com":      true,
+		"https://a.b.chatvui.com":      true,
+		"http://localhost:5173":        true,
+		"http://chatvui.com":           false,
+		"http://api.chatvui.com":       false,
+		"https://evilchatvui.com":      false,
+		"https://chatvui.com.evil.com": false,
+		"https://.chatvui.com":         false,
+		"http://localhost:3000":        false,
+		"https://chatvui.com/path":     false,
+		"null":                         false,
+		"":                             false,
+	}
+	for origin, want := range tests {
+		if got := p.Allowed(origin); got != want {
+			t.Errorf("Allowed(%q): got %v want %v", origin, got, want)
+		}
+	}
+}
+
+func TestParseRejectsInvalidOrigins(t *testing.T) {
+	for _, list := range []string{"*", "chatvui.com", "ftp://chatvui.com", "https://chatvui.com/app", "https://api.*.chatvui.com"} {
+		if _, err := Parse(list); err == nil {
+			t.Errorf("Parse(%q): got nil error", list)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/gin-gonic/gin"
+
+	"backend/cmd/web"
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/moderation"
+	"backend/internal/domain/user"
+)
+
+// adminPageSize is the number of rows of the dashboard's lists.
+const adminPageSize = 50
+
+// requireAdminPage aborts requests of non-admins to the dashboard. It must
+// run after requirePageAuth.
+func (s *Server) requireAdminPage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.isAdmin(currentUserID(c)) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// render writes a templ component as the HTML response.
+func render(c *gin.Context, status int, component templ.Component) {
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := component.Render(c.Request.Context(), c.Writer); err != nil {
+		log.Printf("error rendering page: %v", err)
+	}
+}
+
+// renderError shows message in the page's flash region instead of the
+// request's target.
+func renderError(c *gin.Context, status int, message string) {
+	c.Header("HX-Retarget", "#flash")
+	c.Header("HX-Reswap", "innerHTML")
+	render(c, status, web.Flash(message))
+}
+
+// respondAdminError maps a domain error of a dashboard request to a flash
+// message.
+func respondAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, user.ErrNotFound), errors.Is(err, moderation.ErrFlagNotFound):
+		renderError(c, http.StatusNotFound, err.Error())
+	default:
+		log.Printf("error handling admin request: %v", err)
+		renderError(c, http.StatusInternalServerError, "internal error")
+	}
+}
+
+// pageHeaders returns the JSON encoded headers htmx sends with the requests
+// of a page: the CSRF token of its cookie session.
+func (s *Server) pageHeaders(c *gin.Context) string {
+	data, _ := json.Marshal(map[string]string{csrfHeader: s.csrfToken(c.GetString(ctxSession))})
+	return string(data)
+}
+
+// adminDashboardHandler serves GET /admin
+func (s *Server) adminDashboardHandler(c *gin.Context) {
+	render(c, http.StatusOK, web.AdminDashboard(s.pageHeaders(c)))
+}
+
+// adminStatsHandler serves GET /admin/stats
+func (s *Server) adminStatsHandler(c *gin.Context) {
+	render(c, http.StatusOK, web.AdminStats(s.hub.Stats(), s.db.Health()))
+}
+
+// adminUsersHandler serves GET /admin/users
+func (s *Server) adminUsersHandler(c *gin.Context) {
+	users, err := s.users.Search(c.Request.Context(), strings.TrimSpace(c.Query("q")), adminPageSize)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	render(c, http.StatusOK, web.AdminUsers(users, s.hub.IsOnline))
+}
+
+// adminBanUserHandler serves POST and DELETE /admin/users/:id/ban
+func (s *Server) adminBanUserHandler(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	banned := c.Request.Method == http.MethodPost
+	if banned && s.isAdmin(id) {
+		renderError(c, http.StatusForbidden, "admins cannot be banned")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := s.users.SetBanned(ctx, id, banned); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	action := moderation.AuditUnbanAccount
+	if banned {
+		action = moderation.AuditBanAccount
+		s.hub.Disconnect(id, "banned")
+	}
+	s.audit(ctx, &moderation.AuditEntry{
+		ActorID:      currentUserID(c),
+		Action:       action,
+		TargetUserID: &id,
+	})
+
+	u, err := s.users.Get(ctx, id)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	render(c, http.StatusOK, web.AdminUserRow(*u, s.hub.IsOnline(id)))
+}
+
+// adminConversationsHandler serves GET /admin/conversations
+func (s *Server) adminConversationsHandler(c *gin.Context) {
+	list, err := s.conversations.List(c.Request.Context(), conversation.Query{
+		Name:  strings.TrimSpace(c.Query("q")),
+		Limit: adminPageSize,
+	})
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	render(c, http.StatusOK, web.AdminConversations(list))
+}
+
+// adminQueueHandler serves GET /admin/queue
+func (s *Server) adminQueueHandler(c *gin.Context) {
+	flags, err := s.flags.ListFlags(c.Request.Context(), moderation.FlagQuery{
+		Status: moderation.FlagPending,
+		Limit:  adminPageSize,
+	})
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	render(c, http.StatusOK, web.AdminQueue(flags))
+}
+
+// adminResolveFlagHandler serves POST /admin/queue/:id/resolve
+func (s *Server) adminResolveFlagHandler(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	f, err := s.flags.GetFlag(ctx, id)
+	if err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	actorID := currentUserID(c)
+	if err := s.flags.ResolveFlag(ctx, id, actorID); err != nil {
+		respondAdminError(c, err)
+		return
+	}
+	if c.PostForm("lift") == "true" {
+		if err := s.restrictions.Lift(ctx, f.UserID); err != nil {
+			respondAdminError(c, err)
+			return
+		}
+	}
+	s.audit(ctx, &moderation.AuditEntry{
+		ConversationID: f.ConversationID,
+		ActorID:        actorID,
+		Action:         moderation.AuditResolveFlag,
+		TargetUserID:   &f.UserID,
+		MessageID:      f.MessageID,
+		FlagID:         &f.ID,
+	})
+	c.Status(http.StatusOK)
+}
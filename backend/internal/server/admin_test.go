@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/auth"
+)
+
+func TestAdminDashboardRequiresAdmin(t *testing.T) {
+	tokens := auth.NewTokenManager("test-secret", time.Hour)
+	s := &Server{
+		tokens:  tokens,
+		users:   &fakeUsers{},
+		admins:  map[int64]bool{1: true},
+		csrfKey: []byte("test-secret"),
+	}
+	r := gin.New()
+	admin := r.Group("/admin", s.requirePageAuth(), s.requireAdminPage())
+	admin.GET("", s.adminDashboardHandler)
+
+	get := func(userID int64, bearer bool) (*httptest.ResponseRecorder, string) {
+		token, err := tokens.Issue(userID, "an")
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("GET", "/admin", nil)
+		if bearer {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else {
+			req.AddCookie(&http.Cookie{Name: sessionCookie, Value: token})
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr, token
+	}
+
+	if rr, _ := get(2, false); rr.Code != http.StatusForbidden {
+		t.Errorf("non-admin: got status %d want %d", rr.Code, http.StatusForbidden)
+	}
+	// Pages are only served to cookie sessions.
+	if rr, _ := get(1, true); rr.Code != http.StatusUnauthorized {
+		t.Errorf("bearer token: got status %d want %d", rr.Code, http.StatusUnauthorized)
+	}
+	rr, token := get(1, false)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("admin: got status %d want %d", rr.Code, http.StatusOK)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, csrfHeader) || !strings.Contains(body, s.csrfToken(token)) || !strings.Contains(body, `hx-get="/admin/stats"`) {
+		t.Errorf("dashboard does not carry the CSRF token and panels:\n%s", body)
+	}
+	if strings.Contains(body, "Authorization") || strings.Contains(body, token) {
+		t.Errorf("dashboard leaks the session token:\n%s", body)
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/domain/user"
+)
+
+// blockUserHandler serves POST /api/users/:id/block
+func (s *Server) blockUserHandler(c *gin.Context) {
+	blockedID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	blockerID := currentUserID(c)
+	if blockedID == blockerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": user.ErrBlockSelf.Error()})
+		return
+	}
+
+	if err := s.blocks.Block(c.Request.Context(), blockerID, blockedID); err != nil {
+		respondBlockError(c, err)
+		return
+	}
+	s.hub.SetBlocked(blockerID, blockedID, true)
+	c.Status(http.StatusNoContent)
+}
+
+// unblockUserHandler serves DELETE /api/users/:id/block
+func (s *Server) unblockUserHandler(c *gin.Context) {
+	blockedID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	blockerID := currentUserID(c)
+
+	if err := s.blocks.Unblock(c.Request.Context(), blockerID, blockedID); err != nil {
+		respondBlockError(c, err)
+		return
+	}
+	s.hub.SetBlocked(blockerID, blockedID, false)
+	c.Status(http.StatusNoContent)
+}
+
+// listBlocksHandler serves GET /api/blocks
+func (s *Server) listBlocksHandler(c *gin.Context) {
+	ids, err := s.blocks.BlockedIDs(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		respondBlockError(c, err)
+		return
+	}
+	if ids == nil {
+		ids = []int64{}
+	}
+	c.JSON(http.StatusOK, gin.H{"user_ids": ids})
+}
+
+// blockedUserIDs loads the block list of a user connecting to the hub.
+func (s *Server) blockedUserIDs(userID int64) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.blocks.BlockedIDs(ctx, userID)
+}
+
+// checkBlocked returns user.ErrBlocked when msg is a direct message to a
+// member who blocked its sender.
+func (s *Server) checkBlocked(ctx context.Context, conv *conversation.Conversation, msg *message.Message) error {
+	if conv.IsGroup {
+		return nil
+	}
+
+	members, err := s.conversations.MemberIDs(ctx, msg.ConversationID)
+	if err != nil {
+		return err
+	}
+	blockers, err := s.blocks.BlockerIDs(ctx, msg.SenderID, members)
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return user.ErrBlocked
+	}
+	return nil
+}
+
+func respondBlockError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, user.ErrNotFound), errors.Is(err, user.ErrNotBlocked):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		log.Printf("error handling block request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"backend/internal/domain/conversation"
+)
+
+func TestSendMessageBlocked(t *testing.T) {
+	ts := newTestServer(t)
+	ts.conversations.add(&conversation.Conversation{ID: 5, IsGroup: true}, []int64{1, 2})
+	ts.conversations.add(&conversation.Conversation{ID: 6}, []int64{1, 2})
+	ts.addUser(1, "an")
+	ts.addUser(2, "binh")
+	ts.blocks = &fakeBlocks{blockers: map[int64][]int64{1: {2}}}
+	send := func(conversationID string, userID int64) int {
+		return ts.do("POST", "/api/conversations/"+conversationID+"/messages", userID, createMessageRequest{Content: "hi"}).Code
+	}
+
+	if got := send("6", 1); got != http.StatusForbidden {
+		t.Errorf("direct message to a blocker: got status %d want %d", got, http.StatusForbidden)
+	}
+	if got := send("6", 2); got != http.StatusCreated {
+		t.Errorf("direct message from the blocker: got status %d want %d", got, http.StatusCreated)
+	}
+	// Blocks hide group messages from the blocker instead of refusing them.
+	if got := send("5", 1); got != http.StatusCreated {
+		t.Errorf("group message: got status %d want %d", got, http.StatusCreated)
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/bot"
+	"backend/internal/websocket"
+)
+
+type createBotRequest struct {
+	Username string   `json:"username"`
+	Events   []string `json:"events"`
+}
+
+type updateBotRequest struct {
+	Events []string `json:"events"`
+}
+
+// createBotHandler serves POST /api/bots
+func (s *Server) createBotHandler(c *gin.Context) {
+	if currentBot(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "bots cannot create bots"})
+		return
+	}
+
+	var req createBotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if !bot.ValidUsername(req.Username) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bot.ErrInvalidUsername.Error()})
+		return
+	}
+	events, ok := botEvents(c, req.Events)
+	if !ok {
+		return
+	}
+
+	token, err := newBotToken()
+	if err != nil {
+		respondBotError(c, err)
+		return
+	}
+	b := &bot.Bot{OwnerID: currentUserID(c), Username: req.Username, Events: events}
+	if err := s.bots.Create(c.Request.Context(), b, hashToken(token)); err != nil {
+		respondBotError(c, err)
+		return
+	}
+	b.Token = token
+
+	c.JSON(http.StatusCreated, b)
+}
+
+// listBotsHandler serves GET /api/bots
+func (s *Server) listBotsHandler(c *gin.Context) {
+	bots, err := s.bots.ListByOwner(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		respondBotError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bots": bots})
+}
+
+// updateBotHandler serves PATCH /api/bots/:id
+func (s *Server) updateBotHandler(c *gin.Context) {
+	b, ok := s.ownedBot(c)
+	if !ok {
+		return
+	}
+
+	var req updateBotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	events, ok := botEvents(c, req.Events)
+	if !ok {
+		return
+	}
+
+	if err := s.bots.SetEvents(c.Request.Context(), b.UserID, events); err != nil {
+		respondBotError(c, err)
+		return
+	}
+	b.Events = events
+	c.JSON(http.StatusOK, b)
+}
+
+// rotateBotTokenHandler serves POST /api/bots/:id/token
+func (s *Server) rotateBotTokenHandler(c *gin.Context) {
+	b, ok := s.ownedBot(c)
+	if !ok {
+		return
+	}
+
+	token, err := newBotToken()
+	if err != nil {
+		respondBotError(c, err)
+		return
+	}
+	if err := s.bots.RotateToken(c.Request.Context(), b.UserID, hashToken(token)); err != nil {
+		respondBotError(c, err)
+		return
+	}
+	b.Token = token
+	c.JSON(http.StatusOK, b)
+}
+
+// deleteBotHandler serves DELETE /api/bots/:id
+func (s *Server) deleteBotHandler(c *gin.Context) {
+	b, ok := s.ownedBot(c)
+	if !ok {
+		return
+	}
+	if err := s.bots.Delete(c.Request.Context(), b.UserID); err != nil {
+		respondBotError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ownedBot loads the :id bot, responding with 404 unless the caller owns it.
+func (s *Server) ownedBot(c *gin.Context) (*bot.Bot, bool) {
+	id, ok := pathID(c)
+	if !ok {
+		return nil, false
+	}
+	b, err := s.bots.Get(c.Request.Context(), id)
+	if err == nil && b.OwnerID != currentUserID(c) {
+		err = bot.ErrNotFound
+	}
+	if err != nil {
+		respondBotError(c, err)
+		return nil, false
+	}
+	return b, true
+}
+
+// botEvents validates the event subscriptions of a bot, defaulting to new
+// messages and mentions.
+func botEvents(c *gin.Context, events []string) ([]string, bool) {
+	if len(events) == 0 {
+		return []string{websocket.EventMessage, websocket.EventMention}, true
+	}
+	for _, e := range events {
+		if !websocket.ValidEvent(e) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown event: " + e})
+			return nil, false
+		}
+	}
+	return events, true
+}
+
+func newBotToken() (string, error) {
+	var raw [32]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return "", err
+	}
+	return bot.TokenPrefix + hex.EncodeToString(raw[:]), nil
+}
+
+func respondBotError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, bot.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, bot.ErrUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("error handling bot request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/gin-gonic/gin"
+
+	"backend/cmd/web"
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/websocket"
+)
+
+// chatPageSize is the number of recent messages the chat page shows.
+const chatPageSize = 50
+
+// chatHandler serves GET /chat and GET /chat/:id
+func (s *Server) chatHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := currentUserID(c)
+	conversations, err := s.conversations.List(ctx, conversation.Query{MemberID: userID, Limit: maxPageSize})
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	if c.Param("id") == "" {
+		render(c, http.StatusOK, web.ChatPage(s.pageHeaders(c), conversations, nil, nil, ""))
+		return
+	}
+
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	if !s.requireChatMember(c, conversationID) {
+		return
+	}
+	conv, err := s.conversations.Get(ctx, conversationID)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	messages, err := s.messages.ListByConversation(ctx, conversationID, userID, chatPageSize, 0)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	s.markRead(ctx, conversationID, userID)
+	// The page shows the oldest message first.
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	items, err := s.chatMessages(ctx, userID, messages, map[int64]string{})
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+
+	// EventSource sends the session cookie along.
+	events := chatURL(conversationID) + "/events"
+	render(c, http.StatusOK, web.ChatPage(s.pageHeaders(c), conversations, conv, items, events))
+}
+
+// chatSendHandler serves POST /chat/:id/messages
+func (s *Server) chatSendHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	msg := &message.Message{
+		SenderID:       currentUserID(c),
+		ConversationID: conversationID,
+		Content:        c.PostForm("content"),
+	}
+	reply, err := s.submitMessage(c.Request.Context(), msg, currentUsername(c), nil)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	// The message itself reaches the page through its event stream.
+	if reply == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.Header("HX-Retarget", "#messages")
+	c.Header("HX-Reswap", "beforeend")
+	render(c, http.StatusOK, web.ChatNotice(reply.Text))
+}
+
+// chatEventsHandler serves GET /chat/:id/events, the Server-Sent Events
+// stream of the chat page. It relays the conversation's events of the
+// caller's hub stream as rendered HTML fragments.
+func (s *Server) chatEventsHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	if !s.requireChatMember(c, conversationID) {
+		return
+	}
+	ctx := c.Request.Context()
+	userID := currentUserID(c)
+	conv, err := s.conversations.Get(ctx, conversationID)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	// As in the history, blocked users' messages are hidden in groups.
+	hidden := map[int64]bool{}
+	if conv.IsGroup {
+		blocked, err := s.blocks.BlockedIDs(ctx, userID)
+		if err != nil {
+			respondChatError(c, err)
+			return
+		}
+		for _, id := range blocked {
+			hidden[id] = true
+		}
+	}
+
+	stream, _ := s.hub.Subscribe(userID, currentUsername(c), nil, "")
+	defer s.hub.Unsubscribe(stream)
+
+	startSSE(c)
+	names := map[int64]string{}
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stream.Done():
+			return
+		case <-keepAlive.C:
+			if !writeSSEComment(c) {
+				return
+			}
+		case ev := <-stream.Feed():
+			var frame streamFrame
+			if err := json.Unmarshal(ev.Data, &frame); err != nil {
+				log.Printf("error decoding hub frame: %v", err)
+				continue
+			}
+			event, component, end := s.chatEvent(ctx, userID, conversationID, hidden, names, frame)
+			if component == nil {
+				continue
+			}
+			var buf bytes.Buffer
+			if err := component.Render(ctx, &buf); err != nil {
+				log.Printf("error rendering event: %v", err)
+				continue
+			}
+			if !writeSSE(c, "", event, buf.String()) {
+				return
+			}
+			if end {
+				return
+			}
+		}
+	}
+}
+
+// chatEvent renders a hub frame for the chat page of a conversation,
+// returning the event name and fragment, or a nil fragment for frames the
+// page ignores. end is set when the viewer was removed from the
+// conversation and the stream must end.
+func (s *Server) chatEvent(ctx context.Context, userID, conversationID int64, hidden map[int64]bool, names map[int64]string, frame streamFrame) (event string, component templ.Component, end bool) {
+	switch frame.Type {
+	case websocket.EventOnlineUsers:
+		return "online_users", web.ChatOnline(frame.Users), false
+
+	case websocket.EventMemberRemoved:
+		var removed memberRemovedEvent
+		if err := json.Unmarshal(frame.Data, &removed); err != nil || removed.ConversationID != conversationID || removed.UserID != userID {
+			return "", nil, false
+		}
+		text := "You were removed from this conversation."
+		if removed.Banned {
+			text = "You were banned from this conversation."
+		}
+		return "removed", web.ChatNotice(text), true
+
+	case websocket.EventMessage, websocket.EventMessageEdited, websocket.EventMessageUpdated, websocket.EventMessageDeleted:
+		if frame.ConversationID != conversationID {
+			return "", nil, false
+		}
+		var msg message.Message
+		if err := json.Unmarshal(frame.Data, &msg); err != nil {
+			log.Printf("error decoding message event: %v", err)
+			return "", nil, false
+		}
+		if hidden[msg.SenderID] || msg.IsReply() {
+			return "", nil, false
+		}
+		items, err := s.chatMessages(ctx, userID, []message.Message{msg}, names)
+		if err != nil {
+			log.Printf("error loading sender of message %d: %v", msg.ID, err)
+			return "", nil, false
+		}
+		if frame.Type == websocket.EventMessage {
+			return "message", web.ChatMessageItem(items[0]), false
+		}
+		return "message_updated", web.ChatMessageItem(items[0]), false
+	}
+	return "", nil, false
+}
+
+// chatMessages adds the sender usernames to messages, caching them in
+// names.
+func (s *Server) chatMessages(ctx context.Context, userID int64, messages []message.Message, names map[int64]string) ([]web.ChatMessage, error) {
+	items := make([]web.ChatMessage, 0, len(messages))
+	for _, m := range messages {
+		name, ok := names[m.SenderID]
+		if !ok {
+			u, err := s.users.Get(ctx, m.SenderID)
+			if err != nil {
+				return nil, err
+			}
+			name = u.Username
+			names[m.SenderID] = name
+		}
+		items = append(items, web.ChatMessage{Message: m, Sender: name, Own: m.SenderID == userID})
+	}
+	return items, nil
+}
+
+// requireChatMember responds with 403 unless the caller belongs to the
+// conversation.
+func (s *Server) requireChatMember(c *gin.Context, conversationID int64) bool {
+	ok, err := s.conversations.IsMember(c.Request.Context(), conversationID, currentUserID(c))
+	if err != nil {
+		respondChatError(c, err)
+		return false
+	}
+	if !ok {
+		renderError(c, http.StatusForbidden, conversation.ErrNotMember.Error())
+		return false
+	}
+	return true
+}
+
+func chatURL(conversationID int64) string {
+	return "/chat/" + strconv.FormatInt(conversationID, 10)
+}
+
+// respondChatError shows an error of the chat page in its flash region.
+func respondChatError(c *gin.Context, err error) {
+	if _, _, ok := retryableError(err); ok {
+		renderError(c, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	status := messageErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("error handling chat request: %v", err)
+		renderError(c, status, "internal error")
+		return
+	}
+	renderError(c, status, err.Error())
+}
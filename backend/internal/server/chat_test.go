@@ -0,0 +1,67 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"backend/internal/domain/message"
+	"backend/internal/domain/user"
+	"backend/internal/websocket"
+)
+
+func TestChatEvent(t *testing.T) {
+	s := &Server{users: &fakeUsers{users: map[int64]*user.User{
+		1: {ID: 1, Username: "an"},
+		2: {ID: 2, Username: "binh"},
+	}}}
+	hidden := map[int64]bool{3: true}
+	frame := func(eventType string, conversationID int64, data interface{}) streamFrame {
+		raw, _ := json.Marshal(data)
+		return streamFrame{Type: eventType, ConversationID: conversationID, Data: raw}
+	}
+	parent := int64(9)
+
+	tests := []struct {
+		name      string
+		frame     streamFrame
+		wantEvent string
+		wantHTML  string
+		wantEnd   bool
+	}{
+		{"message", frame(websocket.EventMessage, 5, message.Message{ID: 10, SenderID: 2, Content: "<b>hi</b>"}),
+			"message", "&lt;b&gt;hi&lt;/b&gt;", false},
+		{"edited message", frame(websocket.EventMessageEdited, 5, message.Message{ID: 10, SenderID: 2, Content: "hey"}),
+			"message_updated", `id="message-10"`, false},
+		{"other conversation", frame(websocket.EventMessage, 6, message.Message{ID: 11, SenderID: 2}), "", "", false},
+		{"blocked sender", frame(websocket.EventMessage, 5, message.Message{ID: 12, SenderID: 3}), "", "", false},
+		{"thread reply", frame(websocket.EventMessage, 5, message.Message{ID: 13, SenderID: 2, ParentID: &parent}), "", "", false},
+		{"online users", streamFrame{Type: websocket.EventOnlineUsers, Users: []string{"an", "binh"}},
+			"online_users", "<li>binh</li>", false},
+		{"viewer removed", frame(websocket.EventMemberRemoved, 5, memberRemovedEvent{ConversationID: 5, UserID: 1, Banned: true}),
+			"removed", "banned", true},
+		{"other member removed", frame(websocket.EventMemberRemoved, 5, memberRemovedEvent{ConversationID: 5, UserID: 2}), "", "", false},
+	}
+	for _, tt := range tests {
+		event, component, end := s.chatEvent(context.Background(), 1, 5, hidden, map[int64]string{}, tt.frame)
+		if event != tt.wantEvent || end != tt.wantEnd {
+			t.Errorf("%s: got event %q end %v want %q %v", tt.name, event, end, tt.wantEvent, tt.wantEnd)
+		}
+		if (component == nil) != (tt.wantHTML == "") {
+			t.Errorf("%s: got component %v, want rendered %v", tt.name, component, tt.wantHTML != "")
+			continue
+		}
+		if component == nil {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := component.Render(context.Background(), &buf); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), tt.wantHTML) {
+			t.Errorf("%s: got %s want it to contain %s", tt.name, buf.String(), tt.wantHTML)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	slashcmd "backend/internal/command"
+	"backend/internal/domain/command"
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/domain/moderation"
+	"backend/internal/domain/user"
+	webhookqueue "backend/internal/webhook"
+	"backend/internal/websocket"
+)
+
+type createCommandRequest struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// commandResponse is the payload of the command_response websocket event
+// and of ephemeral replies to POST /api/conversations/:id/messages.
+type commandResponse struct {
+	Command string `json:"command"`
+	Text    string `json:"text"`
+}
+
+// builtinCommands returns the registry of the commands every conversation
+// has.
+func (s *Server) builtinCommands() *slashcmd.Registry {
+	r := slashcmd.NewRegistry()
+	r.Register("help", "/help - list the available commands", slashcmd.HandlerFunc(s.helpCommand))
+	r.Register("me", "/me <action> - post an action in the third person", slashcmd.HandlerFunc(s.meCommand))
+	r.Register("topic", "/topic [text] - set the conversation topic, or clear it", slashcmd.HandlerFunc(s.topicCommand))
+	r.Register("invite", "/invite @username... - add users to the conversation", slashcmd.HandlerFunc(s.inviteCommand))
+	r.Register("mute", "/mute [duration|off] - mute notifications, e.g. /mute 8h", slashcmd.HandlerFunc(s.muteCommand))
+	return r
+}
+
+// submitMessage sends msg, or runs it as a slash command when its content
+// is "/name args". Ephemeral command replies are delivered to the sender
+// over the hub and returned; nil is returned when a message was posted.
+func (s *Server) submitMessage(ctx context.Context, msg *message.Message, username string, attachmentIDs []int64) (*commandResponse, error) {
+	name, args, ok := slashcmd.Parse(msg.Content)
+	if !ok {
+		msg.Content = slashcmd.Unescape(msg.Content)
+		return nil, s.sendMessage(ctx, msg, attachmentIDs)
+	}
+
+	resp, err := s.runCommand(ctx, &slashcmd.Invocation{
+		Name:           name,
+		Args:           args,
+		UserID:         msg.SenderID,
+		Username:       username,
+		ConversationID: msg.ConversationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.InChannel {
+		msg.Content = resp.Text
+		return nil, s.sendMessage(ctx, msg, attachmentIDs)
+	}
+
+	reply := &commandResponse{Command: name, Text: resp.Text}
+	s.sendEvent([]int64{msg.SenderID}, websocket.Message{
+		Type:           websocket.EventCommandResponse,
+		ConversationID: msg.ConversationID,
+		Data:           reply,
+	})
+	return reply, nil
+}
+
+// runCommand dispatches an invocation to a built-in command or to an
+// external command of the conversation.
+func (s *Server) runCommand(ctx context.Context, inv *slashcmd.Invocation) (*slashcmd.Response, error) {
+	ok, err := s.conversations.IsMember(ctx, inv.ConversationID, inv.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, conversation.ErrNotMember
+	}
+
+	h, ok := s.commands.Lookup(inv.Name)
+	if !ok {
+		cmd, err := s.slashCommands.Get(ctx, inv.ConversationID, inv.Name)
+		if errors.Is(err, command.ErrNotFound) {
+			return slashcmd.Reply("Unknown command /%s. Type /help for a list of commands.", inv.Name), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		h = &slashcmd.External{URL: cmd.URL, Secret: cmd.Secret, Client: s.commandClient}
+	}
+
+	resp, err := h.Run(ctx, inv)
+	if errors.Is(err, slashcmd.ErrCommandFailed) {
+		log.Printf("error running command /%s in conversation %d: %v", inv.Name, inv.ConversationID, err)
+		return slashcmd.Reply("/%s failed, please try again later.", inv.Name), nil
+	}
+	return resp, err
+}
+
+func (s *Server) helpCommand(ctx context.Context, inv *slashcmd.Invocation) (*slashcmd.Response, error) {
+	lines := s.commands.Usage()
+	external, err := s.slashCommands.ListByConversation(ctx, inv.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, cmd := range external {
+		lines = append(lines, "/"+cmd.Name)
+	}
+	return &slashcmd.Response{Text: strings.Join(lines, "\n")}, nil
+}
+
+func (s *Server) meCommand(ctx context.Context, inv *slashcmd.Invocation) (*slashcmd.Response, error) {
+	if inv.Args == "" {
+		return slashcmd.Reply("Usage: /me <action>"), nil
+	}
+	return &slashcmd.Response{Text: "_" + inv.Username + " " + inv.Args + "_", InChannel: true}, nil
+}
+
+func (s *Server) topicCommand(ctx context.Context, inv *slashcmd.Invocation) (*slashcmd.Response, error) {
+	admin, err := s.conversations.IsAdmin(ctx, inv.ConversationID, inv.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !admin {
+		return &slashcmd.Response{Text: conversation.ErrNotAdmin.Error()}, nil
+	}
+	if utf8.RuneCountInString(inv.Args) > conversation.MaxTopicLength {
+		return &slashcmd.Response{Text: conversation.ErrTopicTooLong.Error()}, nil
+	}
+	if err := s.conversations.SetTopic(ctx, inv.ConversationID, inv.Args); err != nil {
+		return nil, err
+	}
+
+	s.publishConversationUpdated(ctx, inv.ConversationID, inv.UserID)
+	if inv.Args == "" {
+		return slashcmd.Reply("Topic cleared."), nil
+	}
+	return slashcmd.Reply("Topic set to %q.", inv.Args), nil
+}
+
+func (s *Server) inviteCommand(ctx context.Context, inv *slashcmd.Invocation) (*slashcmd.Response, error) {
+	names := strings.Fields(inv.Args)
+	if len(names) == 0 {
+		return slashcmd.Reply("Usage: /invite @username..."), nil
+	}
+	if len(names) > message.MaxMentions {
+		return slashcmd.Reply("You can invite at most %d users at once.", message.MaxMentions), nil
+	}
+	admin, err := s.conversations.IsAdmin(ctx, inv.ConversationID, inv.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !admin {
+		return &slashcmd.Response{Text: conversation.ErrNotAdmin.Error()}, nil
+	}
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimPrefix(name, "@")
+		u, err := s.users.GetByUsername(ctx, name)
+		if errors.Is(err, user.ErrNotFound) {
+			lines = append(lines, fmt.Sprintf("@%s: no such user.", name))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = s.addMember(ctx, inv.ConversationID, u.ID, inv.UserID)
+		switch {
+		case errors.Is(err, conversation.ErrAlreadyMember):
+			lines = append(lines, fmt.Sprintf("@%s is already a member.", u.Username))
+		case errors.Is(err, moderation.ErrBanned):
+			lines = append(lines, fmt.Sprintf("@%s is banned from this conversation.", u.Username))
+		case err != nil:
+			return nil, err
+		default:
+			lines = append(lines, fmt.Sprintf("Added @%s.", u.Username))
+		}
+	}
+	return &slashcmd.Response{Text: strings.Join(lines, "\n")}, nil
+}
+
+func (s *Server) muteCommand(ctx context.Context, inv *slashcmd.Invocation) (*slashcmd.Response, error) {
+	if inv.Args == "off" {
+		if err := s.conversations.SetMutedUntil(ctx, inv.ConversationID, inv.UserID, nil); err != nil {
+			return nil, err
+		}
+		return slashcmd.Reply("Notifications unmuted."), nil
+	}
+
+	until := mutedForever
+	if inv.Args != "" {
+		d, err := time.ParseDuration(inv.Args)
+		if err != nil || d <= 0 {
+			return slashcmd.Reply("Usage: /mute [duration|off], e.g. /mute 30m or /mute 8h"), nil
+		}
+		if t := time.Now().Add(d); t.Before(mutedForever) {
+			until = t
+		}
+	}
+	if err := s.conversations.SetMutedUntil(ctx, inv.ConversationID, inv.UserID, &until); err != nil {
+		return nil, err
+	}
+	if until.Equal(mutedForever) {
+		return slashcmd.Reply("Notifications muted."), nil
+	}
+	return slashcmd.Reply("Notifications muted until %s.", until.UTC().Format(time.RFC1123)), nil
+}
+
+// createCommandHandler serves POST /api/conversations/:id/commands
+func (s *Server) createCommandHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	var req createCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	req.Name = strings.TrimPrefix(req.Name, "/")
+	if !slashcmd.ValidName(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": command.ErrInvalidName.Error()})
+		return
+	}
+	if _, builtin := s.commands.Lookup(req.Name); builtin {
+		c.JSON(http.StatusConflict, gin.H{"error": command.ErrNameTaken.Error()})
+		return
+	}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": command.ErrInvalidURL.Error()})
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	secret, err := webhookqueue.NewSecret()
+	if err != nil {
+		respondCommandError(c, err)
+		return
+	}
+	cmd := &command.Command{
+		ConversationID: conversationID,
+		CreatorID:      currentUserID(c),
+		Name:           req.Name,
+		URL:            req.URL,
+		Secret:         secret,
+	}
+	if err := s.slashCommands.Create(c.Request.Context(), cmd); err != nil {
+		respondCommandError(c, err)
+		return
+	}
+
+	// The secret is only returned once, on creation.
+	c.JSON(http.StatusCreated, cmd)
+}
+
+// listCommandsHandler serves GET /api/conversations/:id/commands
+func (s *Server) listCommandsHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	commands, err := s.slashCommands.ListByConversation(c.Request.Context(), conversationID)
+	if err != nil {
+		respondCommandError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"commands": commands})
+}
+
+// deleteCommandHandler serves DELETE /api/conversations/:id/commands/:command_id
+func (s *Server) deleteCommandHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	commandID, err := strconv.ParseInt(c.Param("command_id"), 10, 64)
+	if err != nil || commandID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	if err := s.slashCommands.Delete(c.Request.Context(), conversationID, commandID); err != nil {
+		respondCommandError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondCommandError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, command.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, command.ErrNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("error handling command request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"backend/internal/domain/command"
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	webhookqueue "backend/internal/webhook"
+)
+
+// fakeSlashCommands keeps the external commands of conversations in memory.
+type fakeSlashCommands struct {
+	commands []command.Command
+}
+
+func (f *fakeSlashCommands) Create(ctx context.Context, c *command.Command) error {
+	c.ID = int64(len(f.commands) + 1)
+	f.commands = append(f.commands, *c)
+	return nil
+}
+
+func (f *fakeSlashCommands) Get(ctx context.Context, conversationID int64, name string) (*command.Command, error) {
+	for _, c := range f.commands {
+		if c.ConversationID == conversationID && c.Name == name {
+			return &c, nil
+		}
+	}
+	return nil, command.ErrNotFound
+}
+
+func (f *fakeSlashCommands) ListByConversation(ctx context.Context, conversationID int64) ([]command.Command, error) {
+	var out []command.Command
+	for _, c := range f.commands {
+		if c.ConversationID == conversationID {
+			c.Secret = ""
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeSlashCommands) Delete(ctx context.Context, conversationID, id int64) error {
+	return command.ErrNotFound
+}
+
+// newCommandsTestServer has a group conversation 5 with the member 1.
+func newCommandsTestServer(t *testing.T) *testServer {
+	ts := newTestServer(t)
+	ts.conversations.add(&conversation.Conversation{ID: 5, IsGroup: true}, []int64{1})
+	ts.addUser(1, "an")
+	ts.slashCommands = &fakeSlashCommands{}
+	return ts
+}
+
+// runCommand sends content to conversation 5 as userID and returns the
+// ephemeral reply, failing unless the request got one.
+func (ts *testServer) runCommand(userID int64, content string) string {
+	ts.t.Helper()
+	rr := ts.do("POST", "/api/conversations/5/messages", userID, createMessageRequest{Content: content})
+	expect(ts.t, content, rr, http.StatusOK)
+	var reply commandResponse
+	decodeJSON(ts.t, rr, &reply)
+	return reply.Text
+}
+
+func TestBuiltinCommands(t *testing.T) {
+	ts := newCommandsTestServer(t)
+	ts.slashCommands.Create(context.Background(), &command.Command{ConversationID: 5, Name: "deploy", URL: "https://example.com/deploy"})
+
+	rr := ts.do("POST", "/api/conversations/5/messages", 1, createMessageRequest{Content: "/me waves"})
+	expect(t, "/me", rr, http.StatusCreated)
+	var msg message.Message
+	decodeJSON(t, rr, &msg)
+	if got := ts.messages.messages[msg.ID].Content; got != "_an waves_" {
+		t.Errorf("/me: posted %q", got)
+	}
+	if got := ts.runCommand(1, "/me"); got != "Usage: /me <action>" {
+		t.Errorf("/me without args: got %q", got)
+	}
+
+	help := ts.runCommand(1, "/help")
+	for _, want := range []string{"/help - ", "/me <action>", "/topic [text]", "/deploy"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("/help: %q does not list %q", help, want)
+		}
+	}
+
+	// Escaped slashes are sent as messages.
+	expect(t, "escaped", ts.do("POST", "/api/conversations/5/messages", 1, createMessageRequest{Content: "//me"}), http.StatusCreated)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	ts := newCommandsTestServer(t)
+
+	if got := ts.runCommand(1, "/nope now"); got != "Unknown command /nope. Type /help for a list of commands." {
+		t.Errorf("unknown command: got %q", got)
+	}
+	if len(ts.messages.messages) != 0 {
+		t.Errorf("unknown command posted messages: %+v", ts.messages.messages)
+	}
+	expect(t, "non-member", ts.do("POST", "/api/conversations/5/messages", 3, createMessageRequest{Content: "/help"}), http.StatusForbidden)
+}
+
+func TestExternalCommand(t *testing.T) {
+	var calls atomic.Int32
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"text": "deployed", "response_type": "ephemeral"}`))
+	}))
+	defer endpoint.Close()
+
+	ts := newCommandsTestServer(t)
+	ts.slashCommands.Create(context.Background(), &command.Command{ConversationID: 5, Name: "deploy", URL: endpoint.URL, Secret: "s"})
+
+	// The production client refuses to connect to loopback addresses.
+	ts.commandClient = webhookqueue.NewHTTPClient(time.Second, false)
+	if got := ts.runCommand(1, "/deploy"); got != "/deploy failed, please try again later." {
+		t.Errorf("loopback command: got %q", got)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("loopback endpoint was called %d times", n)
+	}
+
+	ts.commandClient = webhookqueue.NewHTTPClient(time.Second, true)
+	if got := ts.runCommand(1, "/deploy"); got != "deployed" {
+		t.Errorf("command with private addresses allowed: got %q", got)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("endpoint was called %d times want 1", n)
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/websocket"
+)
+
+// updateConversationRequest holds the settings to change; omitted fields
+// are left as they are.
+type updateConversationRequest struct {
+	Topic            *string `json:"topic"`
+	SlowModeSeconds  *int    `json:"slow_mode_seconds"`
+	AnnouncementOnly *bool   `json:"announcement_only"`
+}
+
+// conversationUpdatedEvent is the payload of the conversation_updated event.
+type conversationUpdatedEvent struct {
+	ConversationID   int64  `json:"conversation_id"`
+	Topic            string `json:"topic"`
+	SlowModeSeconds  int    `json:"slow_mode_seconds"`
+	AnnouncementOnly bool   `json:"announcement_only"`
+	UpdatedBy        int64  `json:"updated_by"`
+}
+
+// updateConversationHandler serves PATCH /api/conversations/:id
+func (s *Server) updateConversationHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	var req updateConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if req.Topic != nil && utf8.RuneCountInString(*req.Topic) > conversation.MaxTopicLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": conversation.ErrTopicTooLong.Error()})
+		return
+	}
+	if req.SlowModeSeconds != nil && !conversation.ValidSlowMode(*req.SlowModeSeconds) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": conversation.ErrInvalidSlowMode.Error()})
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	conv, err := s.conversations.Get(ctx, conversationID)
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	if req.Topic != nil {
+		if err := s.conversations.SetTopic(ctx, conversationID, *req.Topic); err != nil {
+			respondConversationError(c, err)
+			return
+		}
+		conv.Topic = *req.Topic
+	}
+	if req.SlowModeSeconds != nil || req.AnnouncementOnly != nil {
+		if req.SlowModeSeconds != nil {
+			conv.SlowModeSeconds = *req.SlowModeSeconds
+		}
+		if req.AnnouncementOnly != nil {
+			conv.AnnouncementOnly = *req.AnnouncementOnly
+		}
+		if err := s.conversations.SetPostingRules(ctx, conversationID, conv.SlowModeSeconds, conv.AnnouncementOnly); err != nil {
+			respondConversationError(c, err)
+			return
+		}
+	}
+
+	s.publish(ctx, conversationID, websocket.Message{
+		Type: websocket.EventConversationUpdated,
+		Data: newConversationUpdatedEvent(conv, currentUserID(c)),
+	})
+	c.JSON(http.StatusOK, conv)
+}
+
+// publishConversationUpdated tells the members of a conversation about its
+// current settings after updatedBy changed them.
+func (s *Server) publishConversationUpdated(ctx context.Context, conversationID, updatedBy int64) {
+	conv, err := s.conversations.Get(ctx, conversationID)
+	if err != nil {
+		log.Printf("error loading conversation %d: %v", conversationID, err)
+		return
+	}
+	s.publish(ctx, conversationID, websocket.Message{
+		Type: websocket.EventConversationUpdated,
+		Data: newConversationUpdatedEvent(conv, updatedBy),
+	})
+}
+
+func newConversationUpdatedEvent(conv *conversation.Conversation, updatedBy int64) conversationUpdatedEvent {
+	return conversationUpdatedEvent{
+		ConversationID:   conv.ID,
+		Topic:            conv.Topic,
+		SlowModeSeconds:  conv.SlowModeSeconds,
+		AnnouncementOnly: conv.AnnouncementOnly,
+		UpdatedBy:        updatedBy,
+	}
+}
+
+// checkPostingRules enforces the announcement-only and slow mode settings
+// of a conversation on a new message; admins are exempt from both. It starts
+// the sender's slow mode interval, so it must be the last check before the
+// message is stored. Slow mode is not enforced if Redis is unavailable.
+func (s *Server) checkPostingRules(ctx context.Context, conv *conversation.Conversation, msg *message.Message) error {
+	if !conv.AnnouncementOnly && conv.SlowModeSeconds == 0 {
+		return nil
+	}
+	admin, err := s.conversations.IsAdmin(ctx, conv.ID, msg.SenderID)
+	if err != nil || admin {
+		return err
+	}
+	if conv.AnnouncementOnly {
+		return conversation.ErrAnnouncementOnly
+	}
+
+	key := "slow_mode:" + strconv.FormatInt(conv.ID, 10) + ":" + strconv.FormatInt(msg.SenderID, 10)
+	res, err := s.cooldowns.Start(ctx, key, conv.SlowMode())
+	if err != nil {
+		log.Printf("error checking slow mode: %v", err)
+		return nil
+	}
+	if !res.Allowed {
+		return &conversation.SlowModeError{RetryAfter: res.RetryAfter}
+	}
+	return nil
+}
+
+func respondConversationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, conversation.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		log.Printf("error handling conversation request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/websocket"
+)
+
+// newConversationsTestServer has a group conversation 5 with the members 1
+// and 2 and the admin 3.
+func newConversationsTestServer(t *testing.T) *testServer {
+	ts := newTestServer(t)
+	ts.conversations.add(&conversation.Conversation{ID: 5, IsGroup: true}, []int64{1, 2}, 3)
+	ts.addUser(1, "an")
+	ts.addUser(3, "chi")
+	return ts
+}
+
+func TestUpdateConversation(t *testing.T) {
+	ts := newConversationsTestServer(t)
+	seconds, yes := 30, true
+	topic := "release planning"
+	long := strings.Repeat("a", conversation.MaxTopicLength+1)
+	invalid := int(conversation.MaxSlowMode.Seconds()) + 1
+
+	expect(t, "member", ts.do("PATCH", "/api/conversations/5", 1, updateConversationRequest{SlowModeSeconds: &seconds}), http.StatusForbidden)
+	expect(t, "topic too long", ts.do("PATCH", "/api/conversations/5", 3, updateConversationRequest{Topic: &long}), http.StatusBadRequest)
+	expect(t, "invalid slow mode", ts.do("PATCH", "/api/conversations/5", 3, updateConversationRequest{SlowModeSeconds: &invalid}), http.StatusBadRequest)
+	expect(t, "other conversation", ts.do("PATCH", "/api/conversations/6", 3, updateConversationRequest{Topic: &topic}), http.StatusForbidden)
+	if c := ts.conversations.conversations[5]; c.SlowModeSeconds != 0 || c.Topic != "" {
+		t.Fatalf("refused updates changed the conversation: %+v", c)
+	}
+
+	rr := ts.do("PATCH", "/api/conversations/5", 3, updateConversationRequest{Topic: &topic, SlowModeSeconds: &seconds})
+	expect(t, "admin", rr, http.StatusOK)
+	var got conversation.Conversation
+	decodeJSON(t, rr, &got)
+	if got.Topic != topic || got.SlowModeSeconds != 30 || got.AnnouncementOnly {
+		t.Errorf("response: got %+v", got)
+	}
+
+	// Omitted fields keep their values.
+	expect(t, "announcement only", ts.do("PATCH", "/api/conversations/5", 3, updateConversationRequest{AnnouncementOnly: &yes}), http.StatusOK)
+	if c := ts.conversations.conversations[5]; c.Topic != topic || c.SlowModeSeconds != 30 || !c.AnnouncementOnly {
+		t.Errorf("conversation: got %+v", c)
+	}
+}
+
+func TestSendMessageSlowMode(t *testing.T) {
+	ts := newConversationsTestServer(t)
+	ts.conversations.conversations[5].SlowModeSeconds = 60
+	send := func(userID int64) *httptest.ResponseRecorder {
+		return ts.do("POST", "/api/conversations/5/messages", userID, createMessageRequest{Content: "hi"})
+	}
+
+	expect(t, "first message", send(1), http.StatusCreated)
+	rr := send(1)
+	expect(t, "second message", rr, http.StatusTooManyRequests)
+	if got := rr.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After: got %q want %q", got, "60")
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	decodeJSON(t, rr, &body)
+	if body.Code != websocket.CodeSlowMode {
+		t.Errorf("code: got %q want %q", body.Code, websocket.CodeSlowMode)
+	}
+
+	// Admins are exempt.
+	expect(t, "admin", send(3), http.StatusCreated)
+	expect(t, "admin again", send(3), http.StatusCreated)
+}
+
+func TestSendMessageAnnouncementOnly(t *testing.T) {
+	ts := newConversationsTestServer(t)
+	ts.conversations.conversations[5].AnnouncementOnly = true
+	send := func(userID int64) *httptest.ResponseRecorder {
+		return ts.do("POST", "/api/conversations/5/messages", userID, createMessageRequest{Content: "hi"})
+	}
+
+	expect(t, "member", send(1), http.StatusForbidden)
+	expect(t, "admin", send(3), http.StatusCreated)
+	if len(ts.messages.messages) != 1 {
+		t.Errorf("stored messages: got %d want 1", len(ts.messages.messages))
+	}
+}
+
+func TestTopicCommand(t *testing.T) {
+	ts := newConversationsTestServer(t)
+	topic := func(userID int64, content string) commandResponse {
+		rr := ts.do("POST", "/api/conversations/5/messages", userID, createMessageRequest{Content: content})
+		expect(t, content, rr, http.StatusOK)
+		var reply commandResponse
+		decodeJSON(t, rr, &reply)
+		return reply
+	}
+
+	if reply := topic(1, "/topic hijacked"); reply.Text != conversation.ErrNotAdmin.Error() {
+		t.Errorf("member: got reply %q want %q", reply.Text, conversation.ErrNotAdmin.Error())
+	}
+	if got := ts.conversations.conversations[5].Topic; got != "" {
+		t.Fatalf("member set the topic to %q", got)
+	}
+
+	if reply := topic(3, "/topic release planning"); reply.Command != "topic" || !strings.HasPrefix(reply.Text, "Topic set") {
+		t.Errorf("admin: got reply %+v", reply)
+	}
+	if got := ts.conversations.conversations[5].Topic; got != "release planning" {
+		t.Errorf("topic: got %q want %q", got, "release planning")
+	}
+	if len(ts.messages.messages) != 0 {
+		t.Errorf("commands posted %d messages", len(ts.messages.messages))
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// sessionCookie holds the session JWT of browser clients that
+	// authenticate with a cookie instead of a bearer token.
+	sessionCookie = "chatvui_session"
+
+	// csrfHeader carries the CSRF token of cookie authenticated requests.
+	csrfHeader = "X-CSRF-Token"
+)
+
+// csrfToken derives the CSRF token of a session. Binding it to the session
+// keeps it stateless: only a page that could read GET /api/csrf, which CORS
+// limits to allowed origins, knows it.
+func (s *Server) csrfToken(session string) string {
+	mac := hmac.New(sha256.New, s.csrfKey)
+	mac.Write([]byte("csrf:" + session))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkCSRF reports whether a cookie authenticated request may proceed.
+// Safe methods always may; others need an allowed, same-origin or absent
+// Origin and the session's CSRF token in the X-CSRF-Token header.
+func (s *Server) checkCSRF(c *gin.Context, session string) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	if origin := c.GetHeader("Origin"); origin != "" && !s.origins.Allowed(origin) && !sameOrigin(c, origin) {
+		return false
+	}
+	return hmac.Equal([]byte(c.GetHeader(csrfHeader)), []byte(s.csrfToken(session)))
+}
+
+// sameOrigin reports whether origin is the host the request was sent to,
+// as for the admin and chat pages.
+func sameOrigin(c *gin.Context, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == c.Request.Host
+}
+
+// csrfHandler serves GET /api/csrf
+func (s *Server) csrfHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"csrf_token": s.csrfToken(c.GetString(ctxSession))})
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/a-h/templ"
+	"github.com/gin-gonic/gin"
+
+	digestmail "backend/internal/digest"
+	"backend/internal/domain/digest"
+)
+
+type digestPreferencesRequest struct {
+	Frequency digest.Frequency `json:"frequency"`
+}
+
+// digestPreferencesHandler serves GET /api/digest/preferences
+func (s *Server) digestPreferencesHandler(c *gin.Context) {
+	f, err := s.digestPreferences.Frequency(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		log.Printf("error loading digest preferences: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"frequency": f})
+}
+
+// updateDigestPreferencesHandler serves PUT /api/digest/preferences
+func (s *Server) updateDigestPreferencesHandler(c *gin.Context) {
+	var req digestPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !req.Frequency.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": digest.ErrInvalidFrequency.Error()})
+		return
+	}
+
+	if err := s.digestPreferences.SetFrequency(c.Request.Context(), currentUserID(c), req.Frequency); err != nil {
+		log.Printf("error saving digest preferences: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"frequency": req.Frequency})
+}
+
+// unsubscribeDigestPageHandler serves GET /api/digest/unsubscribe, the
+// link in digest emails. It only asks for confirmation, so link scanners
+// and prefetching do not unsubscribe anyone.
+func (s *Server) unsubscribeDigestPageHandler(c *gin.Context) {
+	if _, ok := s.unsubscribeUserID(c); !ok {
+		return
+	}
+	templ.Handler(digestmail.ConfirmUnsubscribe(c.Request.URL.RequestURI())).ServeHTTP(c.Writer, c.Request)
+}
+
+// unsubscribeDigestHandler serves POST /api/digest/unsubscribe, sent by the
+// confirmation page and by the one-click unsubscribe of mail clients
+// (RFC 8058).
+func (s *Server) unsubscribeDigestHandler(c *gin.Context) {
+	userID, ok := s.unsubscribeUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.digestPreferences.SetFrequency(c.Request.Context(), userID, digest.Off); err != nil {
+		log.Printf("error unsubscribing user %d from digests: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	templ.Handler(digestmail.Unsubscribed()).ServeHTTP(c.Writer, c.Request)
+}
+
+// unsubscribeUserID returns the user of a signed unsubscribe link, which
+// carries a token instead of a session. It responds with an error if the
+// link is not valid.
+func (s *Server) unsubscribeUserID(c *gin.Context) (int64, bool) {
+	if s.digests == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "email digests are not configured"})
+		return 0, false
+	}
+
+	userID, err := strconv.ParseInt(c.Query("uid"), 10, 64)
+	if err != nil || !s.digests.VerifyUnsubscribe(userID, c.Query("token")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid unsubscribe link"})
+		return 0, false
+	}
+	return userID, true
+}
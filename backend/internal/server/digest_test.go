@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	digestmail "backend/internal/digest"
+	"backend/internal/domain/digest"
+)
+
+type fakeDigestPreferences struct {
+	digest.Repository
+	frequency map[int64]digest.Frequency
+}
+
+func (f *fakeDigestPreferences) SetFrequency(ctx context.Context, userID int64, fr digest.Frequency) error {
+	f.frequency[userID] = fr
+	return nil
+}
+
+func TestUnsubscribeDigest(t *testing.T) {
+	prefs := &fakeDigestPreferences{frequency: map[int64]digest.Frequency{}}
+	digests := digestmail.NewService(prefs, nil, digestmail.Options{PublicURL: "https://api.chatvui.com", SigningKey: []byte("test-secret")})
+	s := &Server{digestPreferences: prefs, digests: digests}
+	r := gin.New()
+	r.GET("/api/digest/unsubscribe", s.unsubscribeDigestPageHandler)
+	r.POST("/api/digest/unsubscribe", s.unsubscribeDigestHandler)
+
+	link := strings.TrimPrefix(digests.UnsubscribeURL(7), "https://api.chatvui.com")
+	do := func(method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+
+	// Following the link only asks for confirmation.
+	rr := do("GET", link)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET: got status %d want %d", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), `method="post"`) {
+		t.Errorf("GET: confirmation form missing: %s", rr.Body.String())
+	}
+	if _, ok := prefs.frequency[7]; ok {
+		t.Fatal("GET changed the digest frequency")
+	}
+
+	if rr := do("POST", link+"x"); rr.Code != http.StatusBadRequest {
+		t.Errorf("tampered link: got status %d want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := do("POST", link); rr.Code != http.StatusOK {
+		t.Fatalf("POST: got status %d want %d", rr.Code, http.StatusOK)
+	}
+	if got := prefs.frequency[7]; got != digest.Off {
+		t.Errorf("frequency: got %q want %q", got, digest.Off)
+	}
+}
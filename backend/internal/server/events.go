@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"backend/internal/websocket"
+)
+
+// publish delivers an event to the live connections of every member of the
+// conversation.
+func (s *Server) publish(ctx context.Context, conversationID int64, event websocket.Message) {
+	members, err := s.conversations.MemberIDs(ctx, conversationID)
+	if err != nil {
+		log.Printf("error loading members of conversation %d: %v", conversationID, err)
+		return
+	}
+
+	event.ConversationID = conversationID
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("error marshaling event: %v", err)
+		return
+	}
+	s.hub.SendConversationEvent(conversationID, members, event.Type, data)
+}
+
+// sendEvent delivers an event to the live connections of the given users.
+func (s *Server) sendEvent(userIDs []int64, event websocket.Message) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("error marshaling event: %v", err)
+		return
+	}
+
+	s.hub.SendEvent(userIDs, event.Type, data)
+}
+
+// sendToClient delivers an event to a single connection, such as the reply
+// to a frame it sent.
+func (s *Server) sendToClient(client *websocket.Client, event websocket.Message) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("error marshaling event: %v", err)
+		return
+	}
+
+	s.hub.SendToClient(client, event.Type, data)
+}
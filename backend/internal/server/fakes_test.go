@@ -0,0 +1,502 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/domain/moderation"
+	"backend/internal/domain/ratelimit"
+	"backend/internal/domain/user"
+	"backend/internal/domain/webhook"
+	"backend/internal/filter"
+	"backend/internal/origin"
+	"backend/internal/spam"
+	webhookqueue "backend/internal/webhook"
+	"backend/internal/websocket"
+	"backend/internal/worker"
+)
+
+// testServer is a Server backed by in-memory fakes, serving the routes of
+// RegisterRoutes.
+type testServer struct {
+	*Server
+	t       *testing.T
+	handler http.Handler
+
+	conversations *fakeConversations
+	messages      *fakeMessages
+	attachments   *fakeAttachments
+	sanctions     *fakeSanctions
+	auditLog      *fakeAuditLog
+	users         *fakeUsers
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	origins, err := origin.Parse("https://chatvui.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub := websocket.NewManager()
+	go hub.Run()
+
+	attachments := &fakeAttachments{attachments: map[int64]*message.Attachment{}}
+	ts := &testServer{
+		t:             t,
+		conversations: newFakeConversations(),
+		messages:      newFakeMessages(attachments),
+		attachments:   attachments,
+		sanctions:     &fakeSanctions{},
+		auditLog:      &fakeAuditLog{},
+		users:         &fakeUsers{users: map[int64]*user.User{}, banned: map[int64]bool{}},
+	}
+	ts.Server = &Server{
+		hub:            hub,
+		admins:         map[int64]bool{},
+		origins:        origins,
+		csrfKey:        []byte("test-secret"),
+		tokens:         auth.NewTokenManager("test-secret", time.Hour),
+		messages:       ts.messages,
+		attachments:    ts.attachments,
+		conversations:  ts.conversations,
+		messagePolicy:  message.Policy{EditWindow: 15 * time.Minute},
+		uploadMaxBytes: 1 << 20,
+		signedURLTTL:   time.Hour,
+		previewWorkers: worker.NewPool(1, 10),
+		dispatcher:     newTestDispatcher(),
+		buckets:        &fakeBuckets{n: 1000, taken: map[string]int{}},
+		cooldowns:      newFakeCooldowns(),
+		spam:           spam.NewDetector(nil, spam.Config{}),
+		restrictions:   &fakeRestrictions{},
+		filters:        filter.NewChain(),
+		filterSettings: &fakeFilterSettings{},
+		flags:          &fakeFlags{},
+		sanctions:      ts.sanctions,
+		auditLog:       ts.auditLog,
+		users:          ts.users,
+		blocks:         &fakeBlocks{},
+	}
+	ts.commands = ts.builtinCommands()
+	ts.handler = ts.RegisterRoutes()
+	return ts
+}
+
+// addUser stores a user whose account is a day old.
+func (ts *testServer) addUser(id int64, username string) {
+	ts.users.users[id] = &user.User{ID: id, Username: username, CreatedAt: time.Now().Add(-24 * time.Hour)}
+}
+
+// do sends a request as userID, or unauthenticated if it is 0, with body
+// encoded as JSON unless it is nil.
+func (ts *testServer) do(method, path string, userID int64, body interface{}) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			ts.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if userID != 0 {
+		username := "user"
+		if u, ok := ts.users.users[userID]; ok {
+			username = u.Username
+		}
+		token, err := ts.tokens.Issue(userID, username)
+		if err != nil {
+			ts.t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// decodeJSON decodes the body of rr into v.
+func decodeJSON(t *testing.T, rr *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rr.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %s: %v", rr.Body.String(), err)
+	}
+}
+
+// expect fails the test unless rr has the wanted status.
+func expect(t *testing.T, name string, rr *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rr.Code != want {
+		t.Errorf("%s: got status %d want %d: %s", name, rr.Code, want, rr.Body.String())
+	}
+}
+
+// fakeConversations keeps conversations and their members in memory.
+// members maps a conversation id to its members, true for admins.
+type fakeConversations struct {
+	conversation.Repository
+	conversations map[int64]*conversation.Conversation
+	members       map[int64]map[int64]bool
+	readAll       map[int64]time.Time
+}
+
+func newFakeConversations() *fakeConversations {
+	return &fakeConversations{
+		conversations: map[int64]*conversation.Conversation{},
+		members:       map[int64]map[int64]bool{},
+		readAll:       map[int64]time.Time{},
+	}
+}
+
+// add stores c with the given members; admins are members too.
+func (f *fakeConversations) add(c *conversation.Conversation, members []int64, admins ...int64) {
+	f.conversations[c.ID] = c
+	f.members[c.ID] = map[int64]bool{}
+	for _, id := range members {
+		f.members[c.ID][id] = false
+	}
+	for _, id := range admins {
+		f.members[c.ID][id] = true
+	}
+}
+
+func (f *fakeConversations) Get(ctx context.Context, id int64) (*conversation.Conversation, error) {
+	c, ok := f.conversations[id]
+	if !ok {
+		return nil, conversation.ErrNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (f *fakeConversations) IsMember(ctx context.Context, conversationID, userID int64) (bool, error) {
+	_, ok := f.members[conversationID][userID]
+	return ok, nil
+}
+
+func (f *fakeConversations) IsAdmin(ctx context.Context, conversationID, userID int64) (bool, error) {
+	return f.members[conversationID][userID], nil
+}
+
+func (f *fakeConversations) AddMember(ctx context.Context, conversationID, userID int64) error {
+	if _, ok := f.members[conversationID][userID]; ok {
+		return conversation.ErrAlreadyMember
+	}
+	f.members[conversationID][userID] = false
+	return nil
+}
+
+func (f *fakeConversations) RemoveMember(ctx context.Context, conversationID, userID int64) error {
+	if _, ok := f.members[conversationID][userID]; !ok {
+		return conversation.ErrNotMember
+	}
+	delete(f.members[conversationID], userID)
+	return nil
+}
+
+func (f *fakeConversations) SetTopic(ctx context.Context, conversationID int64, topic string) error {
+	f.conversations[conversationID].Topic = topic
+	return nil
+}
+
+func (f *fakeConversations) SetPostingRules(ctx context.Context, conversationID int64, slowModeSeconds int, announcementOnly bool) error {
+	c := f.conversations[conversationID]
+	c.SlowModeSeconds, c.AnnouncementOnly = slowModeSeconds, announcementOnly
+	return nil
+}
+
+func (f *fakeConversations) MemberIDs(ctx context.Context, conversationID int64) ([]int64, error) {
+	var ids []int64
+	for id := range f.members[conversationID] {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (f *fakeConversations) MemberIDsByUsername(ctx context.Context, conversationID int64, usernames []string) ([]int64, error) {
+	return nil, nil
+}
+
+func (f *fakeConversations) MutedUserIDs(ctx context.Context, conversationID int64, now time.Time) ([]int64, error) {
+	return nil, nil
+}
+
+func (f *fakeConversations) MarkRead(ctx context.Context, conversationID, userID int64, at time.Time) error {
+	return nil
+}
+
+func (f *fakeConversations) MarkAllRead(ctx context.Context, userID int64, at time.Time) error {
+	f.readAll[userID] = at
+	return nil
+}
+
+// fakeWebhooks has no subscribers, so published events are dropped.
+type fakeWebhooks struct {
+	webhook.Repository
+}
+
+func (f *fakeWebhooks) Subscribers(ctx context.Context, event string, conversationID *int64) ([]webhook.Webhook, error) {
+	return nil, nil
+}
+
+func newTestDispatcher() *webhookqueue.Dispatcher {
+	return webhookqueue.NewDispatcher(&fakeWebhooks{}, nil, webhookqueue.Options{})
+}
+
+// fakeMessages keeps messages in memory. Create links attachments in
+// attachments like the database does and records the ids it was given in
+// linked.
+type fakeMessages struct {
+	message.Repository
+	messages    map[int64]*message.Message
+	edits       map[int64][]message.Edit
+	linked      map[int64][]int64
+	attachments *fakeAttachments
+	nextID      int64
+}
+
+func newFakeMessages(attachments *fakeAttachments) *fakeMessages {
+	return &fakeMessages{
+		messages:    map[int64]*message.Message{},
+		edits:       map[int64][]message.Edit{},
+		linked:      map[int64][]int64{},
+		attachments: attachments,
+		nextID:      100,
+	}
+}
+
+// add stores m as it is, for messages sent before the test.
+func (f *fakeMessages) add(m *message.Message) {
+	cp := *m
+	f.messages[m.ID] = &cp
+}
+
+func (f *fakeMessages) Create(ctx context.Context, m *message.Message, attachmentIDs []int64) error {
+	for _, id := range attachmentIDs {
+		a, ok := f.attachments.attachments[id]
+		if !ok || a.UploaderID != m.SenderID || a.MessageID != nil {
+			return message.ErrAttachmentNotOwned
+		}
+	}
+	f.nextID++
+	m.ID = f.nextID
+	m.CreatedAt = time.Now()
+	f.add(m)
+	if len(attachmentIDs) > 0 {
+		f.linked[m.ID] = attachmentIDs
+	}
+	for _, id := range attachmentIDs {
+		f.attachments.attachments[id].MessageID = &m.ID
+	}
+	if m.ParentID != nil && !m.Shadowed {
+		parent := f.messages[*m.ParentID]
+		parent.ReplyCount++
+		parent.LastReplyAt = &m.CreatedAt
+	}
+	return nil
+}
+
+func (f *fakeMessages) Get(ctx context.Context, id int64) (*message.Message, error) {
+	m, ok := f.messages[id]
+	if !ok {
+		return nil, message.ErrNotFound
+	}
+	cp := *m
+	return &cp, nil
+}
+
+func (f *fakeMessages) UpdateContent(ctx context.Context, id int64, content string) (*message.Message, error) {
+	m := f.messages[id]
+	now := time.Now()
+	f.edits[id] = append(f.edits[id], message.Edit{MessageID: id, Content: m.Content, EditedAt: now})
+	m.Content, m.EditedAt = content, &now
+	return f.Get(ctx, id)
+}
+
+func (f *fakeMessages) SoftDelete(ctx context.Context, id int64) (*message.Message, error) {
+	now := time.Now()
+	f.messages[id].DeletedAt = &now
+	return f.Get(ctx, id)
+}
+
+func (f *fakeMessages) Edits(ctx context.Context, id int64) ([]message.Edit, error) {
+	return f.edits[id], nil
+}
+
+func (f *fakeMessages) ThreadParticipantIDs(ctx context.Context, parentID int64) ([]int64, error) {
+	return []int64{f.messages[parentID].SenderID}, nil
+}
+
+// fakeAttachments keeps attachments in memory.
+type fakeAttachments struct {
+	attachments map[int64]*message.Attachment
+	nextID      int64
+}
+
+func (f *fakeAttachments) Create(ctx context.Context, a *message.Attachment) error {
+	f.nextID++
+	a.ID = f.nextID
+	cp := *a
+	f.attachments[a.ID] = &cp
+	return nil
+}
+
+func (f *fakeAttachments) Get(ctx context.Context, id int64) (*message.Attachment, error) {
+	a, ok := f.attachments[id]
+	if !ok {
+		return nil, message.ErrAttachmentNotFound
+	}
+	cp := *a
+	return &cp, nil
+}
+
+func (f *fakeAttachments) UpdateImage(ctx context.Context, a *message.Attachment) error {
+	cp := *a
+	f.attachments[a.ID] = &cp
+	return nil
+}
+
+func (f *fakeAttachments) ListByMessages(ctx context.Context, messageIDs []int64) (map[int64][]message.Attachment, error) {
+	out := map[int64][]message.Attachment{}
+	for _, id := range messageIDs {
+		for _, a := range f.attachments {
+			if a.MessageID != nil && *a.MessageID == id {
+				out[id] = append(out[id], *a)
+			}
+		}
+	}
+	return out, nil
+}
+
+// fakeSanctions keeps sanctions in memory.
+type fakeSanctions struct {
+	sanctions []moderation.Sanction
+}
+
+func (f *fakeSanctions) Sanction(ctx context.Context, s *moderation.Sanction) error {
+	f.Lift(ctx, s.ConversationID, s.UserID, s.Kind)
+	f.sanctions = append(f.sanctions, *s)
+	return nil
+}
+
+func (f *fakeSanctions) Lift(ctx context.Context, conversationID, userID int64, kind moderation.SanctionKind) error {
+	for i, s := range f.sanctions {
+		if s.ConversationID == conversationID && s.UserID == userID && s.Kind == kind {
+			f.sanctions = append(f.sanctions[:i], f.sanctions[i+1:]...)
+			return nil
+		}
+	}
+	return moderation.ErrNotSanctioned
+}
+
+func (f *fakeSanctions) ActiveSanction(ctx context.Context, conversationID, userID int64, kind moderation.SanctionKind, now time.Time) (*moderation.Sanction, error) {
+	for _, s := range f.sanctions {
+		if s.ConversationID == conversationID && s.UserID == userID && s.Kind == kind && s.Active(now) {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeSanctions) ListSanctions(ctx context.Context, conversationID int64, now time.Time) ([]moderation.Sanction, error) {
+	var out []moderation.Sanction
+	for _, s := range f.sanctions {
+		if s.ConversationID == conversationID && s.Active(now) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+type fakeAuditLog struct {
+	moderation.AuditRepository
+	entries []moderation.AuditEntry
+}
+
+func (f *fakeAuditLog) Record(ctx context.Context, e *moderation.AuditEntry) error {
+	f.entries = append(f.entries, *e)
+	return nil
+}
+
+// fakeFilterSettings leaves every filter at its default action.
+type fakeFilterSettings struct {
+	moderation.FilterSettingsRepository
+}
+
+func (f *fakeFilterSettings) FilterSettings(ctx context.Context, conversationID int64) (moderation.FilterSettings, error) {
+	return moderation.FilterSettings{}, nil
+}
+
+type fakeFlags struct {
+	moderation.FlagRepository
+	flags []moderation.Flag
+}
+
+func (f *fakeFlags) Create(ctx context.Context, fl *moderation.Flag) error {
+	f.flags = append(f.flags, *fl)
+	return nil
+}
+
+// fakeRestrictions restricts no one.
+type fakeRestrictions struct {
+	moderation.RestrictionRepository
+}
+
+func (f *fakeRestrictions) Restricted(ctx context.Context, userID int64, r moderation.Restriction) (bool, error) {
+	return false, nil
+}
+
+// fakeCooldowns runs cooldowns on the wall clock.
+type fakeCooldowns struct {
+	until map[string]time.Time
+}
+
+func newFakeCooldowns() *fakeCooldowns {
+	return &fakeCooldowns{until: map[string]time.Time{}}
+}
+
+func (f *fakeCooldowns) Start(ctx context.Context, key string, d time.Duration) (ratelimit.Result, error) {
+	now := time.Now()
+	if until, ok := f.until[key]; ok && now.Before(until) {
+		return ratelimit.Result{RetryAfter: until.Sub(now)}, nil
+	}
+	f.until[key] = now.Add(d)
+	return ratelimit.Result{Allowed: true}, nil
+}
+
+// fakeBlocks maps a blocked user to the users that blocked them.
+type fakeBlocks struct {
+	user.BlockRepository
+	blockers map[int64][]int64
+}
+
+func (f *fakeBlocks) BlockedIDs(ctx context.Context, blockerID int64) ([]int64, error) {
+	var ids []int64
+	for blocked, blockers := range f.blockers {
+		for _, id := range blockers {
+			if id == blockerID {
+				ids = append(ids, blocked)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeBlocks) BlockerIDs(ctx context.Context, blockedID int64, userIDs []int64) ([]int64, error) {
+	var ids []int64
+	for _, blocker := range f.blockers[blockedID] {
+		for _, id := range userIDs {
+			if id == blocker {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/message"
+	"backend/internal/domain/moderation"
+	"backend/internal/filter"
+)
+
+// newFilterChain builds the content filters from the environment: the
+// built-in word lists named in FILTER_WORD_LISTS plus FILTER_WORDS, the
+// patterns in FILTER_PATTERNS_FILE and the domains in FILTER_BLOCKED_DOMAINS.
+func newFilterChain() (*filter.Chain, error) {
+	var lists []string
+	for _, name := range strings.Split(envString("FILTER_WORD_LISTS", filter.English+","+filter.Vietnamese), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			lists = append(lists, name)
+		}
+	}
+	words, err := filter.LoadWordLists(lists...)
+	if err != nil {
+		return nil, fmt.Errorf("FILTER_WORD_LISTS: %w", err)
+	}
+	words = append(words, strings.Split(os.Getenv("FILTER_WORDS"), ",")...)
+
+	var patterns []string
+	if path := os.Getenv("FILTER_PATTERNS_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("FILTER_PATTERNS_FILE: %w", err)
+		}
+		defer f.Close()
+		if patterns, err = filter.ReadPatterns(f); err != nil {
+			return nil, fmt.Errorf("FILTER_PATTERNS_FILE: %w", err)
+		}
+	}
+	regex, err := filter.NewRegex(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("FILTER_PATTERNS_FILE: %w", err)
+	}
+
+	return filter.NewChain().
+		Add(filter.NewWordList(words), moderation.ActionMask).
+		Add(regex, moderation.ActionFlag).
+		Add(filter.NewLinkBlocklist(strings.Split(os.Getenv("FILTER_BLOCKED_DOMAINS"), ",")), moderation.ActionReject), nil
+}
+
+// filterContent runs content through the filter chain with the
+// conversation's settings, returning ErrMessageRejected if a rejecting
+// filter matched.
+func (s *Server) filterContent(ctx context.Context, conversationID int64, content string) (*filter.Result, error) {
+	settings, err := s.filterSettings.FilterSettings(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	res := s.filters.Run(content, settings)
+	if res.Rejected {
+		return nil, moderation.ErrMessageRejected
+	}
+	return &res, nil
+}
+
+// flagMessage adds a stored message to the moderation queue for each filter
+// that flagged it.
+func (s *Server) flagMessage(ctx context.Context, msg *message.Message, hits []filter.Hit) {
+	for _, hit := range hits {
+		reason := hit.Filter + ": " + strings.Join(hit.Matches, ", ")
+		if len(reason) > 255 {
+			reason = reason[:252] + "..."
+		}
+		f := &moderation.Flag{
+			ConversationID: &msg.ConversationID,
+			MessageID:      &msg.ID,
+			UserID:         msg.SenderID,
+			Source:         moderation.SourceFilter,
+			Reason:         strings.ToValidUTF8(reason, ""),
+		}
+		if err := s.flags.Create(ctx, f); err != nil {
+			log.Printf("error flagging message %d: %v", msg.ID, err)
+		}
+	}
+}
+
+// filterSettingsHandler serves GET /api/conversations/:id/filters
+func (s *Server) filterSettingsHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	settings, err := s.effectiveFilterSettings(c.Request.Context(), conversationID)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"filters": settings})
+}
+
+// updateFilterSettingsHandler serves PUT /api/conversations/:id/filters
+func (s *Server) updateFilterSettingsHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	var req moderation.FilterSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	for name, action := range req {
+		if !s.filters.Has(name) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown filter: " + name})
+			return
+		}
+		if !action.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "action must be off, mask, reject or flag"})
+			return
+		}
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	for name, action := range req {
+		if err := s.filterSettings.SetFilterAction(ctx, conversationID, name, action); err != nil {
+			respondMessageError(c, err)
+			return
+		}
+	}
+
+	settings, err := s.effectiveFilterSettings(ctx, conversationID)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"filters": settings})
+}
+
+// effectiveFilterSettings returns the action of every filter in a
+// conversation, falling back to the defaults.
+func (s *Server) effectiveFilterSettings(ctx context.Context, conversationID int64) (moderation.FilterSettings, error) {
+	stored, err := s.filterSettings.FilterSettings(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	settings := s.filters.Defaults()
+	for name, action := range stored {
+		if _, ok := settings[name]; ok {
+			settings[name] = action
+		}
+	}
+	return settings, nil
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/domain/moderation"
+	"backend/internal/websocket"
+)
+
+// frameTimeout bounds the handling of a single client frame.
+const frameTimeout = 30 * time.Second
+
+// handleFrame handles frames sent by hub clients, which the hub has already
+// validated against the schema of their type.
+func (s *Server) handleFrame(client *websocket.Client, frame websocket.Frame) {
+	if !s.allowFrame(client, frame) {
+		return
+	}
+
+	switch frame.Type {
+	case websocket.FrameMessage:
+		var data websocket.MessageFrame
+		if err := json.Unmarshal(frame.Data, &data); err != nil {
+			s.sendFrameError(client, frame.ConversationID, websocket.CodeInvalidFrame, "invalid message frame")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), frameTimeout)
+		defer cancel()
+		msg := &message.Message{
+			SenderID:       client.UserID,
+			ConversationID: frame.ConversationID,
+			Content:        data.Content,
+			ParentID:       data.ParentID,
+		}
+		if _, err := s.submitMessage(ctx, msg, client.Username, data.AttachmentIDs); err != nil {
+			s.sendMessageError(client, frame.ConversationID, err)
+		}
+	}
+}
+
+// allowFrame takes a token from the user's frame bucket, which is shared by
+// all their connections across instances, and reports a rate_limited error
+// when it is empty. Frames are let through if Redis is unavailable.
+func (s *Server) allowFrame(client *websocket.Client, frame websocket.Frame) bool {
+	if !s.userFrameRate.Enabled() {
+		return true
+	}
+
+	res, err := s.buckets.Take(context.Background(), "ws:user:"+strconv.FormatInt(client.UserID, 10), s.userFrameRate)
+	if err != nil {
+		log.Printf("error checking frame rate limit: %v", err)
+		return true
+	}
+	if !res.Allowed {
+		s.sendToClient(client, websocket.Message{
+			Type:           websocket.EventError,
+			ConversationID: frame.ConversationID,
+			Data: websocket.Error{
+				Code:       websocket.CodeRateLimited,
+				Message:    "too many messages, slow down",
+				RetryAfter: retryAfterSeconds(res.RetryAfter),
+			},
+		})
+		return false
+	}
+	return true
+}
+
+// sendMessageError reports a message frame that could not be sent, with the
+// error code matching the status the REST endpoint would respond with.
+func (s *Server) sendMessageError(client *websocket.Client, conversationID int64, err error) {
+	if code, retryAfter, ok := retryableError(err); ok {
+		s.sendToClient(client, websocket.Message{
+			Type:           websocket.EventError,
+			ConversationID: conversationID,
+			Data:           websocket.Error{Code: code, Message: err.Error(), RetryAfter: retryAfterSeconds(retryAfter)},
+		})
+		return
+	}
+	switch {
+	case errors.Is(err, conversation.ErrAnnouncementOnly):
+		s.sendFrameError(client, conversationID, websocket.CodeAnnouncementOnly, err.Error())
+		return
+	case errors.Is(err, moderation.ErrMuted):
+		s.sendFrameError(client, conversationID, websocket.CodeMuted, err.Error())
+		return
+	}
+	switch messageErrorStatus(err) {
+	case http.StatusBadRequest, http.StatusGone:
+		s.sendFrameError(client, conversationID, websocket.CodeInvalidMessage, err.Error())
+	case http.StatusForbidden:
+		s.sendFrameError(client, conversationID, websocket.CodeForbidden, err.Error())
+	case http.StatusNotFound:
+		s.sendFrameError(client, conversationID, websocket.CodeNotFound, err.Error())
+	case http.StatusUnprocessableEntity:
+		s.sendFrameError(client, conversationID, websocket.CodeMessageRejected, err.Error())
+	default:
+		log.Printf("error handling message frame: %v", err)
+		s.sendFrameError(client, conversationID, websocket.CodeInternal, "internal error")
+	}
+}
+
+// sendFrameError reports a rejected frame to the connection that sent it.
+func (s *Server) sendFrameError(client *websocket.Client, conversationID int64, code, text string) {
+	s.sendToClient(client, websocket.Message{
+		Type:           websocket.EventError,
+		ConversationID: conversationID,
+		Data:           websocket.Error{Code: code, Message: text},
+	})
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/message"
+	"backend/internal/media"
+	"backend/internal/websocket"
+)
+
+// imageContentTypes are the formats the image pipeline can process.
+var imageContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// imageUploadHandler serves POST /api/uploads/images. The image is stored as
+// uploaded and handed to the image workers, which strip its metadata and
+// generate thumbnails. Until then the attachment has status "processing" and
+// no download URL; the uploader is notified with an attachment_updated event.
+func (s *Server) imageUploadHandler(c *gin.Context) {
+	a, ok := s.storeUpload(c, imageContentTypes)
+	if !ok {
+		return
+	}
+	s.submitImage(c, a)
+}
+
+// submitImage hands a stored image to the image workers and responds with
+// the processing attachment, or marks it failed if the queue is full.
+func (s *Server) submitImage(c *gin.Context, a *message.Attachment) {
+	job := *a
+	if !s.imageWorkers.Submit(func(ctx context.Context) { s.processImage(ctx, &job) }) {
+		a.Status = message.AttachmentFailed
+		if err := s.attachments.UpdateImage(c.Request.Context(), a); err != nil {
+			log.Printf("error updating attachment %d: %v", a.ID, err)
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "image processing queue is full"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, a)
+}
+
+// processImage runs on an image worker. It replaces the stored original
+// with a copy without EXIF data, stores the thumbnails and records the image
+// metadata on the attachment.
+func (s *Server) processImage(ctx context.Context, a *message.Attachment) {
+	if err := s.renderImage(ctx, a); err != nil {
+		log.Printf("error processing image attachment %d: %v", a.ID, err)
+		a.Status = message.AttachmentFailed
+	} else {
+		a.Status = message.AttachmentReady
+	}
+
+	if err := s.attachments.UpdateImage(ctx, a); err != nil {
+		log.Printf("error updating attachment %d: %v", a.ID, err)
+		return
+	}
+	s.publishAttachment(ctx, a.ID)
+}
+
+func (s *Server) renderImage(ctx context.Context, a *message.Attachment) error {
+	r, err := s.storage.Get(ctx, a.Key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, s.uploadMaxBytes))
+	r.Close()
+	if err != nil {
+		return err
+	}
+
+	res, err := media.ProcessImage(data, media.DefaultSizes)
+	if err != nil {
+		return err
+	}
+
+	for _, th := range res.Thumbnails {
+		key := a.Key + "_" + th.Name
+		if err := s.storage.Put(ctx, key, bytes.NewReader(th.Data), int64(len(th.Data)), th.ContentType); err != nil {
+			return err
+		}
+		a.Thumbnails = append(a.Thumbnails, message.Thumbnail{Name: th.Name, Key: key, Width: th.Width, Height: th.Height})
+	}
+
+	if res.Original != nil {
+		orig := res.Original.Data
+		if err := s.storage.Put(ctx, a.Key, bytes.NewReader(orig), int64(len(orig)), res.Original.ContentType); err != nil {
+			return err
+		}
+		a.Size = int64(len(orig))
+	}
+
+	a.Width, a.Height, a.Blurhash = res.Width, res.Height, res.Blurhash
+	return nil
+}
+
+// publishAttachment sends the current state of an attachment to its
+// uploader, or to the whole conversation once it is linked to a message.
+func (s *Server) publishAttachment(ctx context.Context, id int64) {
+	a, err := s.attachments.Get(ctx, id)
+	if err != nil {
+		log.Printf("error loading attachment %d: %v", id, err)
+		return
+	}
+	s.signAttachment(ctx, a)
+	event := websocket.Message{Type: websocket.EventAttachmentUpdated, Data: a}
+
+	if a.MessageID == nil {
+		s.sendEvent([]int64{a.UploaderID}, event)
+		return
+	}
+	msg, err := s.messages.Get(ctx, *a.MessageID)
+	if err != nil {
+		log.Printf("error loading message %d: %v", *a.MessageID, err)
+		return
+	}
+	s.publishMessageEvent(ctx, msg, event)
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/message"
+	"backend/internal/domain/webhook"
+)
+
+type createIncomingWebhookRequest struct {
+	Name string `json:"name"`
+}
+
+// incomingMessageRequest is the body POSTed to an incoming webhook URL.
+type incomingMessageRequest struct {
+	Text        string          `json:"text"`
+	Attachments []message.Embed `json:"attachments"`
+}
+
+// createIncomingWebhookHandler serves POST /api/conversations/:id/incoming-webhooks
+func (s *Server) createIncomingWebhookHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	var req createIncomingWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": webhook.ErrInvalidName.Error()})
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	var raw [32]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	token := hex.EncodeToString(raw[:])
+
+	w := &webhook.Incoming{
+		ConversationID: conversationID,
+		CreatorID:      currentUserID(c),
+		Name:           req.Name,
+	}
+	if err := s.incomingWebhooks.Create(c.Request.Context(), w, hashToken(token)); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	w.Token = token
+
+	// The token is only returned once, on creation.
+	c.JSON(http.StatusCreated, gin.H{
+		"webhook": w,
+		"url":     strings.TrimRight(s.publicURL, "/") + "/api/hooks/" + token,
+	})
+}
+
+// listIncomingWebhooksHandler serves GET /api/conversations/:id/incoming-webhooks
+func (s *Server) listIncomingWebhooksHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	hooks, err := s.incomingWebhooks.ListByConversation(c.Request.Context(), conversationID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks})
+}
+
+// revokeIncomingWebhookHandler serves DELETE /api/conversations/:id/incoming-webhooks/:hook_id
+func (s *Server) revokeIncomingWebhookHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	hookID, err := strconv.ParseInt(c.Param("hook_id"), 10, 64)
+	if err != nil || hookID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	if err := s.incomingWebhooks.Revoke(c.Request.Context(), conversationID, hookID); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// incomingMessageHandler serves POST /api/hooks/:token. The token in the
+// URL is the only credential.
+func (s *Server) incomingMessageHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	w, err := s.incomingWebhooks.GetByTokenHash(ctx, hashToken(c.Param("token")))
+	if errors.Is(err, webhook.ErrInvalidToken) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	res, err := s.limiter.Allow(ctx, "incoming_webhook:"+strconv.FormatInt(w.ID, 10), s.incomingWebhookRate, time.Minute)
+	if err != nil {
+		// Fail open: a Redis outage should not stop integrations.
+		log.Printf("error checking incoming webhook rate limit: %v", err)
+	} else if !res.Allowed {
+		respondRateLimited(c, res.RetryAfter)
+		return
+	}
+
+	var req incomingMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	msg := &message.Message{
+		SenderID:       w.BotUserID,
+		ConversationID: w.ConversationID,
+		Content:        req.Text,
+		Embeds:         req.Attachments,
+	}
+	if err := s.sendMessage(ctx, msg, nil); err != nil {
+		respondMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": msg.ID})
+}
+
+// hashToken returns the hex SHA-256 of a webhook token, which is what the
+// database stores.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/ratelimit"
+	"backend/internal/domain/user"
+	"backend/internal/domain/webhook"
+)
+
+// fakeIncomingWebhooks serves webhooks by the hash of their token.
+type fakeIncomingWebhooks struct {
+	webhook.IncomingRepository
+	hooks map[string]*webhook.Incoming
+}
+
+func (f *fakeIncomingWebhooks) GetByTokenHash(ctx context.Context, tokenHash string) (*webhook.Incoming, error) {
+	w, ok := f.hooks[tokenHash]
+	if !ok || w.RevokedAt != nil {
+		return nil, webhook.ErrInvalidToken
+	}
+	return w, nil
+}
+
+// fakeLimiter allows limit requests per key.
+type fakeLimiter struct {
+	counts map[string]int
+}
+
+func (f *fakeLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (ratelimit.Result, error) {
+	f.counts[key]++
+	if f.counts[key] > limit {
+		return ratelimit.Result{RetryAfter: window}, nil
+	}
+	return ratelimit.Result{Allowed: true}, nil
+}
+
+func TestIncomingMessageHandler(t *testing.T) {
+	ts := newTestServer(t)
+	ts.conversations.add(&conversation.Conversation{ID: 5, IsGroup: true}, []int64{1, 50})
+	ts.users.users[50] = &user.User{ID: 50, Username: "deploys", IsBot: true}
+	revokedAt := time.Now()
+	ts.incomingWebhooks = &fakeIncomingWebhooks{hooks: map[string]*webhook.Incoming{
+		hashToken("live-token"):    {ID: 1, ConversationID: 5, BotUserID: 50},
+		hashToken("revoked-token"): {ID: 2, ConversationID: 5, BotUserID: 50, RevokedAt: &revokedAt},
+	}}
+	ts.limiter = &fakeLimiter{counts: map[string]int{}}
+	ts.incomingWebhookRate = 2
+	post := func(token string) *httptest.ResponseRecorder {
+		return ts.do("POST", "/api/hooks/"+token, 0, incomingMessageRequest{Text: "deploy finished"})
+	}
+
+	expect(t, "unknown token", post("unknown-token"), http.StatusNotFound)
+	expect(t, "revoked token", post("revoked-token"), http.StatusNotFound)
+
+	rr := post("live-token")
+	expect(t, "valid token", rr, http.StatusCreated)
+	var body struct {
+		ID int64 `json:"id"`
+	}
+	decodeJSON(t, rr, &body)
+	msg, ok := ts.messages.messages[body.ID]
+	if !ok {
+		t.Fatalf("message %d was not stored", body.ID)
+	}
+	if msg.SenderID != 50 || msg.ConversationID != 5 || msg.Content != "deploy finished" {
+		t.Errorf("message: got %+v want it sent by the bot user", msg)
+	}
+
+	expect(t, "within rate", post("live-token"), http.StatusCreated)
+	rr = post("live-token")
+	expect(t, "over rate", rr, http.StatusTooManyRequests)
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("rate limited response has no Retry-After")
+	}
+}
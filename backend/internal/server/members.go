@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/moderation"
+	"backend/internal/domain/webhook"
+	"backend/internal/websocket"
+)
+
+type addMemberRequest struct {
+	UserID int64 `json:"user_id"`
+}
+
+// memberJoinedEvent is the payload of the member_joined websocket event and
+// the data of user.joined webhook events.
+type memberJoinedEvent struct {
+	ConversationID int64 `json:"conversation_id"`
+	UserID         int64 `json:"user_id"`
+	AddedBy        int64 `json:"added_by"`
+}
+
+// addMemberHandler serves POST /api/conversations/:id/members
+func (s *Server) addMemberHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	var req addMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.UserID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	event, err := s.addMember(c.Request.Context(), conversationID, req.UserID, currentUserID(c))
+	switch {
+	case errors.Is(err, conversation.ErrAlreadyMember):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, conversation.ErrUnknownUser):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, moderation.ErrBanned):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		respondMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
+
+// addMember adds userID to the conversation, unless they are banned from
+// it, and announces it to the members and webhooks.
+func (s *Server) addMember(ctx context.Context, conversationID, userID, addedBy int64) (*memberJoinedEvent, error) {
+	if err := s.checkBanned(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.conversations.AddMember(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+	s.hub.AddToConversation(userID, conversationID)
+
+	event := &memberJoinedEvent{ConversationID: conversationID, UserID: userID, AddedBy: addedBy}
+	s.publish(ctx, conversationID, websocket.Message{Type: websocket.EventMemberJoined, Data: event})
+	s.publishWebhook(ctx, webhook.EventUserJoined, &conversationID, event)
+	return event, nil
+}
+
+// requireConversationAdmin responds with 403 unless the caller is an admin
+// of the conversation.
+func (s *Server) requireConversationAdmin(c *gin.Context, conversationID int64) bool {
+	ok, err := s.conversations.IsAdmin(c.Request.Context(), conversationID, currentUserID(c))
+	if err != nil {
+		log.Printf("error checking conversation admin: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": conversation.ErrNotAdmin.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/message"
+	"backend/internal/websocket"
+)
+
+// mentionsHandler serves GET /api/mentions
+func (s *Server) mentionsHandler(c *gin.Context) {
+	limit, offset, err := pagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mentions, err := s.mentions.ListForUser(c.Request.Context(), currentUserID(c), limit, offset)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+
+	msgs := make([]message.Message, len(mentions))
+	for i := range mentions {
+		msgs[i] = *mentions[i].Message
+	}
+	if err := s.decorateMessages(c, msgs); err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	for i := range mentions {
+		mentions[i].Message = &msgs[i]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mentions": mentions, "limit": limit, "offset": offset})
+}
+
+// processMentions resolves the mentions in msg against the conversation's
+// members, stores them and notifies the mentioned users. It returns the ids
+// of the mentioned users.
+func (s *Server) processMentions(ctx context.Context, msg *message.Message) []int64 {
+	parsed := message.ParseMentions(msg.Content)
+	if parsed.IsEmpty() {
+		return nil
+	}
+
+	kinds := map[int64]string{}
+	if parsed.All || parsed.Here {
+		members, err := s.conversations.MemberIDs(ctx, msg.ConversationID)
+		if err != nil {
+			log.Printf("error loading members of conversation %d: %v", msg.ConversationID, err)
+			return nil
+		}
+		for _, id := range members {
+			switch {
+			case parsed.All:
+				kinds[id] = message.MentionAll
+			case s.hub.IsOnline(id):
+				kinds[id] = message.MentionHere
+			}
+		}
+	}
+	named, err := s.conversations.MemberIDsByUsername(ctx, msg.ConversationID, parsed.Usernames)
+	if err != nil {
+		log.Printf("error resolving mentions of message %d: %v", msg.ID, err)
+		return nil
+	}
+	for _, id := range named {
+		kinds[id] = message.MentionUser
+	}
+	delete(kinds, msg.SenderID)
+	if len(kinds) == 0 {
+		return nil
+	}
+
+	mentions := make([]message.Mention, 0, len(kinds))
+	for id, kind := range kinds {
+		mentions = append(mentions, message.Mention{
+			MessageID:      msg.ID,
+			ConversationID: msg.ConversationID,
+			UserID:         id,
+			SenderID:       msg.SenderID,
+			Kind:           kind,
+			CreatedAt:      msg.CreatedAt,
+		})
+	}
+	if err := s.mentions.Create(ctx, mentions); err != nil {
+		log.Printf("error storing mentions of message %d: %v", msg.ID, err)
+		return nil
+	}
+
+	ids := make([]int64, 0, len(mentions))
+	for _, m := range mentions {
+		m.Message = msg
+		s.sendEvent([]int64{m.UserID}, websocket.Message{
+			Type:           websocket.EventMention,
+			ConversationID: msg.ConversationID,
+			Data:           m,
+		})
+		ids = append(ids, m.UserID)
+	}
+	return ids
+}
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/domain/moderation"
+	"backend/internal/domain/user"
+	"backend/internal/websocket"
+)
+
+type createMessageRequest struct {
+	Content       string  `json:"content"`
+	ParentID      *int64  `json:"parent_id"`
+	AttachmentIDs []int64 `json:"attachment_ids"`
+}
+
+type editMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// createMessageHandler serves POST /api/conversations/:id/messages
+func (s *Server) createMessageHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	var req createMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	msg := &message.Message{
+		SenderID:       currentUserID(c),
+		ConversationID: conversationID,
+		Content:        req.Content,
+		ParentID:       req.ParentID,
+	}
+	reply, err := s.submitMessage(c.Request.Context(), msg, currentUsername(c), req.AttachmentIDs)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	// Slash commands answered only to the caller post no message.
+	if reply != nil {
+		c.JSON(http.StatusOK, reply)
+		return
+	}
+
+	c.JSON(http.StatusCreated, msg)
+}
+
+// listMessagesHandler serves GET /api/conversations/:id/messages
+func (s *Server) listMessagesHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	if !s.requireMember(c, conversationID) {
+		return
+	}
+
+	limit, offset, err := pagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := currentUserID(c)
+	messages, err := s.messages.ListByConversation(ctx, conversationID, userID, limit, offset)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	if offset == 0 {
+		s.markRead(ctx, conversationID, userID)
+	}
+	if err := s.decorateMessages(c, messages); err != nil {
+		respondMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages, "limit": limit, "offset": offset})
+}
+
+// markRead records that a user saw the latest messages of a conversation,
+// which keeps them out of their email digest. Failures are only logged.
+func (s *Server) markRead(ctx context.Context, conversationID, userID int64) {
+	if err := s.conversations.MarkRead(ctx, conversationID, userID, time.Now()); err != nil {
+		log.Printf("error marking conversation %d read for user %d: %v", conversationID, userID, err)
+	}
+}
+
+// editMessageHandler serves PATCH /api/messages/:id
+func (s *Server) editMessageHandler(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	var req editMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if err := message.ValidateContent(req.Content); err != nil {
+		respondMessageError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	msg, err := s.messages.Get(ctx, id)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	if err := s.messagePolicy.CanEdit(msg, currentUserID(c), time.Now()); err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	if err := s.checkEditRules(ctx, msg); err != nil {
+		respondMessageError(c, err)
+		return
+	}
+
+	filtered, err := s.filterContent(ctx, msg.ConversationID, req.Content)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+
+	msg, err = s.messages.UpdateContent(ctx, id, filtered.Content)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	s.flagMessage(ctx, msg, filtered.Flagged())
+
+	s.publishMessageEvent(ctx, msg, websocket.Message{Type: websocket.EventMessageEdited, Data: msg})
+	s.enqueueLinkPreviews(msg)
+	c.JSON(http.StatusOK, msg)
+}
+
+// checkEditRules applies the rules of sending to an edit by the author of
+// msg: they must still be a member, not banned or muted, and an admin of an
+// announcement-only conversation. Slow mode does not limit edits.
+func (s *Server) checkEditRules(ctx context.Context, msg *message.Message) error {
+	ok, err := s.conversations.IsMember(ctx, msg.ConversationID, msg.SenderID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return conversation.ErrNotMember
+	}
+	if err := s.checkBanned(ctx, msg.ConversationID, msg.SenderID); err != nil {
+		return err
+	}
+	if err := s.checkMuted(ctx, msg); err != nil {
+		return err
+	}
+
+	conv, err := s.conversations.Get(ctx, msg.ConversationID)
+	if err != nil || !conv.AnnouncementOnly {
+		return err
+	}
+	admin, err := s.conversations.IsAdmin(ctx, conv.ID, msg.SenderID)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return conversation.ErrAnnouncementOnly
+	}
+	return nil
+}
+
+// deleteMessageHandler serves DELETE /api/messages/:id
+func (s *Server) deleteMessageHandler(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	msg, err := s.messages.Get(ctx, id)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	// Conversation admins may delete anyone's message, which is audited.
+	userID := currentUserID(c)
+	moderated := false
+	if err := s.messagePolicy.CanDelete(msg, userID, time.Now()); err != nil {
+		if !errors.Is(err, message.ErrNotAuthor) {
+			respondMessageError(c, err)
+			return
+		}
+		admin, aerr := s.conversations.IsAdmin(ctx, msg.ConversationID, userID)
+		if aerr != nil {
+			respondMessageError(c, aerr)
+			return
+		}
+		if !admin {
+			respondMessageError(c, err)
+			return
+		}
+		moderated = true
+	}
+
+	msg, err = s.messages.SoftDelete(ctx, id)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	if moderated {
+		s.audit(ctx, &moderation.AuditEntry{
+			ConversationID: &msg.ConversationID,
+			ActorID:        userID,
+			Action:         moderation.AuditDeleteMessage,
+			TargetUserID:   &msg.SenderID,
+			MessageID:      &msg.ID,
+			Reason:         c.Query("reason"),
+		})
+	}
+
+	s.publishMessageEvent(ctx, msg, websocket.Message{Type: websocket.EventMessageDeleted, Data: msg})
+	c.JSON(http.StatusOK, msg)
+}
+
+// messageEditsHandler serves GET /api/messages/:id/edits
+func (s *Server) messageEditsHandler(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	msg, err := s.messages.Get(ctx, id)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	if !s.requireMember(c, msg.ConversationID) {
+		return
+	}
+	if msg.IsDeleted() {
+		respondMessageError(c, message.ErrDeleted)
+		return
+	}
+
+	edits, err := s.messages.Edits(ctx, id)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"edits": edits})
+}
+
+// decorateMessages adds the per-response data of a page of messages: the
+// reaction counts as seen by the caller and signed attachment URLs.
+func (s *Server) decorateMessages(c *gin.Context, messages []message.Message) error {
+	if err := s.attachReactions(c, messages, currentUserID(c)); err != nil {
+		return err
+	}
+	return s.attachAttachments(c.Request.Context(), messages)
+}
+
+// requireMember aborts with 403 unless the caller belongs to the conversation.
+func (s *Server) requireMember(c *gin.Context, conversationID int64) bool {
+	ok, err := s.conversations.IsMember(c.Request.Context(), conversationID, currentUserID(c))
+	if err != nil {
+		log.Printf("error checking membership: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": conversation.ErrNotMember.Error()})
+		return false
+	}
+	return true
+}
+
+// pathID parses the :id path parameter, responding with 400 if it is invalid.
+func pathID(c *gin.Context) (int64, bool) {
+	return pathParamID(c, "id")
+}
+
+func respondMessageError(c *gin.Context, err error) {
+	if code, retryAfter, ok := retryableError(err); ok {
+		seconds := retryAfterSeconds(retryAfter)
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": code, "retry_after": seconds})
+		return
+	}
+
+	status := messageErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("error handling message request: %v", err)
+		c.JSON(status, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// retryableError returns the error code and wait of a message refused by
+// slow mode or a spam throttle.
+func retryableError(err error) (code string, retryAfter time.Duration, ok bool) {
+	var (
+		slow      *conversation.SlowModeError
+		throttled *moderation.ThrottledError
+	)
+	switch {
+	case errors.As(err, &slow):
+		return websocket.CodeSlowMode, slow.RetryAfter, true
+	case errors.As(err, &throttled):
+		return websocket.CodeThrottled, throttled.RetryAfter, true
+	}
+	return "", 0, false
+}
+
+// messageErrorStatus maps an error of sending or changing a message to an
+// HTTP status; unexpected errors map to 500.
+func messageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, message.ErrNotFound), errors.Is(err, conversation.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, message.ErrEmptyContent), errors.Is(err, message.ErrContentTooLong), errors.Is(err, message.ErrInvalidParent),
+		errors.Is(err, message.ErrInvalidCharacters),
+		errors.Is(err, message.ErrTooManyAttachments), errors.Is(err, message.ErrAttachmentNotOwned),
+		errors.Is(err, message.ErrTooManyEmbeds), errors.Is(err, message.ErrInvalidEmbed):
+		return http.StatusBadRequest
+	case errors.Is(err, message.ErrAttachmentNotFound):
+		return http.StatusBadRequest
+	case errors.Is(err, message.ErrNotAuthor), errors.Is(err, message.ErrEditWindowExpired), errors.Is(err, conversation.ErrNotMember),
+		errors.Is(err, user.ErrBlocked), errors.Is(err, moderation.ErrMuted), errors.Is(err, moderation.ErrBanned),
+		errors.Is(err, conversation.ErrAnnouncementOnly):
+		return http.StatusForbidden
+	case errors.Is(err, message.ErrDeleted):
+		return http.StatusGone
+	case errors.Is(err, moderation.ErrMessageRejected):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/domain/moderation"
+)
+
+// newMessagesTestServer has a group conversation 5 with the members 1 and 2
+// and the admin 3, and message 10 that 1 sent a minute ago.
+func newMessagesTestServer(t *testing.T) *testServer {
+	ts := newTestServer(t)
+	ts.conversations.add(&conversation.Conversation{ID: 5, IsGroup: true}, []int64{1, 2}, 3)
+	ts.messages.add(&message.Message{ID: 10, SenderID: 1, ConversationID: 5, Content: "hi", CreatedAt: time.Now().Add(-time.Minute)})
+	return ts
+}
+
+func TestEditMessage(t *testing.T) {
+	ts := newMessagesTestServer(t)
+	ts.messages.add(&message.Message{ID: 11, SenderID: 1, ConversationID: 5, Content: "old", CreatedAt: time.Now().Add(-time.Hour)})
+	edit := editMessageRequest{Content: "hello"}
+
+	expect(t, "other user", ts.do("PATCH", "/api/messages/10", 2, edit), http.StatusForbidden)
+	expect(t, "conversation admin", ts.do("PATCH", "/api/messages/10", 3, edit), http.StatusForbidden)
+	expect(t, "edit window expired", ts.do("PATCH", "/api/messages/11", 1, edit), http.StatusForbidden)
+	expect(t, "missing message", ts.do("PATCH", "/api/messages/99", 1, edit), http.StatusNotFound)
+	if got := ts.messages.messages[10].Content; got != "hi" {
+		t.Fatalf("refused edits changed the message to %q", got)
+	}
+
+	expect(t, "author", ts.do("PATCH", "/api/messages/10", 1, edit), http.StatusOK)
+	if got := ts.messages.messages[10].Content; got != "hello" {
+		t.Errorf("content: got %q want %q", got, "hello")
+	}
+
+	expect(t, "edits of non-member", ts.do("GET", "/api/messages/10/edits", 4, nil), http.StatusForbidden)
+	rr := ts.do("GET", "/api/messages/10/edits", 2, nil)
+	expect(t, "edits", rr, http.StatusOK)
+	var body struct {
+		Edits []message.Edit `json:"edits"`
+	}
+	decodeJSON(t, rr, &body)
+	if len(body.Edits) != 1 || body.Edits[0].Content != "hi" {
+		t.Errorf("edits: got %+v want the previous content", body.Edits)
+	}
+
+	expect(t, "author deletes", ts.do("DELETE", "/api/messages/10", 1, nil), http.StatusOK)
+	expect(t, "edit deleted", ts.do("PATCH", "/api/messages/10", 1, edit), http.StatusGone)
+	expect(t, "edits of deleted", ts.do("GET", "/api/messages/10/edits", 2, nil), http.StatusGone)
+	if len(ts.auditLog.entries) != 0 {
+		t.Errorf("deleting an own message was audited: %+v", ts.auditLog.entries)
+	}
+}
+
+func TestEditMessageRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(ts *testServer)
+		want  int
+	}{
+		{"member", func(ts *testServer) {}, http.StatusOK},
+		{"removed from conversation", func(ts *testServer) {
+			delete(ts.conversations.members[5], 1)
+		}, http.StatusForbidden},
+		{"banned", func(ts *testServer) {
+			ts.sanctions.Sanction(context.Background(), &moderation.Sanction{ConversationID: 5, UserID: 1, Kind: moderation.SanctionBan})
+		}, http.StatusForbidden},
+		{"muted", func(ts *testServer) {
+			ts.sanctions.Sanction(context.Background(), &moderation.Sanction{ConversationID: 5, UserID: 1, Kind: moderation.SanctionMute})
+		}, http.StatusForbidden},
+		{"expired mute", func(ts *testServer) {
+			expired := time.Now().Add(-time.Minute)
+			ts.sanctions.Sanction(context.Background(), &moderation.Sanction{ConversationID: 5, UserID: 1, Kind: moderation.SanctionMute, ExpiresAt: &expired})
+		}, http.StatusOK},
+		{"announcement only", func(ts *testServer) {
+			ts.conversations.conversations[5].AnnouncementOnly = true
+		}, http.StatusForbidden},
+		{"announcement only admin", func(ts *testServer) {
+			ts.conversations.conversations[5].AnnouncementOnly = true
+			ts.conversations.members[5][1] = true
+		}, http.StatusOK},
+		{"slow mode", func(ts *testServer) {
+			ts.conversations.conversations[5].SlowModeSeconds = 60
+			ts.cooldowns.(*fakeCooldowns).until["slow_mode:5:1"] = time.Now().Add(time.Minute)
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		ts := newMessagesTestServer(t)
+		tt.setup(ts)
+		expect(t, tt.name, ts.do("PATCH", "/api/messages/10", 1, editMessageRequest{Content: "hello"}), tt.want)
+	}
+}
+
+func TestDeleteMessageByAdmin(t *testing.T) {
+	ts := newMessagesTestServer(t)
+
+	expect(t, "other member", ts.do("DELETE", "/api/messages/10", 2, nil), http.StatusForbidden)
+	if ts.messages.messages[10].IsDeleted() {
+		t.Fatal("message deleted by another member")
+	}
+
+	expect(t, "conversation admin", ts.do("DELETE", "/api/messages/10?reason=spam", 3, nil), http.StatusOK)
+	if !ts.messages.messages[10].IsDeleted() {
+		t.Fatal("admin did not delete the message")
+	}
+	if len(ts.auditLog.entries) != 1 {
+		t.Fatalf("audit log: got %d entries want 1", len(ts.auditLog.entries))
+	}
+	e := ts.auditLog.entries[0]
+	if e.Action != moderation.AuditDeleteMessage || e.ActorID != 3 || *e.TargetUserID != 1 || *e.MessageID != 10 || e.Reason != "spam" {
+		t.Errorf("audit entry: got %+v", e)
+	}
+
+	expect(t, "already deleted", ts.do("DELETE", "/api/messages/10", 1, nil), http.StatusGone)
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/bot"
+	"backend/internal/domain/ratelimit"
+	"backend/internal/domain/user"
+)
+
+const (
+	ctxUserID   = "userID"
+	ctxUsername = "username"
+	ctxBot      = "bot"
+	ctxSession  = "session"
+)
+
+// credentials selects where authenticate looks for the caller's token.
+type credentials int
+
+const (
+	// bearerOrCookie is the Authorization header, then the session cookie.
+	bearerOrCookie credentials = iota
+	// streamCredentials is bearerOrCookie plus the "token" query parameter.
+	streamCredentials
+	// cookieOnly is the session cookie alone.
+	cookieOnly
+)
+
+// requireAuth rejects requests without a valid bearer token or session
+// cookie; cookie authenticated requests that change state must pass the CSRF
+// check. Bots authenticate the same way with their API token.
+func (s *Server) requireAuth() gin.HandlerFunc {
+	return s.authenticate(bearerOrCookie)
+}
+
+// requireStreamAuth is requireAuth for the websocket and Server-Sent Events
+// routes. Browsers cannot set headers on those requests, so the token may
+// also be passed as the "token" query parameter, which requestLogger
+// redacts.
+func (s *Server) requireStreamAuth() gin.HandlerFunc {
+	return s.authenticate(streamCredentials)
+}
+
+// requirePageAuth is requireAuth for the server-rendered pages, which only
+// accept the session cookie. The pages embed the headers of their htmx
+// requests, and those must never include a bearer token.
+func (s *Server) requirePageAuth() gin.HandlerFunc {
+	return s.authenticate(cookieOnly)
+}
+
+func (s *Server) authenticate(from credentials) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var token string
+		if from != cookieOnly {
+			token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if token == "" && from == streamCredentials {
+			token = c.Query("token")
+		}
+		if token == "" {
+			if cookie, err := c.Cookie(sessionCookie); err == nil && cookie != "" {
+				if !s.checkCSRF(c, cookie) {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid CSRF token"})
+					return
+				}
+				token = cookie
+			}
+		}
+		c.Set(ctxSession, token)
+
+		if strings.HasPrefix(token, bot.TokenPrefix) {
+			s.authenticateBot(c, token)
+			return
+		}
+
+		claims, err := s.tokens.Parse(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		if s.rejectBanned(c, claims.UserID) {
+			return
+		}
+		c.Set(ctxUserID, claims.UserID)
+		c.Set(ctxUsername, claims.Username)
+		c.Next()
+	}
+}
+
+// requestLogger logs requests like gin's default logger, with the "token"
+// query parameter redacted so credentials do not end up in the logs.
+func requestLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			p.StatusCode,
+			p.Latency,
+			p.ClientIP,
+			p.Method,
+			redactQuery(p.Path),
+			p.ErrorMessage,
+		)
+	})
+}
+
+// redactQuery replaces the value of the "token" parameter in a request URI.
+// A query that cannot be parsed is dropped.
+func redactQuery(uri string) string {
+	path, rawQuery, ok := strings.Cut(uri, "?")
+	if !ok {
+		return uri
+	}
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return path
+	}
+	if !q.Has("token") {
+		return uri
+	}
+	q.Set("token", "redacted")
+	return path + "?" + q.Encode()
+}
+
+// rejectBanned aborts the request with 403 if the caller's account is
+// banned, reporting whether it did.
+func (s *Server) rejectBanned(c *gin.Context, userID int64) bool {
+	banned, err := s.users.IsBanned(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("error checking ban of user %d: %v", userID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return true
+	}
+	if banned {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": user.ErrBanned.Error()})
+		return true
+	}
+	return false
+}
+
+func (s *Server) authenticateBot(c *gin.Context, token string) {
+	b, err := s.bots.GetByTokenHash(c.Request.Context(), hashToken(token))
+	if errors.Is(err, bot.ErrInvalidToken) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if err != nil {
+		log.Printf("error authenticating bot: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	if s.rejectBanned(c, b.UserID) {
+		return
+	}
+
+	c.Set(ctxUserID, b.UserID)
+	c.Set(ctxUsername, b.Username)
+	c.Set(ctxBot, b)
+	c.Next()
+}
+
+// currentBot returns the authenticated bot, or nil if the caller is a user.
+func currentBot(c *gin.Context) *bot.Bot {
+	v, _ := c.Get(ctxBot)
+	b, _ := v.(*bot.Bot)
+	return b
+}
+
+// currentUserID returns the id of the authenticated caller.
+func currentUserID(c *gin.Context) int64 {
+	return c.GetInt64(ctxUserID)
+}
+
+// currentUsername returns the username of the authenticated caller.
+func currentUsername(c *gin.Context) string {
+	return c.GetString(ctxUsername)
+}
+
+// isAdmin reports whether userID is a server admin.
+func (s *Server) isAdmin(userID int64) bool {
+	return s.admins[userID]
+}
+
+// requireServerAdmin responds with 403 unless the caller is a server admin.
+func (s *Server) requireServerAdmin(c *gin.Context) bool {
+	if !s.isAdmin(currentUserID(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can do this"})
+		return false
+	}
+	return true
+}
+
+// rateLimitIP limits requests per client IP address.
+func (s *Server) rateLimitIP() gin.HandlerFunc {
+	return s.rateLimit("http:ip:", s.httpIPRate, func(c *gin.Context) string {
+		return c.ClientIP()
+	})
+}
+
+// rateLimitUser limits requests per authenticated caller. It must run after
+// requireAuth.
+func (s *Server) rateLimitUser() gin.HandlerFunc {
+	return s.rateLimit("http:user:", s.httpUserRate, func(c *gin.Context) string {
+		return strconv.FormatInt(currentUserID(c), 10)
+	})
+}
+
+// rateLimit rejects requests with 429 once the token bucket of their key is
+// empty. The buckets live in Redis so the limit holds across instances; if
+// Redis is unavailable requests are let through.
+func (s *Server) rateLimit(prefix string, rate ratelimit.Rate, key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rate.Enabled() {
+			c.Next()
+			return
+		}
+
+		res, err := s.buckets.Take(c.Request.Context(), prefix+key(c), rate)
+		if err != nil {
+			log.Printf("error checking rate limit: %v", err)
+		} else if !res.Allowed {
+			respondRateLimited(c, res.RetryAfter)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// respondRateLimited responds with 429 and a Retry-After header in whole
+// seconds.
+func respondRateLimited(c *gin.Context, retryAfter time.Duration) {
+	seconds := retryAfterSeconds(retryAfter)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded", "code": "rate_limited", "retry_after": seconds})
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds, at least one.
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
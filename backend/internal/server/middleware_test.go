@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/auth"
+	"backend/internal/domain/ratelimit"
+	"backend/internal/domain/user"
+	"backend/internal/origin"
+)
+
+// fakeBuckets allows the first n requests of each key.
+type fakeBuckets struct {
+	n     int
+	taken map[string]int
+	err   error
+}
+
+func (f *fakeBuckets) Take(ctx context.Context, key string, rate ratelimit.Rate) (ratelimit.Result, error) {
+	if f.err != nil {
+		return ratelimit.Result{}, f.err
+	}
+	f.taken[key]++
+	if f.taken[key] > f.n {
+		return ratelimit.Result{RetryAfter: 1500 * time.Millisecond}, nil
+	}
+	return ratelimit.Result{Allowed: true}, nil
+}
+
+func TestRateLimitIP(t *testing.T) {
+	buckets := &fakeBuckets{n: 2, taken: map[string]int{}}
+	s := &Server{buckets: buckets, httpIPRate: ratelimit.Rate{PerSecond: 1, Burst: 2}}
+	r := gin.New()
+	r.Use(s.rateLimitIP())
+	r.GET("/", s.HelloWorldHandler)
+
+	get := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 2; i++ {
+		if rr := get("203.0.113.1"); rr.Code != http.StatusOK {
+			t.Fatalf("request %d: got status %d want %d", i, rr.Code, http.StatusOK)
+		}
+	}
+	rr := get("203.0.113.1")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over limit: got status %d want %d", rr.Code, http.StatusTooManyRequests)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After: got %q want %q", got, "2")
+	}
+	if rr := get("203.0.113.2"); rr.Code != http.StatusOK {
+		t.Errorf("other ip: got status %d want %d", rr.Code, http.StatusOK)
+	}
+
+	// A Redis outage lets requests through.
+	buckets.err = errors.New("connection refused")
+	if rr := get("203.0.113.1"); rr.Code != http.StatusOK {
+		t.Errorf("redis down: got status %d want %d", rr.Code, http.StatusOK)
+	}
+}
+
+// fakeUsers serves the users in users and reports those in banned as
+// banned; its other methods are not implemented.
+type fakeUsers struct {
+	user.Repository
+	users  map[int64]*user.User
+	banned map[int64]bool
+}
+
+func (f *fakeUsers) Get(ctx context.Context, id int64) (*user.User, error) {
+	if u, ok := f.users[id]; ok {
+		return u, nil
+	}
+	return nil, user.ErrNotFound
+}
+
+func (f *fakeUsers) IsBanned(ctx context.Context, id int64) (bool, error) {
+	return f.banned[id], nil
+}
+
+func TestCookieAuthCSRF(t *testing.T) {
+	tokens := auth.NewTokenManager("test-secret", time.Hour)
+	origins, err := origin.Parse("https://chatvui.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{tokens: tokens, origins: origins, csrfKey: []byte("test-secret"), users: &fakeUsers{}}
+	r := gin.New()
+	api := r.Group("/api", s.requireAuth())
+	api.GET("/csrf", s.csrfHandler)
+	api.POST("/echo", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	session, err := tokens.Issue(7, "an")
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, path, originHeader, csrf string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: session})
+		if originHeader != "" {
+			req.Header.Set("Origin", originHeader)
+		}
+		if csrf != "" {
+			req.Header.Set(csrfHeader, csrf)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("GET", "/api/csrf", "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET /api/csrf: got status %d want %d", rr.Code, http.StatusOK)
+	}
+	var body struct {
+		Token string `json:"csrf_token"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &body)
+
+	tests := []struct {
+		name         string
+		origin, csrf string
+		want         int
+	}{
+		{"valid token", "https://chatvui.com", body.Token, http.StatusNoContent},
+		{"no origin header", "", body.Token, http.StatusNoContent},
+		{"same origin", "http://example.com", body.Token, http.StatusNoContent},
+		{"missing token", "https://chatvui.com", "", http.StatusForbidden},
+		{"wrong token", "https://chatvui.com", "deadbeef", http.StatusForbidden},
+		{"foreign origin", "https://evil.com", body.Token, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if rr := do("POST", "/api/echo", tt.origin, tt.csrf); rr.Code != tt.want {
+			t.Errorf("%s: got status %d want %d", tt.name, rr.Code, tt.want)
+		}
+	}
+
+	// Bearer tokens are not sent automatically by browsers and need no CSRF
+	// token.
+	req := httptest.NewRequest("POST", "/api/echo", nil)
+	req.Header.Set("Authorization", "Bearer "+session)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("bearer token: got status %d want %d", rr.Code, http.StatusNoContent)
+	}
+}
+
+func TestRequireAuthRejectsBannedUsers(t *testing.T) {
+	tokens := auth.NewTokenManager("test-secret", time.Hour)
+	s := &Server{tokens: tokens, users: &fakeUsers{banned: map[int64]bool{7: true}}}
+	r := gin.New()
+	r.GET("/api/me", s.requireAuth(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for id, want := range map[int64]int{7: http.StatusForbidden, 8: http.StatusNoContent} {
+		token, err := tokens.Issue(id, "an")
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("GET", "/api/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("user %d: got status %d want %d", id, rr.Code, want)
+		}
+	}
+}
+
+func TestQueryTokenOnlyOnStreams(t *testing.T) {
+	tokens := auth.NewTokenManager("test-secret", time.Hour)
+	s := &Server{tokens: tokens, users: &fakeUsers{}}
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r.GET("/api/events", s.requireStreamAuth(), ok)
+	r.GET("/api/me", s.requireAuth(), ok)
+	r.GET("/chat/5/events", s.requirePageAuth(), ok)
+
+	token, err := tokens.Issue(7, "an")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]int{"/api/events": http.StatusNoContent, "/api/me": http.StatusUnauthorized, "/chat/5/events": http.StatusUnauthorized} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", path+"?token="+token, nil))
+		if rr.Code != want {
+			t.Errorf("%s: got status %d want %d", path, rr.Code, want)
+		}
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"/api/me", "/api/me"},
+		{"/api/search?q=hi", "/api/search?q=hi"},
+		{"/api/ws?token=eyJhbGci.x.y", "/api/ws?token=redacted"},
+		{"/api/events?last_event_id=3&token=eyJ", "/api/events?last_event_id=3&token=redacted"},
+		{"/api/events?token=%zz", "/api/events"},
+	}
+	for _, tt := range tests {
+		if got := redactQuery(tt.in); got != tt.want {
+			t.Errorf("redactQuery(%q): got %q want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,477 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/domain/moderation"
+	"backend/internal/domain/user"
+	"backend/internal/websocket"
+)
+
+type reportRequest struct {
+	Reason string `json:"reason"`
+}
+
+type sanctionRequest struct {
+	Duration string `json:"duration"`
+	Reason   string `json:"reason"`
+}
+
+// moderationEvent is the payload of the moderation websocket event, sent to
+// the members of a conversation when one of them is muted or unmuted.
+type moderationEvent struct {
+	ConversationID int64                  `json:"conversation_id"`
+	UserID         int64                  `json:"user_id"`
+	Action         moderation.AuditAction `json:"action"`
+	ActorID        int64                  `json:"actor_id"`
+	ExpiresAt      *time.Time             `json:"expires_at,omitempty"`
+}
+
+// memberRemovedEvent is the payload of the member_removed websocket event.
+type memberRemovedEvent struct {
+	ConversationID int64 `json:"conversation_id"`
+	UserID         int64 `json:"user_id"`
+	RemovedBy      int64 `json:"removed_by"`
+	Banned         bool  `json:"banned"`
+}
+
+// reportMessageHandler serves POST /api/messages/:id/report
+func (s *Server) reportMessageHandler(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	var req reportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if utf8.RuneCountInString(req.Reason) > moderation.MaxReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": moderation.ErrInvalidReason.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	msg, err := s.messages.Get(ctx, id)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	if msg.IsDeleted() {
+		respondMessageError(c, message.ErrDeleted)
+		return
+	}
+	if !s.requireMember(c, msg.ConversationID) {
+		return
+	}
+
+	reporterID := currentUserID(c)
+	f := &moderation.Flag{
+		ConversationID: &msg.ConversationID,
+		MessageID:      &msg.ID,
+		UserID:         msg.SenderID,
+		ReporterID:     &reporterID,
+		Source:         moderation.SourceReport,
+		Reason:         req.Reason,
+	}
+	if err := s.flags.Create(ctx, f); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+	s.audit(ctx, &moderation.AuditEntry{
+		ConversationID: &msg.ConversationID,
+		ActorID:        reporterID,
+		Action:         moderation.AuditReport,
+		TargetUserID:   &msg.SenderID,
+		MessageID:      &msg.ID,
+		FlagID:         &f.ID,
+		Reason:         req.Reason,
+	})
+	c.JSON(http.StatusCreated, f)
+}
+
+// listReportsHandler serves GET /api/conversations/:id/reports
+func (s *Server) listReportsHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	limit, offset, err := pagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status := c.DefaultQuery("status", moderation.FlagPending)
+	if status != moderation.FlagPending && status != moderation.FlagResolved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	flags, err := s.flags.ListFlags(c.Request.Context(), moderation.FlagQuery{
+		ConversationID: conversationID,
+		Status:         status,
+		Limit:          limit,
+		Offset:         offset,
+	})
+	if err != nil {
+		respondModerationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": flags, "limit": limit, "offset": offset})
+}
+
+// resolveReportHandler serves POST /api/conversations/:id/reports/:flag_id/resolve
+func (s *Server) resolveReportHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	flagID, ok := pathParamID(c, "flag_id")
+	if !ok {
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	f, err := s.flags.GetFlag(ctx, flagID)
+	if err == nil && (f.ConversationID == nil || *f.ConversationID != conversationID) {
+		err = moderation.ErrFlagNotFound
+	}
+	if err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	actorID := currentUserID(c)
+	if err := s.flags.ResolveFlag(ctx, flagID, actorID); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+	s.audit(ctx, &moderation.AuditEntry{
+		ConversationID: &conversationID,
+		ActorID:        actorID,
+		Action:         moderation.AuditResolveFlag,
+		TargetUserID:   &f.UserID,
+		MessageID:      f.MessageID,
+		FlagID:         &f.ID,
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// muteMemberHandler serves POST /api/conversations/:id/members/:user_id/mute
+func (s *Server) muteMemberHandler(c *gin.Context) {
+	s.sanctionMember(c, moderation.SanctionMute)
+}
+
+// banMemberHandler serves POST /api/conversations/:id/members/:user_id/ban
+func (s *Server) banMemberHandler(c *gin.Context) {
+	s.sanctionMember(c, moderation.SanctionBan)
+}
+
+// unmuteMemberHandler serves DELETE /api/conversations/:id/members/:user_id/mute
+func (s *Server) unmuteMemberHandler(c *gin.Context) {
+	s.liftSanction(c, moderation.SanctionMute)
+}
+
+// unbanMemberHandler serves DELETE /api/conversations/:id/members/:user_id/ban
+func (s *Server) unbanMemberHandler(c *gin.Context) {
+	s.liftSanction(c, moderation.SanctionBan)
+}
+
+// sanctionMember mutes or bans a user. Mutes need a duration; bans without
+// one are permanent. A banned member is removed from the conversation.
+func (s *Server) sanctionMember(c *gin.Context, kind moderation.SanctionKind) {
+	conversationID, targetID, ok := s.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	var req sanctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if utf8.RuneCountInString(req.Reason) > moderation.MaxReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": moderation.ErrInvalidReason.Error()})
+		return
+	}
+	var d time.Duration
+	if req.Duration != "" || kind == moderation.SanctionMute {
+		var err error
+		if d, err = time.ParseDuration(req.Duration); err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": moderation.ErrInvalidPeriod.Error()})
+			return
+		}
+	}
+	expiresAt := moderation.Expiry(time.Now(), d)
+
+	ctx := c.Request.Context()
+	actorID := currentUserID(c)
+	if kind == moderation.SanctionMute {
+		// Only members can be muted, while bans also keep users out
+		// pre-emptively.
+		member, err := s.conversations.IsMember(ctx, conversationID, targetID)
+		if err != nil {
+			respondModerationError(c, err)
+			return
+		}
+		if !member {
+			respondModerationError(c, conversation.ErrNotMember)
+			return
+		}
+	}
+
+	sanction := &moderation.Sanction{
+		ConversationID: conversationID,
+		UserID:         targetID,
+		Kind:           kind,
+		Reason:         req.Reason,
+		ExpiresAt:      expiresAt,
+		CreatedBy:      actorID,
+	}
+	if err := s.sanctions.Sanction(ctx, sanction); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	action := moderation.AuditMute
+	if kind == moderation.SanctionBan {
+		action = moderation.AuditBan
+		if err := s.removeMember(ctx, conversationID, targetID, actorID, true); err != nil && !errors.Is(err, conversation.ErrNotMember) {
+			respondModerationError(c, err)
+			return
+		}
+	} else {
+		s.publish(ctx, conversationID, websocket.Message{
+			Type: websocket.EventModeration,
+			Data: moderationEvent{ConversationID: conversationID, UserID: targetID, Action: action, ActorID: actorID, ExpiresAt: expiresAt},
+		})
+	}
+	s.audit(ctx, &moderation.AuditEntry{
+		ConversationID: &conversationID,
+		ActorID:        actorID,
+		Action:         action,
+		TargetUserID:   &targetID,
+		Reason:         req.Reason,
+		ExpiresAt:      expiresAt,
+	})
+	c.JSON(http.StatusCreated, sanction)
+}
+
+// liftSanction unmutes or unbans a user.
+func (s *Server) liftSanction(c *gin.Context, kind moderation.SanctionKind) {
+	conversationID, targetID, ok := s.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := s.sanctions.Lift(ctx, conversationID, targetID, kind); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	actorID := currentUserID(c)
+	action := moderation.AuditUnban
+	if kind == moderation.SanctionMute {
+		action = moderation.AuditUnmute
+		s.publish(ctx, conversationID, websocket.Message{
+			Type: websocket.EventModeration,
+			Data: moderationEvent{ConversationID: conversationID, UserID: targetID, Action: action, ActorID: actorID},
+		})
+	}
+	s.audit(ctx, &moderation.AuditEntry{
+		ConversationID: &conversationID,
+		ActorID:        actorID,
+		Action:         action,
+		TargetUserID:   &targetID,
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// kickMemberHandler serves DELETE /api/conversations/:id/members/:user_id
+func (s *Server) kickMemberHandler(c *gin.Context) {
+	conversationID, targetID, ok := s.moderationTarget(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	actorID := currentUserID(c)
+	if err := s.removeMember(ctx, conversationID, targetID, actorID, false); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+	s.audit(ctx, &moderation.AuditEntry{
+		ConversationID: &conversationID,
+		ActorID:        actorID,
+		Action:         moderation.AuditKick,
+		TargetUserID:   &targetID,
+		Reason:         c.Query("reason"),
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// listSanctionsHandler serves GET /api/conversations/:id/sanctions
+func (s *Server) listSanctionsHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	sanctions, err := s.sanctions.ListSanctions(c.Request.Context(), conversationID, time.Now())
+	if err != nil {
+		respondModerationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sanctions": sanctions})
+}
+
+// moderationLogHandler serves GET /api/conversations/:id/moderation-log
+func (s *Server) moderationLogHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+	limit, offset, err := pagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return
+	}
+
+	entries, err := s.auditLog.AuditLog(c.Request.Context(), conversationID, limit, offset)
+	if err != nil {
+		respondModerationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "limit": limit, "offset": offset})
+}
+
+// moderationTarget parses the conversation and target user of a moderation
+// action and checks that the caller is an admin acting on someone who is
+// not.
+func (s *Server) moderationTarget(c *gin.Context) (conversationID, targetID int64, ok bool) {
+	if conversationID, ok = pathID(c); !ok {
+		return 0, 0, false
+	}
+	if targetID, ok = pathParamID(c, "user_id"); !ok {
+		return 0, 0, false
+	}
+	if !s.requireConversationAdmin(c, conversationID) {
+		return 0, 0, false
+	}
+
+	admin, err := s.conversations.IsAdmin(c.Request.Context(), conversationID, targetID)
+	if err != nil {
+		respondModerationError(c, err)
+		return 0, 0, false
+	}
+	if admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": moderation.ErrInvalidTarget.Error()})
+		return 0, 0, false
+	}
+	return conversationID, targetID, true
+}
+
+// removeMember removes a kicked or banned user from the conversation, cuts
+// their live connections off it and tells the remaining members.
+func (s *Server) removeMember(ctx context.Context, conversationID, userID, removedBy int64, banned bool) error {
+	if err := s.conversations.RemoveMember(ctx, conversationID, userID); err != nil {
+		return err
+	}
+
+	event := websocket.Message{
+		Type:           websocket.EventMemberRemoved,
+		ConversationID: conversationID,
+		Data:           memberRemovedEvent{ConversationID: conversationID, UserID: userID, RemovedBy: removedBy, Banned: banned},
+	}
+	notice, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("error marshaling event: %v", err)
+	}
+	s.hub.RemoveFromConversation(userID, conversationID, event.Type, notice)
+	s.publish(ctx, conversationID, event)
+	return nil
+}
+
+// checkMuted returns moderation.ErrMuted if the sender of msg is muted in
+// its conversation.
+func (s *Server) checkMuted(ctx context.Context, msg *message.Message) error {
+	mute, err := s.sanctions.ActiveSanction(ctx, msg.ConversationID, msg.SenderID, moderation.SanctionMute, time.Now())
+	if err != nil {
+		return err
+	}
+	if mute != nil {
+		return moderation.ErrMuted
+	}
+	return nil
+}
+
+// checkBanned returns moderation.ErrBanned if the user is banned from the
+// conversation.
+func (s *Server) checkBanned(ctx context.Context, conversationID, userID int64) error {
+	ban, err := s.sanctions.ActiveSanction(ctx, conversationID, userID, moderation.SanctionBan, time.Now())
+	if err != nil {
+		return err
+	}
+	if ban != nil {
+		return moderation.ErrBanned
+	}
+	return nil
+}
+
+// audit records a moderation action. Failures are logged rather than
+// undoing an action that already took effect.
+func (s *Server) audit(ctx context.Context, e *moderation.AuditEntry) {
+	if err := s.auditLog.Record(ctx, e); err != nil {
+		log.Printf("error recording moderation action %s: %v", e.Action, err)
+	}
+}
+
+// pathParamID parses a numeric path parameter, responding with 400 if it is
+// invalid.
+func pathParamID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func respondModerationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, moderation.ErrFlagNotFound), errors.Is(err, moderation.ErrNotSanctioned):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, conversation.ErrNotMember):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user is not a member of this conversation"})
+	case errors.Is(err, user.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		log.Printf("error handling moderation request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/moderation"
+)
+
+// newModerationTestServer has a group conversation 5 with the members 1 and
+// 2 and the admins 3 and 4.
+func newModerationTestServer(t *testing.T) *testServer {
+	ts := newTestServer(t)
+	ts.conversations.add(&conversation.Conversation{ID: 5, IsGroup: true}, []int64{1, 2}, 3, 4)
+	ts.addUser(1, "an")
+	return ts
+}
+
+func TestModerationRequiresAdmin(t *testing.T) {
+	ts := newModerationTestServer(t)
+	mute := sanctionRequest{Duration: "1h"}
+
+	expect(t, "member mutes", ts.do("POST", "/api/conversations/5/members/1/mute", 2, mute), http.StatusForbidden)
+	expect(t, "member bans", ts.do("POST", "/api/conversations/5/members/1/ban", 2, sanctionRequest{}), http.StatusForbidden)
+	expect(t, "member kicks", ts.do("DELETE", "/api/conversations/5/members/1", 2, nil), http.StatusForbidden)
+	expect(t, "member unmutes", ts.do("DELETE", "/api/conversations/5/members/1/mute", 2, nil), http.StatusForbidden)
+	expect(t, "admin mutes admin", ts.do("POST", "/api/conversations/5/members/4/mute", 3, mute), http.StatusForbidden)
+	expect(t, "admin bans admin", ts.do("POST", "/api/conversations/5/members/4/ban", 3, sanctionRequest{}), http.StatusForbidden)
+	expect(t, "admin kicks admin", ts.do("DELETE", "/api/conversations/5/members/4", 3, nil), http.StatusForbidden)
+	if len(ts.sanctions.sanctions) != 0 || len(ts.conversations.members[5]) != 4 || len(ts.auditLog.entries) != 0 {
+		t.Errorf("refused actions took effect: sanctions %+v, members %v", ts.sanctions.sanctions, ts.conversations.members[5])
+	}
+}
+
+func TestMuteMember(t *testing.T) {
+	ts := newModerationTestServer(t)
+	send := func() int {
+		return ts.do("POST", "/api/conversations/5/messages", 1, createMessageRequest{Content: "hi"}).Code
+	}
+
+	expect(t, "no duration", ts.do("POST", "/api/conversations/5/members/1/mute", 3, sanctionRequest{}), http.StatusBadRequest)
+	expect(t, "invalid duration", ts.do("POST", "/api/conversations/5/members/1/mute", 3, sanctionRequest{Duration: "-1h"}), http.StatusBadRequest)
+	expect(t, "non-member", ts.do("POST", "/api/conversations/5/members/9/mute", 3, sanctionRequest{Duration: "1h"}), http.StatusNotFound)
+
+	expect(t, "mute", ts.do("POST", "/api/conversations/5/members/1/mute", 3, sanctionRequest{Duration: "1h", Reason: "flooding"}), http.StatusCreated)
+	if got := send(); got != http.StatusForbidden {
+		t.Errorf("muted sender: got status %d want %d", got, http.StatusForbidden)
+	}
+
+	expect(t, "unmute", ts.do("DELETE", "/api/conversations/5/members/1/mute", 3, nil), http.StatusNoContent)
+	if got := send(); got != http.StatusCreated {
+		t.Errorf("unmuted sender: got status %d want %d", got, http.StatusCreated)
+	}
+	expect(t, "unmute again", ts.do("DELETE", "/api/conversations/5/members/1/mute", 3, nil), http.StatusNotFound)
+
+	var actions []moderation.AuditAction
+	for _, e := range ts.auditLog.entries {
+		actions = append(actions, e.Action)
+	}
+	if len(actions) != 2 || actions[0] != moderation.AuditMute || actions[1] != moderation.AuditUnmute {
+		t.Errorf("audit log: got %v", actions)
+	}
+}
+
+func TestBanMember(t *testing.T) {
+	ts := newModerationTestServer(t)
+
+	expect(t, "ban", ts.do("POST", "/api/conversations/5/members/1/ban", 3, sanctionRequest{}), http.StatusCreated)
+	if _, ok := ts.conversations.members[5][1]; ok {
+		t.Fatal("banned user is still a member")
+	}
+	if s := ts.sanctions.sanctions; len(s) != 1 || s[0].ExpiresAt != nil {
+		t.Errorf("sanctions: got %+v want one permanent ban", s)
+	}
+	expect(t, "send while banned", ts.do("POST", "/api/conversations/5/messages", 1, createMessageRequest{Content: "hi"}), http.StatusForbidden)
+	expect(t, "re-add", ts.do("POST", "/api/conversations/5/members", 3, addMemberRequest{UserID: 1}), http.StatusForbidden)
+
+	// Bans keep out users who are not members yet.
+	expect(t, "ban non-member", ts.do("POST", "/api/conversations/5/members/9/ban", 3, sanctionRequest{Duration: "24h"}), http.StatusCreated)
+	expect(t, "add banned", ts.do("POST", "/api/conversations/5/members", 3, addMemberRequest{UserID: 9}), http.StatusForbidden)
+
+	expect(t, "unban", ts.do("DELETE", "/api/conversations/5/members/1/ban", 3, nil), http.StatusNoContent)
+	expect(t, "add after unban", ts.do("POST", "/api/conversations/5/members", 3, addMemberRequest{UserID: 1}), http.StatusCreated)
+}
+
+func TestKickMember(t *testing.T) {
+	ts := newModerationTestServer(t)
+
+	expect(t, "kick", ts.do("DELETE", "/api/conversations/5/members/1?reason=rude", 3, nil), http.StatusNoContent)
+	if _, ok := ts.conversations.members[5][1]; ok {
+		t.Fatal("kicked user is still a member")
+	}
+	expect(t, "kick non-member", ts.do("DELETE", "/api/conversations/5/members/1", 3, nil), http.StatusNotFound)
+	// Unlike a ban, a kick does not stop the user from being added back.
+	expect(t, "re-add", ts.do("POST", "/api/conversations/5/members", 3, addMemberRequest{UserID: 1}), http.StatusCreated)
+
+	if e := ts.auditLog.entries; len(e) != 1 || e[0].Action != moderation.AuditKick || e[0].Reason != "rude" {
+		t.Errorf("audit log: got %+v", e)
+	}
+}
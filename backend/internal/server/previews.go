@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"backend/internal/domain/message"
+	"backend/internal/unfurl"
+	"backend/internal/websocket"
+)
+
+const (
+	linkPreviewTTL       = 24 * time.Hour
+	failedLinkPreviewTTL = time.Hour
+)
+
+// enqueueLinkPreviews schedules unfurling of the URLs in msg. Messages
+// without URLs are skipped unless an edit removed the URLs of stored previews.
+func (s *Server) enqueueLinkPreviews(msg *message.Message) {
+	if len(unfurl.ExtractURLs(msg.Content)) == 0 && len(msg.LinkPreviews) == 0 {
+		return
+	}
+	id := msg.ID
+	if !s.previewWorkers.Submit(func(ctx context.Context) { s.unfurlMessage(ctx, id) }) {
+		log.Printf("link preview queue is full, skipping message %d", id)
+	}
+}
+
+// unfurlMessage runs on a preview worker. It stores the previews of the
+// URLs in a message and pushes the updated message to the conversation.
+func (s *Server) unfurlMessage(ctx context.Context, id int64) {
+	msg, err := s.messages.Get(ctx, id)
+	if err != nil {
+		log.Printf("error loading message %d for link previews: %v", id, err)
+		return
+	}
+
+	previews := []message.LinkPreview{}
+	for _, url := range unfurl.ExtractURLs(msg.Content) {
+		if p := s.linkPreview(ctx, url); p != nil {
+			previews = append(previews, *p)
+		}
+	}
+	if len(previews) == 0 && len(msg.LinkPreviews) == 0 {
+		return
+	}
+
+	// The message may have been edited or deleted while we were fetching.
+	current, err := s.messages.Get(ctx, id)
+	if err != nil || current.IsDeleted() || current.Content != msg.Content {
+		return
+	}
+	if err := s.messages.SetLinkPreviews(ctx, id, previews); err != nil {
+		log.Printf("error storing link previews of message %d: %v", id, err)
+		return
+	}
+	current.LinkPreviews = previews
+
+	s.publishMessageEvent(ctx, current, websocket.Message{Type: websocket.EventMessageUpdated, Data: current})
+}
+
+// linkPreview returns the preview of url from the cache, fetching and
+// caching it on a miss. Failures are cached too so that broken links are not
+// fetched for every message that repeats them.
+func (s *Server) linkPreview(ctx context.Context, url string) *message.LinkPreview {
+	p, found, err := s.previewCache.Get(ctx, url)
+	if err != nil {
+		log.Printf("error reading link preview cache: %v", err)
+	} else if found {
+		return p
+	}
+
+	p, err = s.unfurler.Fetch(ctx, url)
+	ttl := linkPreviewTTL
+	if err != nil || p.IsEmpty() {
+		p, ttl = nil, failedLinkPreviewTTL
+	}
+	if err := s.previewCache.Set(ctx, url, p, ttl); err != nil {
+		log.Printf("error writing link preview cache: %v", err)
+	}
+	return p
+}
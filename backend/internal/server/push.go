@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/message"
+	"backend/internal/domain/push"
+	"backend/internal/notification"
+	"backend/internal/unfurl"
+)
+
+const pushBodyLength = 120
+
+// mutedForever is stored for mutes without an end; it is the largest value
+// a MySQL TIMESTAMP can hold.
+var mutedForever = time.Date(2038, 1, 19, 0, 0, 0, 0, time.UTC)
+
+// pushSubscriptionRequest mirrors the JSON of a browser PushSubscription.
+type pushSubscriptionRequest struct {
+	Endpoint       string `json:"endpoint"`
+	ExpirationTime *int64 `json:"expirationTime"`
+	Keys           struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+type muteRequest struct {
+	// Until ends the mute; when omitted the conversation stays muted until
+	// it is unmuted.
+	Until *time.Time `json:"until"`
+}
+
+// vapidKeyHandler serves GET /api/push/vapid-public-key
+func (s *Server) vapidKeyHandler(c *gin.Context) {
+	if s.notifications == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "push notifications are not configured"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"public_key": s.vapidPublicKey})
+}
+
+// createPushSubscriptionHandler serves POST /api/push/subscriptions
+func (s *Server) createPushSubscriptionHandler(c *gin.Context) {
+	var req pushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Endpoint == "" || req.Keys.P256dh == "" || req.Keys.Auth == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endpoint and keys are required"})
+		return
+	}
+	if !validPushEndpoint(req.Endpoint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endpoint must be an https URL of a public host"})
+		return
+	}
+
+	sub := &push.Subscription{
+		UserID:   currentUserID(c),
+		Endpoint: req.Endpoint,
+		P256dh:   req.Keys.P256dh,
+		Auth:     req.Keys.Auth,
+	}
+	if req.ExpirationTime != nil {
+		t := time.UnixMilli(*req.ExpirationTime)
+		sub.ExpiresAt = &t
+	}
+	err := s.pushSubscriptions.Save(c.Request.Context(), sub)
+	if errors.Is(err, push.ErrEndpointTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("error saving push subscription: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// validPushEndpoint reports whether a subscription endpoint may be sent
+// notifications: an https URL whose host is not a private or local address.
+// Names resolving to such addresses are refused when dialing.
+func validPushEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); ip != nil {
+		return unfurl.IsPublicIP(ip)
+	}
+	if !strings.Contains(host, ".") {
+		return false
+	}
+	for _, suffix := range []string{".localhost", ".local", ".internal", ".localdomain"} {
+		if strings.HasSuffix(host, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// deletePushSubscriptionHandler serves DELETE /api/push/subscriptions/:id
+func (s *Server) deletePushSubscriptionHandler(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	err := s.pushSubscriptions.DeleteForUser(c.Request.Context(), id, currentUserID(c))
+	if errors.Is(err, push.ErrSubscriptionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("error deleting push subscription: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// muteConversationHandler serves PUT /api/conversations/:id/mute
+func (s *Server) muteConversationHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	var req muteRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	until := mutedForever
+	if req.Until != nil {
+		if !req.Until.After(time.Now()) || req.Until.After(mutedForever) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be in the future"})
+			return
+		}
+		until = req.Until.UTC()
+	}
+
+	if err := s.conversations.SetMutedUntil(c.Request.Context(), conversationID, currentUserID(c), &until); err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"conversation_id": conversationID, "muted_until": until})
+}
+
+// unmuteConversationHandler serves DELETE /api/conversations/:id/mute
+func (s *Server) unmuteConversationHandler(c *gin.Context) {
+	conversationID, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.conversations.SetMutedUntil(c.Request.Context(), conversationID, currentUserID(c), nil); err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// enqueuePushNotifications schedules Web Push notifications of msg for the
+// members of its conversation that have no live connection and have not
+// muted it. Mentioned users are notified even if they muted it.
+func (s *Server) enqueuePushNotifications(msg *message.Message, mentioned []int64) {
+	if s.notifications == nil {
+		return
+	}
+	m := *msg
+	if !s.pushWorkers.Submit(func(ctx context.Context) { s.notifyOffline(ctx, &m, mentioned) }) {
+		log.Printf("push notification queue is full, skipping message %d", m.ID)
+	}
+}
+
+func (s *Server) notifyOffline(ctx context.Context, msg *message.Message, mentioned []int64) {
+	members, err := s.conversations.MemberIDs(ctx, msg.ConversationID)
+	if err != nil {
+		log.Printf("error loading members of conversation %d: %v", msg.ConversationID, err)
+		return
+	}
+	muted, err := s.conversations.MutedUserIDs(ctx, msg.ConversationID, time.Now())
+	if err != nil {
+		log.Printf("error loading muted members of conversation %d: %v", msg.ConversationID, err)
+		return
+	}
+
+	skip := map[int64]bool{msg.SenderID: true}
+	for _, id := range muted {
+		skip[id] = true
+	}
+	for _, id := range mentioned {
+		skip[id] = false
+	}
+	var offline []int64
+	for _, id := range members {
+		if !skip[id] && !s.hub.IsOnline(id) {
+			offline = append(offline, id)
+		}
+	}
+	if len(offline) == 0 {
+		return
+	}
+
+	if err := s.notifications.Notify(ctx, offline, pushPayload(msg)); err != nil {
+		log.Printf("error sending push notifications for message %d: %v", msg.ID, err)
+	}
+}
+
+// pushPayload summarizes msg for a notification.
+func pushPayload(msg *message.Message) notification.Payload {
+	body := msg.Content
+	if utf8.RuneCountInString(body) > pushBodyLength {
+		body = string([]rune(body)[:pushBodyLength]) + "…"
+	}
+	if body == "" && len(msg.Attachments) > 0 {
+		body = "Sent an attachment"
+	}
+	return notification.Payload{
+		Title:          "New message",
+		Body:           body,
+		ConversationID: msg.ConversationID,
+		MessageID:      msg.ID,
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/message"
+	"backend/internal/websocket"
+)
+
+type reactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+// reactionEvent is the payload of the reaction websocket event.
+type reactionEvent struct {
+	MessageID int64  `json:"message_id"`
+	UserID    int64  `json:"user_id"`
+	Emoji     string `json:"emoji"`
+	Action    string `json:"action"`
+}
+
+// addReactionHandler serves POST /api/messages/:id/reactions
+func (s *Server) addReactionHandler(c *gin.Context) {
+	var req reactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "emoji is required"})
+		return
+	}
+	s.changeReaction(c, req.Emoji, "added")
+}
+
+// removeReactionHandler serves DELETE /api/messages/:id/reactions?emoji=
+func (s *Server) removeReactionHandler(c *gin.Context) {
+	s.changeReaction(c, c.Query("emoji"), "removed")
+}
+
+func (s *Server) changeReaction(c *gin.Context, emoji, action string) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	if !message.ValidEmoji(emoji) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message.ErrInvalidEmoji.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	msg, err := s.messages.Get(ctx, id)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	if !s.requireMember(c, msg.ConversationID) {
+		return
+	}
+	if msg.IsDeleted() {
+		respondMessageError(c, message.ErrDeleted)
+		return
+	}
+
+	reaction := message.Reaction{MessageID: id, UserID: currentUserID(c), Emoji: emoji}
+	if action == "added" {
+		err = s.reactions.Add(ctx, reaction)
+	} else {
+		err = s.reactions.Remove(ctx, reaction)
+	}
+	switch {
+	case errors.Is(err, message.ErrAlreadyReacted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, message.ErrReactionMissing):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		respondMessageError(c, err)
+		return
+	}
+
+	s.publishMessageEvent(ctx, msg, websocket.Message{
+		Type: websocket.EventReaction,
+		Data: reactionEvent{MessageID: id, UserID: reaction.UserID, Emoji: emoji, Action: action},
+	})
+
+	counts, err := s.reactions.Counts(ctx, []int64{id}, reaction.UserID)
+	if err != nil {
+		log.Printf("error counting reactions: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message_id": id, "reactions": counts[id]})
+}
+
+// attachReactions fills in the aggregated reaction counts of a page of
+// messages as seen by userID.
+func (s *Server) attachReactions(c *gin.Context, messages []message.Message, userID int64) error {
+	ids := make([]int64, 0, len(messages))
+	for _, m := range messages {
+		if !m.IsDeleted() {
+			ids = append(ids, m.ID)
+		}
+	}
+
+	counts, err := s.reactions.Counts(c.Request.Context(), ids, userID)
+	if err != nil {
+		return err
+	}
+	for i := range messages {
+		messages[i].Reactions = counts[messages[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+)
+
+// fakeReactions keeps reactions in memory.
+type fakeReactions struct {
+	reactions map[message.Reaction]bool
+}
+
+func (f *fakeReactions) Add(ctx context.Context, r message.Reaction) error {
+	if f.reactions[r] {
+		return message.ErrAlreadyReacted
+	}
+	f.reactions[r] = true
+	return nil
+}
+
+func (f *fakeReactions) Remove(ctx context.Context, r message.Reaction) error {
+	if !f.reactions[r] {
+		return message.ErrReactionMissing
+	}
+	delete(f.reactions, r)
+	return nil
+}
+
+func (f *fakeReactions) Counts(ctx context.Context, messageIDs []int64, userID int64) (map[int64][]message.ReactionCount, error) {
+	counts := map[int64][]message.ReactionCount{}
+	for r := range f.reactions {
+		counts[r.MessageID] = append(counts[r.MessageID], message.ReactionCount{Emoji: r.Emoji, Count: 1, Reacted: r.UserID == userID})
+	}
+	return counts, nil
+}
+
+func TestReactions(t *testing.T) {
+	ts := newTestServer(t)
+	ts.conversations.add(&conversation.Conversation{ID: 5, IsGroup: true}, []int64{1, 2})
+	ts.messages.add(&message.Message{ID: 10, SenderID: 2, ConversationID: 5, Content: "hi"})
+	deletedAt := time.Now()
+	ts.messages.add(&message.Message{ID: 11, SenderID: 2, ConversationID: 5, DeletedAt: &deletedAt})
+	reactions := &fakeReactions{reactions: map[message.Reaction]bool{}}
+	ts.reactions = reactions
+	thumbsUp := reactionRequest{Emoji: "👍"}
+	remove := func(id string, userID int64, emoji string) int {
+		return ts.do("DELETE", "/api/messages/"+id+"/reactions?emoji="+url.QueryEscape(emoji), userID, nil).Code
+	}
+
+	rr := ts.do("POST", "/api/messages/10/reactions", 1, thumbsUp)
+	expect(t, "add", rr, http.StatusOK)
+	var body struct {
+		Reactions []message.ReactionCount `json:"reactions"`
+	}
+	decodeJSON(t, rr, &body)
+	if len(body.Reactions) != 1 || !body.Reactions[0].Reacted {
+		t.Errorf("reactions: got %+v", body.Reactions)
+	}
+
+	expect(t, "duplicate", ts.do("POST", "/api/messages/10/reactions", 1, thumbsUp), http.StatusConflict)
+	expect(t, "invalid emoji", ts.do("POST", "/api/messages/10/reactions", 1, reactionRequest{Emoji: "nope"}), http.StatusBadRequest)
+	expect(t, "non-member", ts.do("POST", "/api/messages/10/reactions", 3, thumbsUp), http.StatusForbidden)
+	expect(t, "deleted message", ts.do("POST", "/api/messages/11/reactions", 1, thumbsUp), http.StatusGone)
+	expect(t, "missing message", ts.do("POST", "/api/messages/99/reactions", 1, thumbsUp), http.StatusNotFound)
+	if len(reactions.reactions) != 1 {
+		t.Errorf("refused reactions were stored: %v", reactions.reactions)
+	}
+
+	if got := remove("10", 2, "👍"); got != http.StatusNotFound {
+		t.Errorf("remove another user's reaction: got status %d want %d", got, http.StatusNotFound)
+	}
+	if got := remove("10", 1, "👍"); got != http.StatusOK {
+		t.Errorf("remove: got status %d want %d", got, http.StatusOK)
+	}
+	if got := remove("10", 1, "👍"); got != http.StatusNotFound {
+		t.Errorf("remove missing reaction: got status %d want %d", got, http.StatusNotFound)
+	}
+	if got := remove("10", 3, "👍"); got != http.StatusForbidden {
+		t.Errorf("remove as non-member: got status %d want %d", got, http.StatusForbidden)
+	}
+}
@@ -19,14 +19,17 @@ import (
 )
 
 func (s *Server) RegisterRoutes() http.Handler {
-	r := gin.Default()
+	r := gin.New()
+	r.Use(requestLogger(), gin.Recovery())
 
 	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173"}, // Add your frontend URL
+		AllowOriginFunc:  s.origins.Allowed, // ALLOWED_ORIGINS, shared with websocket upgrades
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", csrfHeader, "Last-Event-ID"},
 		AllowCredentials: true, // Enable cookies/auth
+		ExposeHeaders:    []string{"Retry-After"},
 	}))
+	r.Use(s.rateLimitIP())
 
 	r.GET("/", s.HelloWorldHandler)
 
@@ -36,6 +39,89 @@ func (s *Server) RegisterRoutes() http.Handler {
 
 	r.GET("/connect", s.connectHandler)
 
+	r.GET("/api/files/*key", s.fileHandler)
+	r.GET("/api/digest/unsubscribe", s.unsubscribeDigestPageHandler)
+	r.POST("/api/digest/unsubscribe", s.unsubscribeDigestHandler)
+	r.POST("/api/hooks/:token", s.incomingMessageHandler)
+
+	stream := r.Group("/api", s.requireStreamAuth(), s.rateLimitUser())
+	stream.GET("/ws", s.hubHandler)
+	stream.GET("/events", s.eventsHandler)
+
+	api := r.Group("/api", s.requireAuth(), s.rateLimitUser())
+	api.GET("/csrf", s.csrfHandler)
+	api.GET("/search", s.searchHandler)
+	api.GET("/mentions", s.mentionsHandler)
+	api.GET("/blocks", s.listBlocksHandler)
+	api.POST("/users/:id/block", s.blockUserHandler)
+	api.DELETE("/users/:id/block", s.unblockUserHandler)
+	api.PATCH("/conversations/:id", s.updateConversationHandler)
+	api.GET("/conversations/:id/messages", s.listMessagesHandler)
+	api.POST("/conversations/:id/messages", s.createMessageHandler)
+	api.POST("/conversations/:id/members", s.addMemberHandler)
+	api.DELETE("/conversations/:id/members/:user_id", s.kickMemberHandler)
+	api.POST("/conversations/:id/members/:user_id/mute", s.muteMemberHandler)
+	api.DELETE("/conversations/:id/members/:user_id/mute", s.unmuteMemberHandler)
+	api.POST("/conversations/:id/members/:user_id/ban", s.banMemberHandler)
+	api.DELETE("/conversations/:id/members/:user_id/ban", s.unbanMemberHandler)
+	api.GET("/conversations/:id/sanctions", s.listSanctionsHandler)
+	api.GET("/conversations/:id/reports", s.listReportsHandler)
+	api.POST("/conversations/:id/reports/:flag_id/resolve", s.resolveReportHandler)
+	api.GET("/conversations/:id/moderation-log", s.moderationLogHandler)
+	api.GET("/conversations/:id/incoming-webhooks", s.listIncomingWebhooksHandler)
+	api.POST("/conversations/:id/incoming-webhooks", s.createIncomingWebhookHandler)
+	api.DELETE("/conversations/:id/incoming-webhooks/:hook_id", s.revokeIncomingWebhookHandler)
+	api.GET("/conversations/:id/commands", s.listCommandsHandler)
+	api.POST("/conversations/:id/commands", s.createCommandHandler)
+	api.DELETE("/conversations/:id/commands/:command_id", s.deleteCommandHandler)
+	api.GET("/conversations/:id/filters", s.filterSettingsHandler)
+	api.PUT("/conversations/:id/filters", s.updateFilterSettingsHandler)
+	api.PUT("/conversations/:id/mute", s.muteConversationHandler)
+	api.DELETE("/conversations/:id/mute", s.unmuteConversationHandler)
+	api.PATCH("/messages/:id", s.editMessageHandler)
+	api.DELETE("/messages/:id", s.deleteMessageHandler)
+	api.GET("/messages/:id/edits", s.messageEditsHandler)
+	api.GET("/messages/:id/thread", s.threadHandler)
+	api.POST("/messages/:id/report", s.reportMessageHandler)
+	api.POST("/messages/:id/reactions", s.addReactionHandler)
+	api.DELETE("/messages/:id/reactions", s.removeReactionHandler)
+	api.POST("/uploads", s.uploadHandler)
+	api.POST("/uploads/images", s.imageUploadHandler)
+	api.GET("/attachments/:id", s.attachmentHandler)
+	api.GET("/push/vapid-public-key", s.vapidKeyHandler)
+	api.POST("/push/subscriptions", s.createPushSubscriptionHandler)
+	api.DELETE("/push/subscriptions/:id", s.deletePushSubscriptionHandler)
+	api.GET("/digest/preferences", s.digestPreferencesHandler)
+	api.PUT("/digest/preferences", s.updateDigestPreferencesHandler)
+	api.GET("/bots", s.listBotsHandler)
+	api.POST("/bots", s.createBotHandler)
+	api.PATCH("/bots/:id", s.updateBotHandler)
+	api.POST("/bots/:id/token", s.rotateBotTokenHandler)
+	api.DELETE("/bots/:id", s.deleteBotHandler)
+	api.GET("/moderation/queue", s.moderationQueueHandler)
+	api.POST("/moderation/queue/:id/resolve", s.resolveFlagHandler)
+	api.DELETE("/moderation/restrictions/:id", s.liftRestrictionsHandler)
+	api.GET("/webhooks", s.listWebhooksHandler)
+	api.POST("/webhooks", s.createWebhookHandler)
+	api.DELETE("/webhooks/:id", s.deleteWebhookHandler)
+	api.GET("/webhooks/:id/deliveries", s.webhookDeliveriesHandler)
+
+	admin := r.Group("/admin", s.requirePageAuth(), s.requireAdminPage())
+	admin.GET("", s.adminDashboardHandler)
+	admin.GET("/stats", s.adminStatsHandler)
+	admin.GET("/users", s.adminUsersHandler)
+	admin.POST("/users/:id/ban", s.adminBanUserHandler)
+	admin.DELETE("/users/:id/ban", s.adminBanUserHandler)
+	admin.GET("/conversations", s.adminConversationsHandler)
+	admin.GET("/queue", s.adminQueueHandler)
+	admin.POST("/queue/:id/resolve", s.adminResolveFlagHandler)
+
+	chat := r.Group("/chat", s.requirePageAuth(), s.rateLimitUser())
+	chat.GET("", s.chatHandler)
+	chat.GET("/:id", s.chatHandler)
+	chat.POST("/:id/messages", s.chatSendHandler)
+	chat.GET("/:id/events", s.chatEventsHandler)
+
 	staticFiles, _ := fs.Sub(web.Files, "assets")
 	r.StaticFS("/assets", http.FS(staticFiles))
 
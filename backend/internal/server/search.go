@@ -0,0 +1,97 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/message"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// searchHandler serves GET /api/search?q=&conversation_id=&sender_id=&from=&to=&limit=&offset=
+func (s *Server) searchHandler(c *gin.Context) {
+	q := message.SearchQuery{
+		UserID: currentUserID(c),
+		Text:   c.Query("q"),
+	}
+	if q.Text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	var err error
+	if q.ConversationID, err = queryInt64(c, "conversation_id"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid conversation_id"})
+		return
+	}
+	if q.SenderID, err = queryInt64(c, "sender_id"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sender_id"})
+		return
+	}
+	if q.From, err = queryTime(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp"})
+		return
+	}
+	if q.To, err = queryTime(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+		return
+	}
+	if q.Limit, q.Offset, err = pagination(c); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := s.searcher.Search(c.Request.Context(), q)
+	if err != nil {
+		log.Printf("error searching messages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func queryInt64(c *gin.Context, key string) (int64, error) {
+	v := c.Query(key)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+func queryTime(c *gin.Context, key string) (time.Time, error) {
+	v := c.Query(key)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// pagination reads the limit and offset query parameters, applying the
+// default page size and clamping to maxPageSize.
+func pagination(c *gin.Context) (limit, offset int, err error) {
+	limit, offset = defaultPageSize, 0
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			return 0, 0, errors.New("invalid limit")
+		}
+		if limit > maxPageSize {
+			limit = maxPageSize
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, errors.New("invalid offset")
+		}
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/auth"
+	"backend/internal/domain/message"
+)
+
+type fakeSearcher struct {
+	got message.SearchQuery
+}
+
+func (f *fakeSearcher) Search(ctx context.Context, q message.SearchQuery) (*message.SearchResult, error) {
+	f.got = q
+	return &message.SearchResult{Hits: []message.SearchHit{}, Limit: q.Limit, Offset: q.Offset}, nil
+}
+
+func newSearchTestRouter(searcher message.Searcher) (*gin.Engine, *auth.TokenManager) {
+	tokens := auth.NewTokenManager("test-secret", time.Hour)
+	s := &Server{tokens: tokens, searcher: searcher, users: &fakeUsers{}}
+	r := gin.New()
+	r.GET("/api/search", s.requireAuth(), s.searchHandler)
+	return r, tokens
+}
+
+func TestSearchHandlerRequiresAuth(t *testing.T) {
+	r, _ := newSearchTestRouter(&fakeSearcher{})
+
+	req, err := http.NewRequest("GET", "/api/search?q=hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusUnauthorized)
+	}
+}
+
+func TestSearchHandlerFilters(t *testing.T) {
+	searcher := &fakeSearcher{}
+	r, tokens := newSearchTestRouter(searcher)
+	token, err := tokens.Issue(7, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", "/api/search?q=hello&conversation_id=3&sender_id=9&from=2025-01-01T00:00:00Z&limit=500&offset=20", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	want := message.SearchQuery{
+		UserID:         7,
+		Text:           "hello",
+		ConversationID: 3,
+		SenderID:       9,
+		From:           time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Limit:          maxPageSize,
+		Offset:         20,
+	}
+	if searcher.got != want {
+		t.Errorf("Searcher received unexpected query: got %+v want %+v", searcher.got, want)
+	}
+}
+
+func TestSearchHandlerBadRequest(t *testing.T) {
+	r, tokens := newSearchTestRouter(&fakeSearcher{})
+	token, err := tokens.Issue(7, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{"/api/search", "/api/search?q=x&from=yesterday", "/api/search?q=x&limit=-1"} {
+		req, err := http.NewRequest("GET", target, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s returned wrong status code: got %v want %v", target, status, http.StatusBadRequest)
+		}
+	}
+}
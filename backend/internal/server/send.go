@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/domain/webhook"
+	"backend/internal/websocket"
+)
+
+// threadUpdate is the payload of the thread_updated event, sent to the whole
+// conversation so clients can refresh the reply summary of the parent.
+type threadUpdate struct {
+	ParentID    int64      `json:"parent_id"`
+	ReplyCount  int        `json:"reply_count"`
+	LastReplyAt *time.Time `json:"last_reply_at"`
+}
+
+// sendMessage validates, stores and fans out a new message from
+// msg.SenderID, linking the given uploaded attachments to it. On success msg
+// holds the stored message.
+func (s *Server) sendMessage(ctx context.Context, msg *message.Message, attachmentIDs []int64) error {
+	// A message carrying attachments or embeds may have no text.
+	if (len(attachmentIDs) == 0 && len(msg.Embeds) == 0) || msg.Content != "" {
+		if err := message.ValidateContent(msg.Content); err != nil {
+			return err
+		}
+	}
+	attachmentIDs = uniqueIDs(attachmentIDs)
+	if len(attachmentIDs) > message.MaxAttachments {
+		return message.ErrTooManyAttachments
+	}
+	if err := message.ValidateEmbeds(msg.Embeds); err != nil {
+		return err
+	}
+
+	ok, err := s.conversations.IsMember(ctx, msg.ConversationID, msg.SenderID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return conversation.ErrNotMember
+	}
+	conv, err := s.conversations.Get(ctx, msg.ConversationID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkBlocked(ctx, conv, msg); err != nil {
+		return err
+	}
+	if err := s.checkMuted(ctx, msg); err != nil {
+		return err
+	}
+
+	if msg.ParentID != nil {
+		parent, err := s.messages.Get(ctx, *msg.ParentID)
+		if errors.Is(err, message.ErrNotFound) {
+			return message.ErrInvalidParent
+		}
+		if err != nil {
+			return err
+		}
+		if err := message.ValidateParent(parent, msg.ConversationID); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range attachmentIDs {
+		a, err := s.attachments.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if a.UploaderID != msg.SenderID || a.MessageID != nil {
+			return message.ErrAttachmentNotOwned
+		}
+	}
+
+	filtered, err := s.filterContent(ctx, msg.ConversationID, msg.Content)
+	if err != nil {
+		return err
+	}
+	msg.Content = filtered.Content
+
+	if msg.Shadowed, err = s.checkSpam(ctx, conv, msg); err != nil {
+		return err
+	}
+	if err := s.checkPostingRules(ctx, conv, msg); err != nil {
+		return err
+	}
+	if err := s.messages.Create(ctx, msg, attachmentIDs); err != nil {
+		return err
+	}
+	s.flagMessage(ctx, msg, filtered.Flagged())
+
+	if len(attachmentIDs) > 0 {
+		msgs := []message.Message{*msg}
+		if err := s.attachAttachments(ctx, msgs); err != nil {
+			return err
+		}
+		msg.Attachments = msgs[0].Attachments
+	}
+
+	// Messages of shadow-muted senders seem sent to them but reach no one
+	// else.
+	if msg.Shadowed {
+		eventType := websocket.EventMessage
+		if msg.IsReply() {
+			eventType = websocket.EventThreadReply
+		}
+		s.sendEvent([]int64{msg.SenderID}, websocket.Message{Type: eventType, ConversationID: msg.ConversationID, Data: msg})
+		return nil
+	}
+
+	if msg.IsReply() {
+		s.publishReply(ctx, msg)
+	} else {
+		s.publish(ctx, msg.ConversationID, websocket.Message{Type: websocket.EventMessage, Data: msg})
+	}
+	s.publishWebhook(ctx, webhook.EventMessageCreated, &msg.ConversationID, msg)
+	mentioned := s.processMentions(ctx, msg)
+	s.enqueueLinkPreviews(msg)
+	s.enqueuePushNotifications(msg, mentioned)
+	return nil
+}
+
+// publishMessageEvent publishes an event about msg to its conversation, or
+// only to its sender if msg is shadowed.
+func (s *Server) publishMessageEvent(ctx context.Context, msg *message.Message, event websocket.Message) {
+	if msg.Shadowed {
+		event.ConversationID = msg.ConversationID
+		s.sendEvent([]int64{msg.SenderID}, event)
+		return
+	}
+	s.publish(ctx, msg.ConversationID, event)
+}
+
+// publishReply notifies the thread participants of a new reply and the rest
+// of the conversation of the parent's new reply count.
+func (s *Server) publishReply(ctx context.Context, reply *message.Message) {
+	parent, err := s.messages.Get(ctx, *reply.ParentID)
+	if err != nil {
+		log.Printf("error loading thread parent %d: %v", *reply.ParentID, err)
+		return
+	}
+	s.publish(ctx, reply.ConversationID, websocket.Message{
+		Type: websocket.EventThreadUpdated,
+		Data: threadUpdate{ParentID: parent.ID, ReplyCount: parent.ReplyCount, LastReplyAt: parent.LastReplyAt},
+	})
+
+	participants, err := s.messages.ThreadParticipantIDs(ctx, parent.ID)
+	if err != nil {
+		log.Printf("error loading thread participants: %v", err)
+		return
+	}
+	members, err := s.conversations.MemberIDs(ctx, reply.ConversationID)
+	if err != nil {
+		log.Printf("error loading members of conversation %d: %v", reply.ConversationID, err)
+		return
+	}
+	s.sendEvent(intersect(participants, members), websocket.Message{
+		Type:           websocket.EventThreadReply,
+		ConversationID: reply.ConversationID,
+		Data:           reply,
+	})
+}
+
+// intersect returns the ids present in both a and b.
+func intersect(a, b []int64) []int64 {
+	set := make(map[int64]bool, len(b))
+	for _, id := range b {
+		set[id] = true
+	}
+	var out []int64
+	for _, id := range a {
+		if set[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// uniqueIDs returns ids without duplicates, in their original order.
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
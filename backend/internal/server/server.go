@@ -1,29 +1,255 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/joho/godotenv/autoload"
 
+	"backend/internal/auth"
+	slashcmd "backend/internal/command"
 	"backend/internal/database"
+	digestmail "backend/internal/digest"
+	"backend/internal/domain/bot"
+	"backend/internal/domain/command"
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/digest"
+	"backend/internal/domain/message"
+	"backend/internal/domain/moderation"
+	"backend/internal/domain/push"
+	"backend/internal/domain/ratelimit"
+	"backend/internal/domain/user"
+	"backend/internal/domain/webhook"
+	"backend/internal/filter"
+	"backend/internal/infratructure/repositories"
+	"backend/internal/mail"
+	"backend/internal/notification"
+	"backend/internal/origin"
+	"backend/internal/spam"
+	"backend/internal/storage"
+	"backend/internal/unfurl"
+	webhookqueue "backend/internal/webhook"
+	"backend/internal/websocket"
+	"backend/internal/worker"
 )
 
 type Server struct {
-	port int
+	port      int
+	publicURL string
 
 	db database.Service
+
+	hub *websocket.Manager
+
+	// admins are the users allowed to manage server-wide settings.
+	admins map[int64]bool
+
+	// origins are the browser origins allowed by CORS and websocket
+	// upgrades.
+	origins *origin.Policy
+	csrfKey []byte
+
+	tokens        *auth.TokenManager
+	searcher      message.Searcher
+	messages      message.Repository
+	reactions     message.ReactionRepository
+	attachments   message.AttachmentRepository
+	conversations conversation.Repository
+	mentions      message.MentionRepository
+	messagePolicy message.Policy
+
+	storage        storage.Storage
+	uploadMaxBytes int64
+	signedURLTTL   time.Duration
+	imageWorkers   *worker.Pool
+
+	unfurler       *unfurl.Fetcher
+	previewCache   message.LinkPreviewCache
+	previewWorkers *worker.Pool
+
+	pushSubscriptions push.SubscriptionRepository
+	notifications     *notification.Service
+	vapidPublicKey    string
+	pushWorkers       *worker.Pool
+
+	digestPreferences digest.Repository
+	digests           *digestmail.Service
+
+	webhooks          webhook.Repository
+	webhookDeliveries webhook.DeliveryRepository
+	dispatcher        *webhookqueue.Dispatcher
+
+	incomingWebhooks    webhook.IncomingRepository
+	incomingWebhookRate int
+
+	limiter ratelimit.Limiter
+
+	// buckets hold the rate limits shared by all instances.
+	buckets       ratelimit.Buckets
+	userFrameRate ratelimit.Rate
+	httpIPRate    ratelimit.Rate
+	httpUserRate  ratelimit.Rate
+
+	// cooldowns enforce slow mode and spam throttles.
+	cooldowns ratelimit.Cooldowns
+
+	spam                 *spam.Detector
+	restrictions         moderation.RestrictionRepository
+	spamThrottleInterval time.Duration
+	spamThrottleFor      time.Duration
+	spamShadowMuteFor    time.Duration
+
+	bots bot.Repository
+
+	filters        *filter.Chain
+	filterSettings moderation.FilterSettingsRepository
+	flags          moderation.FlagRepository
+	sanctions      moderation.SanctionRepository
+	auditLog       moderation.AuditRepository
+
+	users         user.Repository
+	blocks        user.BlockRepository
+	commands      *slashcmd.Registry
+	slashCommands command.Repository
+	commandClient *http.Client
 }
 
 func NewServer() *http.Server {
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
+
+	// The secret signs session tokens, CSRF tokens and unsubscribe links.
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		log.Fatal("JWT_SECRET must be set")
+	}
+	db := database.New()
+	if err := db.Migrate(context.Background()); err != nil {
+		log.Fatalf("failed to migrate database: %v", err)
+	}
+
+	store, err := storage.New()
+	if err != nil {
+		log.Fatalf("failed to initialize storage: %v", err)
+	}
+
+	origins, err := origin.Parse(envString("ALLOWED_ORIGINS", "http://localhost:5173"))
+	if err != nil {
+		log.Fatalf("invalid ALLOWED_ORIGINS: %v", err)
+	}
+
+	filters, err := newFilterChain()
+	if err != nil {
+		log.Fatalf("invalid content filter configuration: %v", err)
+	}
+
+	hub := websocket.NewManager()
+	hub.SetOriginPolicy(origins.Allowed)
+
 	NewServer := &Server{
-		port: port,
+		port:      port,
+		publicURL: os.Getenv("PUBLIC_URL"),
+
+		db: db,
+
+		hub: hub,
+
+		admins: envIDs("ADMIN_USER_IDS"),
+
+		origins: origins,
+		csrfKey: []byte(secret),
+
+		tokens:        auth.NewTokenManager(secret, 24*time.Hour),
+		searcher:      repositories.NewMySQLMessageSearchRepo(db.GetDB()),
+		messages:      repositories.NewMySQLMessageRepo(db.GetDB()),
+		reactions:     repositories.NewMySQLReactionRepo(db.GetDB()),
+		attachments:   repositories.NewMySQLAttachmentRepo(db.GetDB()),
+		conversations: repositories.NewMySQLConversationRepo(db.GetDB()),
+		mentions:      repositories.NewMySQLMentionRepo(db.GetDB()),
+		messagePolicy: message.Policy{
+			EditWindow:   envDuration("MESSAGE_EDIT_WINDOW", 15*time.Minute),
+			DeleteWindow: envDuration("MESSAGE_DELETE_WINDOW", 0),
+		},
+
+		storage:        store,
+		uploadMaxBytes: envInt64("UPLOAD_MAX_BYTES", 10<<20),
+		signedURLTTL:   envDuration("STORAGE_URL_TTL", time.Hour),
+		imageWorkers:   worker.NewPool(int(envInt64("IMAGE_WORKERS", 4)), 100),
 
-		db: database.New(),
+		unfurler: unfurl.NewFetcher(unfurl.Options{
+			Timeout:  envDuration("LINK_PREVIEW_TIMEOUT", 5*time.Second),
+			MaxBytes: envInt64("LINK_PREVIEW_MAX_BYTES", 512<<10),
+		}),
+		previewCache:   repositories.NewRedisLinkPreviewCache(db.GetRedisClient()),
+		previewWorkers: worker.NewPool(int(envInt64("LINK_PREVIEW_WORKERS", 4)), 1000),
+
+		pushSubscriptions: repositories.NewMySQLPushSubscriptionRepo(db.GetDB()),
+		vapidPublicKey:    os.Getenv("VAPID_PUBLIC_KEY"),
+		pushWorkers:       worker.NewPool(int(envInt64("PUSH_WORKERS", 4)), 1000),
+
+		digestPreferences: repositories.NewMySQLDigestRepo(db.GetDB()),
+
+		webhooks:          repositories.NewMySQLWebhookRepo(db.GetDB()),
+		webhookDeliveries: repositories.NewMySQLWebhookDeliveryRepo(db.GetDB()),
+
+		incomingWebhooks:    repositories.NewMySQLIncomingWebhookRepo(db.GetDB()),
+		incomingWebhookRate: int(envInt64("INCOMING_WEBHOOK_RATE", 30)),
+
+		limiter: repositories.NewRedisRateLimiter(db.GetRedisClient()),
+
+		buckets:       repositories.NewRedisTokenBuckets(db.GetRedisClient()),
+		userFrameRate: envRate("WS_USER", ratelimit.Rate{PerSecond: 10, Burst: 30}),
+		httpIPRate:    envRate("HTTP_IP", ratelimit.Rate{PerSecond: 20, Burst: 60}),
+		httpUserRate:  envRate("HTTP_USER", ratelimit.Rate{PerSecond: 10, Burst: 40}),
+
+		cooldowns: repositories.NewRedisCooldowns(db.GetRedisClient()),
+
+		spam:                 spam.NewDetector(repositories.NewRedisWindows(db.GetRedisClient()), spamConfig()),
+		restrictions:         repositories.NewRedisRestrictions(db.GetRedisClient()),
+		spamThrottleInterval: envDuration("SPAM_THROTTLE_INTERVAL", 30*time.Second),
+		spamThrottleFor:      envDuration("SPAM_THROTTLE_FOR", 10*time.Minute),
+		spamShadowMuteFor:    envDuration("SPAM_SHADOW_MUTE_FOR", 24*time.Hour),
+
+		bots: repositories.NewMySQLBotRepo(db.GetDB()),
+
+		filters:        filters,
+		filterSettings: repositories.NewMySQLModerationRepo(db.GetDB()),
+		flags:          repositories.NewMySQLModerationRepo(db.GetDB()),
+		sanctions:      repositories.NewMySQLModerationRepo(db.GetDB()),
+		auditLog:       repositories.NewMySQLModerationRepo(db.GetDB()),
+
+		users:         repositories.NewMySQLUserRepo(db.GetDB()),
+		blocks:        repositories.NewMySQLBlockRepo(db.GetDB()),
+		slashCommands: repositories.NewMySQLCommandRepo(db.GetDB()),
+		commandClient: webhookqueue.NewHTTPClient(envDuration("COMMAND_TIMEOUT", 3*time.Second), false),
+	}
+	NewServer.commands = NewServer.builtinCommands()
+	NewServer.dispatcher = webhookqueue.NewDispatcher(NewServer.webhooks, NewServer.webhookDeliveries, webhookqueue.Options{
+		MaxAttempts: int(envInt64("WEBHOOK_MAX_ATTEMPTS", 8)),
+		Backoff:     envDuration("WEBHOOK_BACKOFF", 10*time.Second),
+		MaxBackoff:  envDuration("WEBHOOK_MAX_BACKOFF", time.Hour),
+		Timeout:     envDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+	})
+
+	hub.OnStatusChange(NewServer.userStatusChanged)
+	hub.OnFrame(NewServer.handleFrame)
+	hub.SetBlockList(NewServer.blockedUserIDs)
+	hub.SetFrameRate(envRate("WS_CONNECTION", ratelimit.Rate{PerSecond: 5, Burst: 20}))
+	go hub.Run()
+	// Push notifications are disabled until a VAPID key pair is configured.
+	if NewServer.vapidPublicKey != "" && os.Getenv("VAPID_PRIVATE_KEY") != "" {
+		NewServer.notifications = notification.NewService(NewServer.pushSubscriptions, &notification.WebPush{
+			PublicKey:  NewServer.vapidPublicKey,
+			PrivateKey: os.Getenv("VAPID_PRIVATE_KEY"),
+			Subject:    os.Getenv("VAPID_SUBJECT"),
+			TTL:        envDuration("PUSH_TTL", 24*time.Hour),
+			HTTPClient: webhookqueue.NewHTTPClient(envDuration("PUSH_TIMEOUT", 10*time.Second), false),
+		})
 	}
 
 	// Declare Server config
@@ -34,6 +260,100 @@ func NewServer() *http.Server {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
+	server.RegisterOnShutdown(NewServer.imageWorkers.Stop)
+	server.RegisterOnShutdown(NewServer.previewWorkers.Stop)
+	server.RegisterOnShutdown(NewServer.pushWorkers.Stop)
+
+	webhookCtx, stopWebhooks := context.WithCancel(context.Background())
+	go NewServer.dispatcher.Run(webhookCtx)
+	server.RegisterOnShutdown(stopWebhooks)
+
+	// Email digests are disabled until an SMTP relay is configured.
+	if os.Getenv("SMTP_HOST") != "" {
+		NewServer.digests = digestmail.NewService(NewServer.digestPreferences, mail.New(), digestmail.Options{
+			Delay:      envDuration("DIGEST_DELAY", 30*time.Minute),
+			MaxItems:   int(envInt64("DIGEST_MAX_MESSAGES", 50)),
+			PublicURL:  os.Getenv("PUBLIC_URL"),
+			AppURL:     os.Getenv("APP_URL"),
+			SigningKey: []byte(secret),
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		go NewServer.digests.Run(ctx, envDuration("DIGEST_INTERVAL", 5*time.Minute))
+		server.RegisterOnShutdown(cancel)
+	}
 
 	return server
 }
+
+// envString reads a string from the environment, falling back to def when
+// the variable is unset.
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envDuration reads a duration such as "15m" from the environment, falling
+// back to def when the variable is unset or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("invalid %s %q, using default %s", key, v, def)
+		return def
+	}
+	return d
+}
+
+// envRate reads a token bucket rate from <prefix>_RATE, in requests per
+// second, and <prefix>_BURST. A rate of 0 disables the limit.
+func envRate(prefix string, def ratelimit.Rate) ratelimit.Rate {
+	rate := def
+	if v := os.Getenv(prefix + "_RATE"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			log.Printf("invalid %s_RATE %q, using default %g", prefix, v, def.PerSecond)
+		} else {
+			rate.PerSecond = f
+		}
+	}
+	rate.Burst = int(envInt64(prefix+"_BURST", int64(def.Burst)))
+	return rate
+}
+
+// envIDs reads a comma separated list of ids from the environment, skipping
+// invalid entries.
+func envIDs(key string) map[int64]bool {
+	ids := map[int64]bool{}
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Printf("invalid id %q in %s", v, key)
+			continue
+		}
+		ids[id] = true
+	}
+	return ids
+}
+
+// envInt64 reads an integer from the environment, falling back to def when
+// the variable is unset or invalid.
+func envInt64(key string, def int64) int64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		log.Printf("invalid %s %q, using default %d", key, v, def)
+		return def
+	}
+	return n
+}
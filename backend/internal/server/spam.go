@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/domain/moderation"
+	"backend/internal/spam"
+	"backend/internal/unfurl"
+)
+
+type resolveFlagRequest struct {
+	// Lift also lifts the automatic restrictions of the flagged user.
+	Lift bool `json:"lift"`
+}
+
+// spamConfig reads the spam rule thresholds from the environment.
+func spamConfig() spam.Config {
+	def := spam.DefaultConfig()
+	return spam.Config{
+		RepeatLimit:   int(envInt64("SPAM_REPEAT_LIMIT", int64(def.RepeatLimit))),
+		RepeatWindow:  envDuration("SPAM_REPEAT_WINDOW", def.RepeatWindow),
+		LinkLimit:     int(envInt64("SPAM_LINK_LIMIT", int64(def.LinkLimit))),
+		LinkWindow:    envDuration("SPAM_LINK_WINDOW", def.LinkWindow),
+		DMLimit:       int(envInt64("SPAM_DM_LIMIT", int64(def.DMLimit))),
+		DMWindow:      envDuration("SPAM_DM_WINDOW", def.DMWindow),
+		NewAccountAge: envDuration("SPAM_NEW_ACCOUNT_AGE", def.NewAccountAge),
+	}
+}
+
+// checkSpam applies the sender's automatic restrictions and the spam rules
+// to a new message, restricting and flagging the sender when it breaks a
+// rule. It reports whether the message must be shadowed. Bots are exempt,
+// and messages are let through if Redis is unavailable.
+func (s *Server) checkSpam(ctx context.Context, conv *conversation.Conversation, msg *message.Message) (bool, error) {
+	sender, err := s.users.Get(ctx, msg.SenderID)
+	if err != nil {
+		return false, err
+	}
+	if sender.IsBot {
+		return false, nil
+	}
+
+	shadowed, err := s.restrictions.Restricted(ctx, msg.SenderID, moderation.RestrictShadowMute)
+	if err != nil {
+		log.Printf("error checking restrictions of user %d: %v", msg.SenderID, err)
+		return false, nil
+	}
+	if shadowed {
+		return true, nil
+	}
+	throttled, err := s.restrictions.Restricted(ctx, msg.SenderID, moderation.RestrictThrottle)
+	if err != nil {
+		log.Printf("error checking restrictions of user %d: %v", msg.SenderID, err)
+		return false, nil
+	}
+	if throttled {
+		return false, s.throttle(ctx, msg.SenderID)
+	}
+
+	verdict, err := s.spam.Check(ctx, spam.Activity{
+		UserID:         msg.SenderID,
+		AccountAge:     time.Since(sender.CreatedAt),
+		ConversationID: conv.ID,
+		Direct:         !conv.IsGroup,
+		Content:        msg.Content,
+		HasLinks:       len(unfurl.ExtractURLs(msg.Content)) > 0,
+	})
+	if err != nil {
+		log.Printf("error checking spam rules: %v", err)
+		return false, nil
+	}
+	if verdict == nil {
+		return false, nil
+	}
+
+	d := s.spamThrottleFor
+	if verdict.Restriction == moderation.RestrictShadowMute {
+		d = s.spamShadowMuteFor
+	}
+	if err := s.restrictions.Restrict(ctx, msg.SenderID, verdict.Restriction, d); err != nil {
+		log.Printf("error restricting user %d: %v", msg.SenderID, err)
+	}
+	f := &moderation.Flag{
+		ConversationID: &conv.ID,
+		UserID:         msg.SenderID,
+		Source:         moderation.SourceSpam,
+		Reason:         string(verdict.Rule) + ": " + verdict.Reason,
+	}
+	if err := s.flags.Create(ctx, f); err != nil {
+		log.Printf("error flagging user %d: %v", msg.SenderID, err)
+	}
+
+	if verdict.Restriction == moderation.RestrictShadowMute {
+		return true, nil
+	}
+	return false, s.throttle(ctx, msg.SenderID)
+}
+
+// throttle lets a throttled user post once per throttle interval.
+func (s *Server) throttle(ctx context.Context, userID int64) error {
+	res, err := s.cooldowns.Start(ctx, "spam_throttle:"+strconv.FormatInt(userID, 10), s.spamThrottleInterval)
+	if err != nil {
+		log.Printf("error checking throttle of user %d: %v", userID, err)
+		return nil
+	}
+	if !res.Allowed {
+		return &moderation.ThrottledError{RetryAfter: res.RetryAfter}
+	}
+	return nil
+}
+
+// moderationQueueHandler serves GET /api/moderation/queue
+func (s *Server) moderationQueueHandler(c *gin.Context) {
+	if !s.requireServerAdmin(c) {
+		return
+	}
+	limit, offset, err := pagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status := c.DefaultQuery("status", moderation.FlagPending)
+	if status != moderation.FlagPending && status != moderation.FlagResolved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+
+	flags, err := s.flags.ListFlags(c.Request.Context(), moderation.FlagQuery{
+		Status: status,
+		Source: c.Query("source"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondModerationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"flags": flags, "limit": limit, "offset": offset})
+}
+
+// resolveFlagHandler serves POST /api/moderation/queue/:id/resolve
+func (s *Server) resolveFlagHandler(c *gin.Context) {
+	if !s.requireServerAdmin(c) {
+		return
+	}
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	var req resolveFlagRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	f, err := s.flags.GetFlag(ctx, id)
+	if err != nil {
+		respondModerationError(c, err)
+		return
+	}
+	actorID := currentUserID(c)
+	if err := s.flags.ResolveFlag(ctx, id, actorID); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+	if req.Lift {
+		if err := s.restrictions.Lift(ctx, f.UserID); err != nil {
+			respondModerationError(c, err)
+			return
+		}
+	}
+	s.audit(ctx, &moderation.AuditEntry{
+		ConversationID: f.ConversationID,
+		ActorID:        actorID,
+		Action:         moderation.AuditResolveFlag,
+		TargetUserID:   &f.UserID,
+		MessageID:      f.MessageID,
+		FlagID:         &f.ID,
+	})
+	c.Status(http.StatusNoContent)
+}
+
+// liftRestrictionsHandler serves DELETE /api/moderation/restrictions/:id
+func (s *Server) liftRestrictionsHandler(c *gin.Context) {
+	if !s.requireServerAdmin(c) {
+		return
+	}
+	userID, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := s.restrictions.Lift(ctx, userID); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+	s.audit(ctx, &moderation.AuditEntry{
+		ActorID:      currentUserID(c),
+		Action:       moderation.AuditLiftRestrictions,
+		TargetUserID: &userID,
+	})
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/websocket"
+)
+
+// sseKeepAlive is the interval of the comments that keep idle event
+// streams open through proxies.
+const sseKeepAlive = 25 * time.Second
+
+// streamFrame decodes the hub frames relayed by event streams.
+type streamFrame struct {
+	Type           string          `json:"type"`
+	Users          []string        `json:"users,omitempty"`
+	ConversationID int64           `json:"conversation_id,omitempty"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
+
+// eventsHandler serves GET /api/events, a Server-Sent Events transport
+// for clients that cannot use websockets. Every hub frame the caller would
+// receive on a websocket is sent as an unnamed event whose data is the
+// frame's JSON. Events addressed to the caller carry an ID: reconnecting
+// with it in the Last-Event-ID header, or the last_event_id query parameter,
+// replays the events missed in between, or sends a resync frame if they are
+// no longer known. Messages are sent with POST
+// /api/conversations/:id/messages.
+func (s *Server) eventsHandler(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var events []string
+	if b := currentBot(c); b != nil {
+		events = b.Events
+	}
+
+	stream, resumed := s.hub.Subscribe(currentUserID(c), currentUsername(c), events, lastEventID)
+	defer s.hub.Unsubscribe(stream)
+
+	startSSE(c)
+	if lastEventID != "" && !resumed {
+		data, _ := json.Marshal(websocket.Message{Type: websocket.EventResync})
+		if !writeSSE(c, "", "", string(data)) {
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stream.Done():
+			return
+		case <-keepAlive.C:
+			if !writeSSEComment(c) {
+				return
+			}
+		case ev := <-stream.Feed():
+			if !writeSSE(c, ev.ID, "", string(ev.Data)) {
+				return
+			}
+		}
+	}
+}
+
+// startSSE sends the headers of an event stream and lifts the server's
+// write timeout, which would otherwise cut the stream.
+func startSSE(c *gin.Context) {
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("error lifting write deadline of event stream: %v", err)
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// Keep nginx from buffering the stream.
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+}
+
+// writeSSE writes an event with an optional ID and name, reporting whether
+// the stream is still open. Each line of data becomes a data field.
+func writeSSE(c *gin.Context, id, event, data string) bool {
+	var b strings.Builder
+	if id != "" {
+		b.WriteString("id: " + id + "\n")
+	}
+	if event != "" {
+		b.WriteString("event: " + event + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	b.WriteString("\n")
+	if _, err := io.WriteString(c.Writer, b.String()); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
+
+// writeSSEComment writes a comment line, reporting whether the stream is
+// still open.
+func writeSSEComment(c *gin.Context) bool {
+	if _, err := io.WriteString(c.Writer, ": keep-alive\n\n"); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/auth"
+	"backend/internal/websocket"
+)
+
+// readSSE reads events from an event stream until one whose data is a
+// frame of the given type, returning its ID.
+func readSSE(t *testing.T, r *bufio.Reader, frameType string) string {
+	t.Helper()
+	var id string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", frameType, err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			var msg websocket.Message
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg.Type == frameType {
+				return id
+			}
+		case line == "":
+			id = ""
+		}
+	}
+}
+
+func TestEventsHandler(t *testing.T) {
+	tokens := auth.NewTokenManager("test-secret", time.Hour)
+	hub := websocket.NewManager()
+	go hub.Run()
+	s := &Server{tokens: tokens, users: &fakeUsers{}, hub: hub}
+	r := gin.New()
+	r.GET("/api/events", s.requireStreamAuth(), s.eventsHandler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	token, err := tokens.Issue(7, "an")
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func(lastEventID string) (*bufio.Reader, func()) {
+		req, _ := http.NewRequest("GET", srv.URL+"/api/events", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("content type: got %q want text/event-stream", ct)
+		}
+		return bufio.NewReader(resp.Body), func() { resp.Body.Close() }
+	}
+
+	stream, closeStream := open("")
+	readSSE(t, stream, websocket.EventOnlineUsers)
+	s.sendEvent([]int64{7}, websocket.Message{Type: websocket.EventMessage, ConversationID: 1})
+	id := readSSE(t, stream, websocket.EventMessage)
+	if id == "" {
+		t.Fatal("message event has no ID")
+	}
+
+	// Resuming from a known event replays nothing missed and needs no
+	// resync; an unknown one does.
+	resumed, closeResumed := open(id)
+	defer closeResumed()
+	s.sendEvent([]int64{7}, websocket.Message{Type: websocket.EventMessage, ConversationID: 2})
+	if next := readSSE(t, resumed, websocket.EventMessage); next == "" || next == id {
+		t.Errorf("next event ID: got %q, want a new one after %q", next, id)
+	}
+	closeStream()
+
+	stale, closeStale := open("0-1")
+	defer closeStale()
+	readSSE(t, stale, websocket.EventResync)
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+
+	"backend/internal/domain/message"
+
+	"github.com/gin-gonic/gin"
+)
+
+// threadHandler serves GET /api/messages/:id/thread. Asking for the thread
+// of a reply returns the thread it belongs to.
+func (s *Server) threadHandler(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+	limit, offset, err := pagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	parent, err := s.messages.Get(ctx, id)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	if parent.IsReply() {
+		if parent, err = s.messages.Get(ctx, *parent.ParentID); err != nil {
+			respondMessageError(c, err)
+			return
+		}
+	}
+	if !s.requireMember(c, parent.ConversationID) {
+		return
+	}
+	if parent.Shadowed && parent.SenderID != currentUserID(c) {
+		respondMessageError(c, message.ErrNotFound)
+		return
+	}
+	parent.Tombstone()
+
+	replies, err := s.messages.Thread(ctx, parent.ID, currentUserID(c), limit, offset)
+	if err != nil {
+		respondMessageError(c, err)
+		return
+	}
+	if err := s.decorateMessages(c, replies); err != nil {
+		respondMessageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"parent": parent, "replies": replies, "limit": limit, "offset": offset})
+}
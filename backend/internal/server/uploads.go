@@ -0,0 +1,272 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/message"
+	"backend/internal/storage"
+)
+
+// multipartOverhead is the allowance for multipart headers on top of the
+// maximum file size when limiting the request body.
+const multipartOverhead = 1 << 20
+
+// uploadHandler serves POST /api/uploads. The file is sent as the "file"
+// field of a multipart form and stored as an unlinked attachment. Images go
+// through the image pipeline as on POST /api/uploads/images, so that their
+// metadata is stripped before anyone can download them.
+func (s *Server) uploadHandler(c *gin.Context) {
+	a, ok := s.storeUpload(c, message.AllowedContentTypes)
+	if !ok {
+		return
+	}
+	if a.Status == message.AttachmentProcessing {
+		s.submitImage(c, a)
+		return
+	}
+	s.signAttachment(c.Request.Context(), a)
+
+	c.JSON(http.StatusCreated, a)
+}
+
+// storeUpload saves the uploaded "file" form field if its detected content
+// type is allowed and records it as an unlinked attachment. Images the
+// image pipeline processes are recorded as processing, other files as
+// ready. It responds with an error and returns false otherwise.
+func (s *Server) storeUpload(c *gin.Context, allowed map[string]bool) (*message.Attachment, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, s.uploadMaxBytes+multipartOverhead)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respondAttachmentError(c, message.ErrFileTooLarge)
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return nil, false
+	}
+	if header.Size > s.uploadMaxBytes {
+		respondAttachmentError(c, message.ErrFileTooLarge)
+		return nil, false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		respondAttachmentError(c, err)
+		return nil, false
+	}
+	defer file.Close()
+
+	// Detect the type from the content instead of trusting the client.
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		respondAttachmentError(c, err)
+		return nil, false
+	}
+	head = head[:n]
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !allowed[contentType] {
+		respondAttachmentError(c, message.ErrUnsupportedFileType)
+		return nil, false
+	}
+
+	key, err := newObjectKey("attachments")
+	if err != nil {
+		respondAttachmentError(c, err)
+		return nil, false
+	}
+
+	ctx := c.Request.Context()
+	body := io.MultiReader(bytes.NewReader(head), file)
+	if err := s.storage.Put(ctx, key, body, header.Size, contentType); err != nil {
+		respondAttachmentError(c, err)
+		return nil, false
+	}
+
+	status := message.AttachmentReady
+	if imageContentTypes[contentType] {
+		status = message.AttachmentProcessing
+	}
+	a := &message.Attachment{
+		UploaderID:  currentUserID(c),
+		Key:         key,
+		Filename:    sanitizeFilename(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		Status:      status,
+	}
+	if err := s.attachments.Create(ctx, a); err != nil {
+		respondAttachmentError(c, err)
+		return nil, false
+	}
+	return a, true
+}
+
+// attachmentHandler serves GET /api/attachments/:id, returning the metadata
+// with a freshly signed download URL.
+func (s *Server) attachmentHandler(c *gin.Context) {
+	id, ok := pathID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	a, err := s.attachments.Get(ctx, id)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+
+	// Unlinked attachments are only visible to their uploader; linked ones
+	// to the members of the message's conversation.
+	if a.MessageID == nil {
+		if a.UploaderID != currentUserID(c) {
+			respondAttachmentError(c, message.ErrAttachmentNotFound)
+			return
+		}
+	} else {
+		msg, err := s.messages.Get(ctx, *a.MessageID)
+		if err != nil {
+			respondMessageError(c, err)
+			return
+		}
+		if !s.requireMember(c, msg.ConversationID) {
+			return
+		}
+		if msg.IsDeleted() {
+			respondMessageError(c, message.ErrDeleted)
+			return
+		}
+	}
+
+	s.signAttachment(ctx, a)
+	c.JSON(http.StatusOK, a)
+}
+
+// fileHandler serves GET /api/files/*key for the local storage backend.
+// Access is granted by the signature in the URL rather than by a token.
+func (s *Server) fileHandler(c *gin.Context) {
+	local, ok := s.storage.(*storage.Local)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := local.Verify(key, c.Query("expires"), c.Query("signature")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	r, err := local.Get(c.Request.Context(), key)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	defer r.Close()
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, max-age=3600")
+	if rs, ok := r.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", time.Time{}, rs)
+		return
+	}
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, r)
+}
+
+// signAttachment sets the signed download URLs of a and its thumbnails.
+// Attachments that are still processing get no URL.
+func (s *Server) signAttachment(ctx context.Context, a *message.Attachment) {
+	if a.Status != message.AttachmentReady {
+		return
+	}
+
+	url, err := s.storage.SignedURL(ctx, a.Key, s.signedURLTTL)
+	if err != nil {
+		log.Printf("error signing attachment %d: %v", a.ID, err)
+		return
+	}
+	a.URL = url
+
+	for i := range a.Thumbnails {
+		if a.Thumbnails[i].URL, err = s.storage.SignedURL(ctx, a.Thumbnails[i].Key, s.signedURLTTL); err != nil {
+			log.Printf("error signing thumbnail of attachment %d: %v", a.ID, err)
+		}
+	}
+}
+
+// attachAttachments fills in the signed attachments of a page of messages.
+func (s *Server) attachAttachments(ctx context.Context, messages []message.Message) error {
+	ids := make([]int64, 0, len(messages))
+	for _, m := range messages {
+		if !m.IsDeleted() {
+			ids = append(ids, m.ID)
+		}
+	}
+
+	byMessage, err := s.attachments.ListByMessages(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range messages {
+		attachments := byMessage[messages[i].ID]
+		for j := range attachments {
+			s.signAttachment(ctx, &attachments[j])
+		}
+		messages[i].Attachments = attachments
+	}
+	return nil
+}
+
+// newObjectKey returns a random, unguessable storage key below prefix.
+func newObjectKey(prefix string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + "/" + time.Now().UTC().Format("2006/01") + "/" + hex.EncodeToString(b), nil
+}
+
+// sanitizeFilename keeps only the base name of a client supplied file name.
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		name = "file"
+	}
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[len(name)-255:], "")
+	}
+	return name
+}
+
+func respondAttachmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, message.ErrAttachmentNotFound), errors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": message.ErrAttachmentNotFound.Error()})
+	case errors.Is(err, message.ErrFileTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, message.ErrUnsupportedFileType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, message.ErrAttachmentNotOwned):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("error handling attachment request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/message"
+	"backend/internal/storage"
+)
+
+// newUploadsTestServer stores uploads in a temporary directory served at
+// /api/files.
+func newUploadsTestServer(t *testing.T) *testServer {
+	ts := newTestServer(t)
+	local, err := storage.NewLocal(t.TempDir(), "/api/files", []byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.storage = local
+	return ts
+}
+
+// upload posts content as the "file" field of a multipart form.
+func (ts *testServer) upload(userID int64, filename string, content []byte) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	part.Write(content)
+	w.Close()
+
+	req := httptest.NewRequest("POST", "/api/uploads", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	token, err := ts.tokens.Issue(userID, "an")
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestUploadDetectsContentType(t *testing.T) {
+	ts := newUploadsTestServer(t)
+
+	// The type comes from the content, not the file name.
+	rr := ts.upload(1, "notes.png", []byte("just some notes"))
+	expect(t, "text", rr, http.StatusCreated)
+	var a message.Attachment
+	decodeJSON(t, rr, &a)
+	if a.ContentType != "text/plain" || a.Filename != "notes.png" || a.URL == "" {
+		t.Errorf("attachment: got %+v", a)
+	}
+
+	expect(t, "html", ts.upload(1, "notes.txt", []byte("<html><script>alert(1)</script></html>")), http.StatusUnsupportedMediaType)
+	expect(t, "executable", ts.upload(1, "notes.txt", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff")), http.StatusUnsupportedMediaType)
+	if len(ts.attachments.attachments) != 1 {
+		t.Errorf("rejected uploads were stored: %d attachments", len(ts.attachments.attachments))
+	}
+}
+
+func TestUploadSizeLimit(t *testing.T) {
+	ts := newUploadsTestServer(t)
+	ts.uploadMaxBytes = 16
+
+	expect(t, "at the limit", ts.upload(1, "a.txt", bytes.Repeat([]byte("a"), 16)), http.StatusCreated)
+	expect(t, "over the limit", ts.upload(1, "a.txt", bytes.Repeat([]byte("a"), 17)), http.StatusRequestEntityTooLarge)
+	expect(t, "over the body limit", ts.upload(1, "a.txt", bytes.Repeat([]byte("a"), 2*multipartOverhead)), http.StatusRequestEntityTooLarge)
+}
+
+func TestFileHandlerVerifiesSignature(t *testing.T) {
+	ts := newUploadsTestServer(t)
+	rr := ts.upload(1, "a.txt", []byte("hello"))
+	expect(t, "upload", rr, http.StatusCreated)
+	var a message.Attachment
+	decodeJSON(t, rr, &a)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		ts.handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		return rr
+	}
+
+	rr = get(a.URL)
+	expect(t, "signed URL", rr, http.StatusOK)
+	if rr.Body.String() != "hello" || rr.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("download: got %q with headers %v", rr.Body.String(), rr.Header())
+	}
+
+	u, err := url.Parse(a.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	tampered := *u
+	tq := url.Values{"expires": {q.Get("expires")}, "signature": {strings.Repeat("0", len(q.Get("signature")))}}
+	tampered.RawQuery = tq.Encode()
+	expect(t, "tampered signature", get(tampered.String()), http.StatusForbidden)
+
+	tq = url.Values{"expires": {strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10)}, "signature": {q.Get("signature")}}
+	tampered.RawQuery = tq.Encode()
+	expect(t, "extended expiry", get(tampered.String()), http.StatusForbidden)
+
+	other := *u
+	other.Path = u.Path + "0"
+	expect(t, "other key", get(other.String()), http.StatusForbidden)
+
+	expired, err := ts.storage.SignedURL(t.Context(), ts.attachments.attachments[a.ID].Key, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, "expired", get(expired), http.StatusForbidden)
+}
+
+func TestSendMessageAttachments(t *testing.T) {
+	ts := newUploadsTestServer(t)
+	ts.conversations.add(&conversation.Conversation{ID: 5, IsGroup: true}, []int64{1, 2})
+	ts.addUser(1, "an")
+	upload := func(userID int64) int64 {
+		rr := ts.upload(userID, "a.txt", []byte("hello"))
+		expect(t, "upload", rr, http.StatusCreated)
+		var a message.Attachment
+		decodeJSON(t, rr, &a)
+		return a.ID
+	}
+	mine, theirs := upload(1), upload(2)
+	send := func(ids ...int64) *httptest.ResponseRecorder {
+		return ts.do("POST", "/api/conversations/5/messages", 1, createMessageRequest{AttachmentIDs: ids})
+	}
+
+	expect(t, "another user's upload", send(theirs), http.StatusBadRequest)
+	expect(t, "missing upload", send(99), http.StatusBadRequest)
+
+	// Repeated ids are linked once rather than failing the ownership check.
+	rr := send(mine, mine)
+	expect(t, "repeated id", rr, http.StatusCreated)
+	var msg message.Message
+	decodeJSON(t, rr, &msg)
+	if got := ts.messages.linked[msg.ID]; len(got) != 1 || got[0] != mine {
+		t.Errorf("linked attachments: got %v want [%d]", got, mine)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].URL == "" {
+		t.Errorf("message attachments: got %+v", msg.Attachments)
+	}
+
+	expect(t, "already linked", send(mine), http.StatusBadRequest)
+}
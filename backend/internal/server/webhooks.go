@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend/internal/domain/conversation"
+	"backend/internal/domain/webhook"
+	webhookqueue "backend/internal/webhook"
+)
+
+type createWebhookRequest struct {
+	URL            string   `json:"url"`
+	ConversationID *int64   `json:"conversation_id"`
+	Events         []string `json:"events"`
+}
+
+// userStatusEvent is the data of user.status webhook events.
+type userStatusEvent struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Status   string `json:"status"`
+}
+
+// createWebhookHandler serves POST /api/webhooks
+func (s *Server) createWebhookHandler(c *gin.Context) {
+	var req createWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": webhook.ErrInvalidURL.Error()})
+		return
+	}
+	if len(req.Events) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": webhook.ErrNoEvents.Error()})
+		return
+	}
+	for _, e := range req.Events {
+		if !webhook.ValidEvent(e) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": webhook.ErrInvalidEvent.Error() + ": " + e})
+			return
+		}
+	}
+
+	// Global webhooks see every conversation, so only server admins may
+	// create them; conversation webhooks need a conversation admin.
+	userID := currentUserID(c)
+	if req.ConversationID == nil {
+		if !s.isAdmin(userID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only admins can create global webhooks"})
+			return
+		}
+	} else if !s.requireConversationAdmin(c, *req.ConversationID) {
+		return
+	}
+
+	secret, err := webhookqueue.NewSecret()
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	w := &webhook.Webhook{
+		OwnerID:        userID,
+		ConversationID: req.ConversationID,
+		URL:            req.URL,
+		Secret:         secret,
+		Events:         req.Events,
+	}
+	if err := s.webhooks.Create(c.Request.Context(), w); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	// The secret is only returned once, on creation.
+	c.JSON(http.StatusCreated, w)
+}
+
+// listWebhooksHandler serves GET /api/webhooks
+func (s *Server) listWebhooksHandler(c *gin.Context) {
+	hooks, err := s.webhooks.ListByOwner(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks})
+}
+
+// deleteWebhookHandler serves DELETE /api/webhooks/:id
+func (s *Server) deleteWebhookHandler(c *gin.Context) {
+	w, ok := s.ownedWebhook(c)
+	if !ok {
+		return
+	}
+	if err := s.webhooks.Delete(c.Request.Context(), w.ID); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// webhookDeliveriesHandler serves GET /api/webhooks/:id/deliveries
+func (s *Server) webhookDeliveriesHandler(c *gin.Context) {
+	w, ok := s.ownedWebhook(c)
+	if !ok {
+		return
+	}
+	limit, offset, err := pagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deliveries, err := s.webhookDeliveries.ListByWebhook(c.Request.Context(), w.ID, limit, offset)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries, "limit": limit, "offset": offset})
+}
+
+// ownedWebhook loads the :id webhook, responding with 404 unless the caller
+// owns it.
+func (s *Server) ownedWebhook(c *gin.Context) (*webhook.Webhook, bool) {
+	id, ok := pathID(c)
+	if !ok {
+		return nil, false
+	}
+	w, err := s.webhooks.Get(c.Request.Context(), id)
+	if err == nil && w.OwnerID != currentUserID(c) {
+		err = webhook.ErrNotFound
+	}
+	if err != nil {
+		respondWebhookError(c, err)
+		return nil, false
+	}
+	return w, true
+}
+
+// publishWebhook queues event for the subscribed webhooks.
+func (s *Server) publishWebhook(ctx context.Context, event string, conversationID *int64, data interface{}) {
+	if err := s.dispatcher.Publish(ctx, event, conversationID, data); err != nil {
+		log.Printf("error queueing %s webhooks: %v", event, err)
+	}
+}
+
+// userStatusChanged is called by the hub when a user comes online or goes
+// offline. A live connection receives every message as it is sent, so once
+// the user's last one closes their conversations count as read up to then.
+// Messages missed while offline stay unread on reconnecting.
+func (s *Server) userStatusChanged(userID int64, username string, online bool) {
+	if !online {
+		if err := s.conversations.MarkAllRead(context.Background(), userID, time.Now()); err != nil {
+			log.Printf("error marking conversations read for user %d: %v", userID, err)
+		}
+	}
+
+	status := "offline"
+	if online {
+		status = "online"
+	}
+	s.publishWebhook(context.Background(), webhook.EventUserStatus, nil, userStatusEvent{
+		UserID:   userID,
+		Username: username,
+		Status:   status,
+	})
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, conversation.ErrNotAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		log.Printf("error handling webhook request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
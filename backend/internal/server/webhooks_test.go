@@ -0,0 +1,17 @@
+package server
+
+import "testing"
+
+func TestUserStatusChangedMarksReadOnDisconnect(t *testing.T) {
+	conversations := newFakeConversations()
+	s := &Server{conversations: conversations, dispatcher: newTestDispatcher()}
+
+	s.userStatusChanged(7, "an", true)
+	if _, ok := conversations.readAll[7]; ok {
+		t.Fatal("coming online marked conversations read")
+	}
+	s.userStatusChanged(7, "an", false)
+	if _, ok := conversations.readAll[7]; !ok {
+		t.Error("going offline did not mark conversations read")
+	}
+}
@@ -0,0 +1,15 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// hubHandler upgrades an authenticated request to a websocket connection
+// managed by the hub. Bots only receive the events they subscribed to.
+func (s *Server) hubHandler(c *gin.Context) {
+	if b := currentBot(c); b != nil {
+		s.hub.HandleBotWebSocket(c.Writer, c.Request, b.UserID, b.Username, b.Events)
+		return
+	}
+	s.hub.HandleUserWebSocket(c.Writer, c.Request, currentUserID(c), currentUsername(c))
+}
@@ -0,0 +1,156 @@
+// Package spam detects abusive posting patterns from sliding windows of
+// each account's recent messages.
+package spam
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/domain/moderation"
+	"backend/internal/domain/ratelimit"
+)
+
+// Rule names a posting pattern the detector looks for.
+type Rule string
+
+const (
+	// RuleRepeated trips on the same message posted over and over.
+	RuleRepeated Rule = "repeated_message"
+	// RuleLinkBurst trips on many messages with links in a short time.
+	RuleLinkBurst Rule = "link_burst"
+	// RuleMassDM trips on a new account messaging many people directly.
+	RuleMassDM Rule = "mass_dm"
+)
+
+// Config holds the thresholds of the rules: a rule trips when an account
+// goes over its limit within its window. A zero limit disables a rule.
+type Config struct {
+	RepeatLimit  int
+	RepeatWindow time.Duration
+
+	LinkLimit  int
+	LinkWindow time.Duration
+
+	// DMLimit is the number of distinct direct conversations an account
+	// younger than NewAccountAge may post to within DMWindow.
+	DMLimit       int
+	DMWindow      time.Duration
+	NewAccountAge time.Duration
+}
+
+// DefaultConfig returns the thresholds used unless configured otherwise.
+func DefaultConfig() Config {
+	return Config{
+		RepeatLimit:   3,
+		RepeatWindow:  time.Minute,
+		LinkLimit:     5,
+		LinkWindow:    time.Minute,
+		DMLimit:       5,
+		DMWindow:      10 * time.Minute,
+		NewAccountAge: 24 * time.Hour,
+	}
+}
+
+// Activity is a message about to be posted.
+type Activity struct {
+	UserID         int64
+	AccountAge     time.Duration
+	ConversationID int64
+	// Direct is set for messages in one-to-one conversations.
+	Direct   bool
+	Content  string
+	HasLinks bool
+}
+
+// Verdict is the rule an activity broke and the restriction it earns.
+type Verdict struct {
+	Rule        Rule
+	Restriction moderation.Restriction
+	Reason      string
+}
+
+type Detector struct {
+	windows ratelimit.Windows
+	config  Config
+	seq     atomic.Int64
+}
+
+func NewDetector(windows ratelimit.Windows, config Config) *Detector {
+	return &Detector{windows: windows, config: config}
+}
+
+// Check records an activity in the account's windows and returns the
+// verdict of the first rule it breaks, or nil. Mass DMs from new accounts
+// take precedence, as they earn the harsher restriction.
+func (d *Detector) Check(ctx context.Context, a Activity) (*Verdict, error) {
+	user := strconv.FormatInt(a.UserID, 10)
+	var verdicts []*Verdict
+
+	if a.Direct && d.config.DMLimit > 0 && a.AccountAge < d.config.NewAccountAge {
+		n, err := d.windows.Add(ctx, "spam:dm:"+user, strconv.FormatInt(a.ConversationID, 10), d.config.DMWindow)
+		if err != nil {
+			return nil, err
+		}
+		if n > d.config.DMLimit {
+			verdicts = append(verdicts, &Verdict{
+				Rule:        RuleMassDM,
+				Restriction: moderation.RestrictShadowMute,
+				Reason:      fmt.Sprintf("new account messaged %d people within %s", n, d.config.DMWindow),
+			})
+		}
+	}
+
+	if text := normalize(a.Content); text != "" && d.config.RepeatLimit > 0 {
+		n, err := d.windows.Add(ctx, "spam:repeat:"+user+":"+digest(text), d.event(), d.config.RepeatWindow)
+		if err != nil {
+			return nil, err
+		}
+		if n > d.config.RepeatLimit {
+			verdicts = append(verdicts, &Verdict{
+				Rule:        RuleRepeated,
+				Restriction: moderation.RestrictThrottle,
+				Reason:      fmt.Sprintf("same message posted %d times within %s", n, d.config.RepeatWindow),
+			})
+		}
+	}
+
+	if a.HasLinks && d.config.LinkLimit > 0 {
+		n, err := d.windows.Add(ctx, "spam:links:"+user, d.event(), d.config.LinkWindow)
+		if err != nil {
+			return nil, err
+		}
+		if n > d.config.LinkLimit {
+			verdicts = append(verdicts, &Verdict{
+				Rule:        RuleLinkBurst,
+				Restriction: moderation.RestrictThrottle,
+				Reason:      fmt.Sprintf("%d messages with links within %s", n, d.config.LinkWindow),
+			})
+		}
+	}
+
+	if len(verdicts) == 0 {
+		return nil, nil
+	}
+	return verdicts[0], nil
+}
+
+// event returns a window member unique to one message.
+func (d *Detector) event() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatInt(d.seq.Add(1), 36)
+}
+
+// normalize makes messages differing only in case and spacing identical.
+func normalize(content string) string {
+	return strings.Join(strings.Fields(strings.ToLower(content)), " ")
+}
+
+func digest(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,83 @@
+package spam
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/domain/moderation"
+)
+
+// memoryWindows counts distinct members per key, ignoring time.
+type memoryWindows map[string]map[string]bool
+
+func (w memoryWindows) Add(ctx context.Context, key, member string, window time.Duration) (int, error) {
+	if w[key] == nil {
+		w[key] = map[string]bool{}
+	}
+	w[key][member] = true
+	return len(w[key]), nil
+}
+
+func TestRepeatedMessages(t *testing.T) {
+	d := NewDetector(memoryWindows{}, DefaultConfig())
+	ctx := context.Background()
+
+	for i, content := range []string{"Buy now", "buy  NOW", "buy now", "something else"} {
+		v, err := d.Check(ctx, Activity{UserID: 1, ConversationID: 2, Content: content})
+		if err != nil || v != nil {
+			t.Fatalf("message %d: got %+v, %v want no verdict", i, v, err)
+		}
+	}
+	v, _ := d.Check(ctx, Activity{UserID: 1, ConversationID: 3, Content: "Buy now"})
+	if v == nil || v.Rule != RuleRepeated || v.Restriction != moderation.RestrictThrottle {
+		t.Errorf("fourth repeat: got %+v want %s throttle", v, RuleRepeated)
+	}
+
+	// Other accounts have their own windows.
+	if v, _ := d.Check(ctx, Activity{UserID: 2, ConversationID: 2, Content: "Buy now"}); v != nil {
+		t.Errorf("other user: got %+v want no verdict", v)
+	}
+}
+
+func TestLinkBurst(t *testing.T) {
+	d := NewDetector(memoryWindows{}, Config{LinkLimit: 2, LinkWindow: time.Minute})
+	ctx := context.Background()
+
+	var v *Verdict
+	for i := 0; i < 3; i++ {
+		v, _ = d.Check(ctx, Activity{UserID: 1, Content: "see https://example.com", HasLinks: true})
+	}
+	if v == nil || v.Rule != RuleLinkBurst {
+		t.Errorf("third link: got %+v want %s", v, RuleLinkBurst)
+	}
+}
+
+func TestMassDM(t *testing.T) {
+	config := Config{DMLimit: 2, DMWindow: time.Minute, NewAccountAge: time.Hour}
+	ctx := context.Background()
+
+	d := NewDetector(memoryWindows{}, config)
+	var v *Verdict
+	for _, conv := range []int64{1, 2, 2, 3} {
+		v, _ = d.Check(ctx, Activity{UserID: 1, AccountAge: time.Minute, ConversationID: conv, Direct: true})
+	}
+	if v == nil || v.Rule != RuleMassDM || v.Restriction != moderation.RestrictShadowMute {
+		t.Errorf("third recipient: got %+v want %s shadow mute", v, RuleMassDM)
+	}
+
+	// Established accounts and group conversations are not counted.
+	d = NewDetector(memoryWindows{}, config)
+	for _, conv := range []int64{1, 2, 3} {
+		v, _ = d.Check(ctx, Activity{UserID: 1, AccountAge: 48 * time.Hour, ConversationID: conv, Direct: true})
+	}
+	if v != nil {
+		t.Errorf("old account: got %+v want no verdict", v)
+	}
+	for _, conv := range []int64{1, 2, 3} {
+		v, _ = d.Check(ctx, Activity{UserID: 2, AccountAge: time.Minute, ConversationID: conv})
+	}
+	if v != nil {
+		t.Errorf("group messages: got %+v want no verdict", v)
+	}
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(t.TempDir(), "http://localhost:8080/api/files", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Put(ctx, "a/b/hello.txt", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := l.Get(ctx, "a/b/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "hello" {
+		t.Errorf("Get returned unexpected content: got %q want %q", data, "hello")
+	}
+
+	if err := l.Delete(ctx, "a/b/hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get(ctx, "a/b/hello.txt"); err != ErrNotFound {
+		t.Errorf("Get after Delete: got %v want %v", err, ErrNotFound)
+	}
+
+	for _, key := range []string{"", "../escape", "/etc/passwd"} {
+		if err := l.Put(ctx, key, strings.NewReader(""), 0, ""); err != ErrInvalidKey {
+			t.Errorf("Put(%q): got %v want %v", key, err, ErrInvalidKey)
+		}
+	}
+}
+
+func TestLocalSignedURL(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), "http://localhost:8080/api/files/", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := l.SignedURL(context.Background(), "a/file.png", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/api/files/a/file.png" {
+		t.Errorf("unexpected path: got %q want %q", u.Path, "/api/files/a/file.png")
+	}
+
+	q := u.Query()
+	if err := l.Verify("a/file.png", q.Get("expires"), q.Get("signature")); err != nil {
+		t.Errorf("Verify returned %v for a valid signature", err)
+	}
+	if err := l.Verify("a/other.png", q.Get("expires"), q.Get("signature")); err != ErrInvalidSignature {
+		t.Errorf("Verify with another key: got %v want %v", err, ErrInvalidSignature)
+	}
+	if err := l.Verify("a/file.png", "1", q.Get("signature")); err != ErrInvalidSignature {
+		t.Errorf("Verify with expired time: got %v want %v", err, ErrInvalidSignature)
+	}
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a minimal in-memory stand-in for an S3-compatible server that
+// understands single-part PUT, GET, HEAD and DELETE on objects.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") == "" && r.URL.Query().Get("X-Amz-Signature") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			data = decodeChunked(data)
+		}
+		f.objects[r.URL.Path] = data
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`)
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// decodeChunked strips the aws-chunked framing ("<hex size>;chunk-signature=...")
+// that clients use for streaming signed uploads over plain HTTP.
+func decodeChunked(body []byte) []byte {
+	var out []byte
+	rest := string(body)
+	for {
+		header, after, ok := strings.Cut(rest, "\r\n")
+		if !ok {
+			return out
+		}
+		sizeHex, _, _ := strings.Cut(header, ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil || size == 0 || int(size) > len(after) {
+			return out
+		}
+		out = append(out, after[:size]...)
+		rest = strings.TrimPrefix(after[size:], "\r\n")
+	}
+}
+
+func TestS3(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	s, err := NewS3(S3Config{
+		Endpoint:  strings.TrimPrefix(srv.URL, "http://"),
+		Bucket:    "chatvui",
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "a/hello.txt", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.objects["/chatvui/a/hello.txt"]; !ok {
+		t.Fatalf("object not stored under bucket path, have %v", fake.objects)
+	}
+
+	r, err := s.Get(ctx, "a/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "hello" {
+		t.Errorf("Get returned unexpected content: got %q want %q", data, "hello")
+	}
+
+	if _, err := s.Get(ctx, "missing.txt"); err != ErrNotFound {
+		t.Errorf("Get of missing object: got %v want %v", err, ErrNotFound)
+	}
+
+	raw, err := s.SignedURL(ctx, "a/hello.txt", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/chatvui/a/hello.txt" || u.Query().Get("X-Amz-Expires") != "60" {
+		t.Errorf("unexpected presigned URL %q", raw)
+	}
+	resp, err := http.Get(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("presigned GET returned %d", resp.StatusCode)
+	}
+
+	if err := s.Delete(ctx, "a/hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.objects) != 0 {
+		t.Errorf("object not deleted: %v", fake.objects)
+	}
+}
@@ -0,0 +1,314 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"backend/internal/domain/message"
+)
+
+var (
+	ErrBlockedAddress = errors.New("unfurl: address is not allowed")
+	ErrNotHTML        = errors.New("unfurl: response is not HTML")
+)
+
+// MaxURLs is the maximum number of URLs unfurled per message.
+const MaxURLs = 3
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+// ExtractURLs returns the distinct http(s) URLs in text, up to MaxURLs.
+func ExtractURLs(text string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, raw := range urlPattern.FindAllString(text, -1) {
+		// Trailing punctuation usually belongs to the sentence, not the URL.
+		raw = strings.TrimRight(raw, ".,;:!?)]}")
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || seen[raw] {
+			continue
+		}
+		seen[raw] = true
+		urls = append(urls, raw)
+		if len(urls) == MaxURLs {
+			break
+		}
+	}
+	return urls
+}
+
+type Options struct {
+	// Timeout bounds the whole fetch including redirects.
+	Timeout time.Duration
+
+	// MaxBytes is the maximum number of body bytes read from a page.
+	MaxBytes int64
+
+	// AllowPrivate disables the SSRF protection that refuses to connect to
+	// private, loopback and link-local addresses and to ports other than 80
+	// and 443. It exists for tests against local servers.
+	AllowPrivate bool
+}
+
+// Fetcher fetches pages and extracts their OpenGraph and Twitter card
+// metadata.
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func NewFetcher(opts Options) *Fetcher {
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.MaxBytes == 0 {
+		opts.MaxBytes = 512 << 10
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		// The check runs on the resolved address of every connection,
+		// including those made for redirects, so DNS tricks cannot reach
+		// internal hosts.
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			return checkAddress(address)
+		}
+	}
+
+	transport := &http.Transport{
+		// Never go through an environment proxy, which would bypass the
+		// address check.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 3 {
+					return errors.New("unfurl: too many redirects")
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return ErrBlockedAddress
+				}
+				return nil
+			},
+		},
+		maxBytes: opts.MaxBytes,
+	}
+}
+
+// checkAddress refuses connections to non-public IPs and unusual ports.
+func checkAddress(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if port != "80" && port != "443" {
+		return ErrBlockedAddress
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicIP(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// reserved lists the special-purpose ranges of the IANA IPv4 and IPv6
+// registries that are not globally routable unicast.
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+
+	netip.MustParsePrefix("::/127"),
+	netip.MustParsePrefix("::ffff:0:0/96"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("3fff::/20"),
+	netip.MustParsePrefix("5f00::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("fec0::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// IsPublicIP reports whether ip is a globally routable unicast address.
+// IPv4-mapped IPv6 addresses are checked as IPv4.
+func IsPublicIP(ip net.IP) bool {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range reserved {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Fetch downloads rawURL and returns its preview metadata.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*message.LinkPreview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, ErrBlockedAddress
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "ChatVuiBot/1.0 (+link preview)")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && errors.Is(opErr.Err, ErrBlockedAddress) {
+			return nil, ErrBlockedAddress
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unfurl: unexpected status %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" {
+		return nil, ErrNotHTML
+	}
+
+	preview, err := parse(io.LimitReader(resp.Body, f.maxBytes), resp.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	preview.URL = rawURL
+	return preview, nil
+}
+
+// parse extracts the preview from the <head> of an HTML document. OpenGraph
+// properties take precedence over Twitter cards, which take precedence over
+// the plain <title> and description.
+func parse(r io.Reader, base *url.URL) (*message.LinkPreview, error) {
+	meta := make(map[string]string)
+	var title string
+
+	z := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return buildPreview(meta, title, base), nil
+			}
+			return nil, z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "body":
+				// Metadata lives in the head; stop before reading the page.
+				return buildPreview(meta, title, base), nil
+			case "title":
+				inTitle = tt == html.StartTagToken
+			case "meta":
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = string(v)
+					}
+				}
+				if key != "" && content != "" {
+					if _, ok := meta[key]; !ok {
+						meta[key] = strings.TrimSpace(content)
+					}
+				}
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.TrimSpace(string(z.Text()))
+			}
+		case html.EndTagToken:
+			inTitle = false
+		}
+	}
+}
+
+func buildPreview(meta map[string]string, title string, base *url.URL) *message.LinkPreview {
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := meta[k]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	p := &message.LinkPreview{
+		Title:       truncate(first("og:title", "twitter:title"), 300),
+		Description: truncate(first("og:description", "twitter:description", "description"), 1000),
+		SiteName:    truncate(first("og:site_name"), 100),
+	}
+	if p.Title == "" {
+		p.Title = truncate(title, 300)
+	}
+	if img := first("og:image", "og:image:url", "twitter:image", "twitter:image:src"); img != "" {
+		if u, err := base.Parse(img); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			p.Image = u.String()
+		}
+	}
+	return p
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
@@ -0,0 +1,133 @@
+package unfurl
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const page = `<!DOCTYPE html>
+<html>
+<head>
+	<title>Fallback title</title>
+	<meta property="og:title" content="Chat Vui">
+	<meta name="twitter:title" content="Twitter title">
+	<meta name="twitter:description" content="Trò chuyện vui vẻ">
+	<meta property="og:image" content="/cover.png">
+	<meta property="og:site_name" content="chatvui.com">
+</head>
+<body><meta property="og:title" content="ignored"></body>
+</html>`
+
+func TestExtractURLs(t *testing.T) {
+	got := ExtractURLs("xem https://example.com/a, và (http://example.org/b) https://example.com/a ftp://x.y https://c.d https://e.f")
+	want := []string{"https://example.com/a", "http://example.org/b", "https://c.d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractURLs: got %v want %v", got, want)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	f := NewFetcher(Options{AllowPrivate: true})
+	p, err := f.Fetch(context.Background(), srv.URL+"/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.URL != srv.URL+"/post" || p.Title != "Chat Vui" || p.Description != "Trò chuyện vui vẻ" ||
+		p.Image != srv.URL+"/cover.png" || p.SiteName != "chatvui.com" {
+		t.Errorf("unexpected preview: %+v", p)
+	}
+}
+
+func TestFetchLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+			return
+		case "/huge":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head>" + strings.Repeat("<meta name=x content=y>", 10000) + `<meta property="og:title" content="late">`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	f := NewFetcher(Options{AllowPrivate: true, Timeout: 100 * time.Millisecond, MaxBytes: 1024})
+	ctx := context.Background()
+
+	if _, err := f.Fetch(ctx, srv.URL+"/slow"); err == nil {
+		t.Error("Fetch of a slow page succeeded")
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/json"); err != ErrNotHTML {
+		t.Errorf("Fetch of JSON: got %v want %v", err, ErrNotHTML)
+	}
+	if p, err := f.Fetch(ctx, srv.URL+"/huge"); err != nil || p.Title != "" {
+		t.Errorf("Fetch read past the size limit: %+v, %v", p, err)
+	}
+}
+
+func TestFetchBlocksPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	f := NewFetcher(Options{})
+	for _, target := range []string{srv.URL, "http://127.0.0.1/", "http://[::1]/", "http://169.254.169.254/latest/meta-data", "file:///etc/passwd"} {
+		if _, err := f.Fetch(context.Background(), target); err != ErrBlockedAddress {
+			t.Errorf("Fetch(%s): got %v want %v", target, err, ErrBlockedAddress)
+		}
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":         true,
+		"2606:4700::1111": true,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"100.64.0.1":      false,
+		"127.0.0.1":       false,
+		"169.254.169.254": false,
+		"::ffff:10.0.0.1": false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"0.1.2.3":         false,
+		"192.0.0.8":       false,
+		"198.18.0.1":      false,
+		"240.0.0.1":       false,
+		"255.255.255.255": false,
+		"::":              false,
+		"::1":             false,
+		"64:ff9b::a00:1":  false,
+		"2001:db8::1":     false,
+		"2002:a00:1::":    false,
+		"fe80::1":         false,
+		"ff02::1":         false,
+	}
+	for ip, want := range tests {
+		if got := IsPublicIP(net.ParseIP(ip)); got != want {
+			t.Errorf("IsPublicIP(%s): got %v want %v", ip, got, want)
+		}
+	}
+}
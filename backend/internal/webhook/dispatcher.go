@@ -0,0 +1,285 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"backend/internal/domain/webhook"
+	"backend/internal/unfurl"
+)
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook's secret, prefixed "sha256=".
+const (
+	HeaderEvent     = "X-Chatvui-Event"
+	HeaderDelivery  = "X-Chatvui-Delivery"
+	HeaderTimestamp = "X-Chatvui-Timestamp"
+	HeaderSignature = "X-Chatvui-Signature"
+)
+
+var errBlockedAddress = errors.New("webhook: address is not allowed")
+
+// Payload is the JSON body POSTed to webhooks.
+type Payload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type Options struct {
+	// MaxAttempts is the number of tries before a delivery is failed.
+	MaxAttempts int
+	// Backoff is the delay before the first retry; it doubles after each
+	// failed attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout bounds a single delivery request.
+	Timeout time.Duration
+	// PollInterval is how often the queue is checked for due deliveries.
+	PollInterval time.Duration
+	// BatchSize is the number of deliveries claimed per poll.
+	BatchSize int
+	// AllowPrivate permits delivering to private and loopback addresses.
+	// It exists for tests and development.
+	AllowPrivate bool
+}
+
+// Dispatcher turns events into queued deliveries and works the queue.
+type Dispatcher struct {
+	hooks      webhook.Repository
+	deliveries webhook.DeliveryRepository
+	client     *http.Client
+	opts       Options
+	now        func() time.Time
+}
+
+func NewDispatcher(hooks webhook.Repository, deliveries webhook.DeliveryRepository, opts Options) *Dispatcher {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 10 * time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Hour
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+
+	return &Dispatcher{
+		hooks:      hooks,
+		deliveries: deliveries,
+		client:     NewHTTPClient(opts.Timeout, opts.AllowPrivate),
+		opts:       opts,
+		now:        time.Now,
+	}
+}
+
+// NewHTTPClient returns a client for calling user supplied URLs. Unless
+// allowPrivate is set it refuses to connect to non-public addresses, checked
+// on every dial so DNS rebinding cannot reach internal services, and it does
+// not follow redirects.
+func NewHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !unfurl.IsPublicIP(ip) {
+				return errBlockedAddress
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{Proxy: nil, DialContext: dialer.DialContext},
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Publish queues a delivery of event to every subscribed webhook. Events
+// without a conversation only reach global webhooks.
+func (d *Dispatcher) Publish(ctx context.Context, event string, conversationID *int64, data interface{}) error {
+	hooks, err := d.hooks.Subscribers(ctx, event, conversationID)
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	now := d.now()
+	body, err := json.Marshal(Payload{ID: hex.EncodeToString(id[:]), Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]webhook.Delivery, len(hooks))
+	for i, h := range hooks {
+		deliveries[i] = webhook.Delivery{WebhookID: h.ID, Event: event, Payload: body, NextAttemptAt: now}
+	}
+	return d.deliveries.Enqueue(ctx, deliveries)
+}
+
+// Run works the delivery queue until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		n, err := d.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("error processing webhook deliveries: %v", err)
+		}
+		// Keep draining while full batches come back.
+		if n == d.opts.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce attempts the deliveries that are due and returns how many it
+// claimed.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	// The lease outlives a request, so a crashed worker's deliveries are
+	// retried by another one.
+	due, err := d.deliveries.Claim(ctx, d.now(), 2*d.opts.Timeout, d.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i := range due {
+		d.attempt(ctx, &due[i])
+		if err := d.deliveries.Update(ctx, &due[i]); err != nil {
+			log.Printf("error updating webhook delivery %d: %v", due[i].ID, err)
+		}
+	}
+	return len(due), nil
+}
+
+// attempt sends one delivery and records the outcome on it.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *webhook.Delivery) {
+	delivery.Attempts++
+	delivery.LastStatus = 0
+	delivery.LastError = ""
+
+	hook, err := d.hooks.Get(ctx, delivery.WebhookID)
+	if errors.Is(err, webhook.ErrNotFound) {
+		delivery.Status = webhook.StatusFailed
+		delivery.LastError = err.Error()
+		return
+	}
+	if err == nil {
+		delivery.LastStatus, err = d.post(ctx, hook, delivery)
+	}
+
+	now := d.now()
+	switch {
+	case err == nil && delivery.LastStatus >= 200 && delivery.LastStatus < 300:
+		delivery.Status = webhook.StatusSucceeded
+		delivery.DeliveredAt = &now
+		return
+	case err != nil:
+		delivery.LastError = truncate(err.Error(), 255)
+	default:
+		delivery.LastError = fmt.Sprintf("unexpected status %d", delivery.LastStatus)
+	}
+
+	if delivery.Attempts >= d.opts.MaxAttempts {
+		delivery.Status = webhook.StatusFailed
+		return
+	}
+	delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.Backoff
+	for i := 1; i < attempts && delay < d.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.opts.MaxBackoff {
+		delay = d.opts.MaxBackoff
+	}
+	return delay
+}
+
+func (d *Dispatcher) post(ctx context.Context, hook *webhook.Webhook, delivery *webhook.Delivery) (int, error) {
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ChatVui-Webhook/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(hook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256 signature of a payload sent at timestamp.
+// Receivers recompute it to verify deliveries.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random signing secret for a new webhook.
+func NewSecret() (string, error) {
+	var b [24]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b[:]), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
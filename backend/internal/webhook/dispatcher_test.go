@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/webhook"
+)
+
+type fakeHooks struct {
+	hooks []webhook.Webhook
+}
+
+func (f *fakeHooks) Create(ctx context.Context, w *webhook.Webhook) error { return nil }
+
+func (f *fakeHooks) Get(ctx context.Context, id int64) (*webhook.Webhook, error) {
+	for _, h := range f.hooks {
+		if h.ID == id {
+			return &h, nil
+		}
+	}
+	return nil, webhook.ErrNotFound
+}
+
+func (f *fakeHooks) ListByOwner(ctx context.Context, ownerID int64) ([]webhook.Webhook, error) {
+	return nil, nil
+}
+
+func (f *fakeHooks) Delete(ctx context.Context, id int64) error { return nil }
+
+func (f *fakeHooks) Subscribers(ctx context.Context, event string, conversationID *int64) ([]webhook.Webhook, error) {
+	var out []webhook.Webhook
+	for _, h := range f.hooks {
+		global := h.ConversationID == nil
+		scoped := conversationID != nil && h.ConversationID != nil && *h.ConversationID == *conversationID
+		if h.Subscribes(event) && (global || scoped) {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+type fakeDeliveries struct {
+	mu     sync.Mutex
+	queue  []*webhook.Delivery
+	nextID int64
+}
+
+func (f *fakeDeliveries) Enqueue(ctx context.Context, deliveries []webhook.Delivery) error {
+	for _, d := range deliveries {
+		f.nextID++
+		d.ID = f.nextID
+		d.Status = webhook.StatusPending
+		f.queue = append(f.queue, &d)
+	}
+	return nil
+}
+
+func (f *fakeDeliveries) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhook.Delivery, error) {
+	var out []webhook.Delivery
+	for _, d := range f.queue {
+		if d.Status == webhook.StatusPending && !d.NextAttemptAt.After(now) && len(out) < limit {
+			d.NextAttemptAt = now.Add(lease)
+			out = append(out, *d)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeDeliveries) Update(ctx context.Context, d *webhook.Delivery) error {
+	for i, q := range f.queue {
+		if q.ID == d.ID {
+			c := *d
+			f.queue[i] = &c
+		}
+	}
+	return nil
+}
+
+func (f *fakeDeliveries) ListByWebhook(ctx context.Context, webhookID int64, limit, offset int) ([]webhook.Delivery, error) {
+	return nil, nil
+}
+
+func TestDispatcher(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []*http.Request
+		bodies   [][]byte
+		failures = 2
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		if r.URL.Path == "/flaky" && failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}))
+	defer srv.Close()
+
+	convID, otherID := int64(7), int64(8)
+	hooks := &fakeHooks{hooks: []webhook.Webhook{
+		{ID: 1, URL: srv.URL + "/global", Secret: "s1", Events: []string{webhook.EventMessageCreated, webhook.EventUserStatus}},
+		{ID: 2, ConversationID: &convID, URL: srv.URL + "/flaky", Secret: "s2", Events: []string{webhook.EventMessageCreated}},
+		{ID: 3, ConversationID: &otherID, URL: srv.URL + "/other", Secret: "s3", Events: []string{webhook.EventMessageCreated}},
+		{ID: 4, ConversationID: &convID, URL: srv.URL + "/broken", Secret: "s4", Events: []string{webhook.EventMessageCreated}},
+	}}
+	deliveries := &fakeDeliveries{}
+	d := NewDispatcher(hooks, deliveries, Options{
+		MaxAttempts:  3,
+		Backoff:      time.Second,
+		MaxBackoff:   time.Minute,
+		AllowPrivate: true,
+	})
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	if err := d.Publish(ctx, webhook.EventMessageCreated, &convID, map[string]string{"content": "xin chào"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if len(deliveries.queue) != 3 {
+		t.Fatalf("queued deliveries: got %d want 3", len(deliveries.queue))
+	}
+
+	// First pass: /global succeeds, /flaky and /broken fail.
+	if n, err := d.RunOnce(ctx); err != nil || n != 3 {
+		t.Fatalf("RunOnce: got %d, %v", n, err)
+	}
+	flaky := deliveries.queue[1]
+	if flaky.Status != webhook.StatusPending || flaky.Attempts != 1 || !flaky.NextAttemptAt.Equal(now.Add(time.Second)) {
+		t.Errorf("after 1 failure: got %+v", flaky)
+	}
+
+	// Nothing is due until the backoff elapsed.
+	if n, _ := d.RunOnce(ctx); n != 0 {
+		t.Errorf("deliveries retried before backoff: %d", n)
+	}
+	now = now.Add(time.Second)
+	d.RunOnce(ctx)
+	if got := deliveries.queue[1].NextAttemptAt; !got.Equal(now.Add(2 * time.Second)) {
+		t.Errorf("second backoff: got %v want %v", got, now.Add(2*time.Second))
+	}
+	now = now.Add(2 * time.Second)
+	d.RunOnce(ctx)
+
+	for i, want := range []string{webhook.StatusSucceeded, webhook.StatusSucceeded, webhook.StatusFailed} {
+		if got := deliveries.queue[i]; got.Status != want {
+			t.Errorf("delivery %d: got %s want %s (%+v)", got.ID, got.Status, want, got)
+		}
+	}
+	if got := deliveries.queue[2]; got.Attempts != 3 || got.LastStatus != 500 {
+		t.Errorf("failed delivery: got attempts %d status %d", got.Attempts, got.LastStatus)
+	}
+
+	r, body := requests[0], bodies[0]
+	if r.Header.Get(HeaderEvent) != webhook.EventMessageCreated {
+		t.Errorf("%s: got %q", HeaderEvent, r.Header.Get(HeaderEvent))
+	}
+	secret := "s1"
+	if strings.HasSuffix(r.URL.Path, "flaky") {
+		secret = "s2"
+	}
+	if got, want := r.Header.Get(HeaderSignature), "sha256="+Sign(secret, r.Header.Get(HeaderTimestamp), body); got != want {
+		t.Errorf("signature: got %q want %q", got, want)
+	}
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil || p.Event != webhook.EventMessageCreated || p.ID == "" {
+		t.Errorf("payload: got %s (%v)", body, err)
+	}
+}
+
+func TestDispatcherBlocksPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request reached private address")
+	}))
+	defer srv.Close()
+
+	hooks := &fakeHooks{hooks: []webhook.Webhook{{ID: 1, URL: srv.URL, Secret: "s", Events: []string{webhook.EventUserStatus}}}}
+	deliveries := &fakeDeliveries{}
+	d := NewDispatcher(hooks, deliveries, Options{MaxAttempts: 1})
+
+	ctx := context.Background()
+	d.Publish(ctx, webhook.EventUserStatus, nil, nil)
+	d.RunOnce(ctx)
+
+	if got := deliveries.queue[0]; got.Status != webhook.StatusFailed || !strings.Contains(got.LastError, "not allowed") {
+		t.Errorf("delivery to private address: got %+v", got)
+	}
+}
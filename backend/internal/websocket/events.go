@@ -0,0 +1,82 @@
+package websocket
+
+// Event types pushed to clients in addition to the presence frames.
+const (
+	EventMessage        = "message"
+	EventThreadReply    = "thread_reply"
+	EventThreadUpdated  = "thread_updated"
+	EventMessageEdited  = "message_edited"
+	EventMessageUpdated = "message_updated"
+	EventMessageDeleted = "message_deleted"
+	EventReaction       = "reaction"
+	EventMention        = "mention"
+	EventMemberJoined   = "member_joined"
+	EventMemberRemoved  = "member_removed"
+	EventModeration     = "moderation"
+
+	EventAttachmentUpdated = "attachment_updated"
+
+	EventConversationUpdated = "conversation_updated"
+	EventCommandResponse     = "command_response"
+	EventError               = "error"
+
+	// Presence frames.
+	EventOnlineUsers = "online_users"
+	EventUserStatus  = "user_status"
+)
+
+// EventResync tells a resuming stream client that events it missed are no
+// longer known, so it must reload its state. It is always delivered.
+const EventResync = "resync"
+
+// Codes of error frames.
+const (
+	CodeRateLimited     = "rate_limited"
+	CodeFrameTooLarge   = "frame_too_large"
+	CodeInvalidFrame    = "invalid_frame"
+	CodeUnknownType     = "unknown_type"
+	CodeMissingField    = "missing_field"
+	CodeInvalidField    = "invalid_field"
+	CodeInvalidUsername = "invalid_username"
+
+	// Codes of frames that were valid but could not be carried out.
+	CodeInvalidMessage   = "invalid_message"
+	CodeMessageRejected  = "message_rejected"
+	CodeForbidden        = "forbidden"
+	CodeMuted            = "muted"
+	CodeSlowMode         = "slow_mode"
+	CodeThrottled        = "throttled"
+	CodeAnnouncementOnly = "announcement_only"
+	CodeNotFound         = "not_found"
+	CodeInternal         = "internal_error"
+)
+
+// Error is the payload of error frames. RetryAfter is set in seconds when
+// the frame was rate limited, or refused by slow mode or a spam throttle.
+type Error struct {
+	Code       string `json:"code,omitempty"`
+	Message    string `json:"message"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+}
+
+// Frame types sent by clients.
+const (
+	FrameMessage = "message"
+)
+
+// Events lists the event types clients can subscribe to.
+var Events = []string{
+	EventMessage, EventThreadReply, EventThreadUpdated, EventMessageEdited, EventMessageUpdated, EventMessageDeleted,
+	EventReaction, EventMention, EventMemberJoined, EventMemberRemoved, EventModeration, EventAttachmentUpdated,
+	EventConversationUpdated, EventCommandResponse, EventError, EventOnlineUsers, EventUserStatus,
+}
+
+// ValidEvent reports whether eventType is one of Events.
+func ValidEvent(eventType string) bool {
+	for _, e := range Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -3,99 +3,505 @@ package websocket
 import (
 	"encoding/json"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
+
+	"backend/internal/domain/ratelimit"
+	"backend/internal/domain/user"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins for development
-	},
-}
+const (
+	// helloTimeout bounds the wait for the initial frame of the legacy
+	// endpoint.
+	helloTimeout = 10 * time.Second
+
+	// writeWait bounds the time a frame may take to be written.
+	writeWait = 10 * time.Second
+
+	// sendBuffer is the number of frames a websocket client may fall behind
+	// before it is dropped.
+	sendBuffer = 64
+)
 
 type Client struct {
 	Conn     *websocket.Conn
+	UserID   int64
 	Username string
+
+	// Events restricts the event types sent to the client, as bots
+	// subscribe to the events they handle. A nil set receives everything.
+	Events map[string]bool
+
+	// frames limits the frames the client may send.
+	frames *ratelimit.TokenBucket
+
+	// blocked are the users this client's user blocked; their presence is
+	// hidden from the client. Guarded by Manager.mu.
+	blocked map[int64]bool
+
+	// removed are the conversations the user was kicked or banned from
+	// while connected; their events are no longer delivered and their
+	// frames are refused. Guarded by Manager.mu.
+	removed map[int64]bool
+
+	// send queues the frames of a websocket client for its write pump.
+	send chan []byte
+
+	// stream is set on stream clients, which have no websocket.
+	stream *stream
+
+	closeOnce sync.Once
+}
+
+// close stops the client's write pump, which then closes its websocket, or
+// ends its stream.
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		switch {
+		case c.stream != nil:
+			close(c.stream.done)
+		case c.send != nil:
+			close(c.send)
+		default:
+			c.Conn.Close()
+		}
+	})
+}
+
+// wants reports whether the client subscribed to events of the given type.
+// Untyped frames are always delivered.
+func (c *Client) wants(eventType string) bool {
+	return c.Events == nil || eventType == "" || c.Events[eventType]
 }
 
 type Message struct {
-	Type     string   `json:"type"`
-	Username string   `json:"username,omitempty"`
-	Status   string   `json:"status,omitempty"`
-	Users    []string `json:"users,omitempty"`
+	Type           string      `json:"type"`
+	Username       string      `json:"username,omitempty"`
+	Status         string      `json:"status,omitempty"`
+	Users          []string    `json:"users,omitempty"`
+	ConversationID int64       `json:"conversation_id,omitempty"`
+	Data           interface{} `json:"data,omitempty"`
 }
 
+// Frame is a frame sent by a client. Data is decoded by the frame handler
+// according to Type.
+type Frame struct {
+	Type           string          `json:"type"`
+	ConversationID int64           `json:"conversation_id,omitempty"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
+
+// FrameFunc handles a frame sent by an authenticated client. It runs on the
+// client's read goroutine, so frames of one connection are handled in order.
+type FrameFunc func(client *Client, frame Frame)
+
+// envelope is a frame addressed to the connections of specific users, or
+// to a single connection when client is set. Frames of a conversation skip
+// the connections removed from it.
+type envelope struct {
+	client         *Client
+	userIDs        map[int64]bool
+	conversationID int64
+	eventType      string
+	data           []byte
+}
+
+// BlockListFunc returns the ids of the users a user blocked.
+type BlockListFunc func(userID int64) ([]int64, error)
+
+// StatusFunc is called when a user's first connection opens or their last
+// connection closes.
+type StatusFunc func(userID int64, username string, online bool)
+
 type Manager struct {
 	clients    map[*Client]bool
 	broadcast  chan []byte
+	direct     chan envelope
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
+	onStatus   StatusFunc
+	onFrame    FrameFunc
+	frameRate  ratelimit.Rate
+	upgrader   websocket.Upgrader
+	blockList  BlockListFunc
+	presence   chan struct{}
+
+	// history holds the recent events of users with stream clients, by
+	// user. Event IDs are the epoch of the hub followed by seq.
+	history map[int64]*eventLog
+	seq     int64
+	epoch   string
 }
 
 func NewManager() *Manager {
 	return &Manager{
 		clients:    make(map[*Client]bool),
 		broadcast:  make(chan []byte),
+		direct:     make(chan envelope, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		presence:   make(chan struct{}, 1),
+		history:    make(map[int64]*eventLog),
+		epoch:      strconv.FormatInt(time.Now().UnixNano(), 36),
+		// Without an origin policy only same-origin browser pages may
+		// connect.
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+	}
+}
+
+// SetOriginPolicy restricts the browser origins that may open connections
+// to those allowed reports true for. Requests without an Origin header come
+// from non-browser clients such as bots and are accepted. It must be called
+// before connections are handled.
+func (m *Manager) SetOriginPolicy(allowed func(origin string) bool) {
+	m.upgrader.CheckOrigin = func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || allowed(origin)
+	}
+}
+
+// OnStatusChange registers fn to be notified of users going online or
+// offline. It must be called before Run; fn runs on its own goroutine.
+func (m *Manager) OnStatusChange(fn StatusFunc) {
+	m.onStatus = fn
+}
+
+// OnFrame registers fn to handle frames sent by authenticated clients. It
+// must be called before Run.
+func (m *Manager) OnFrame(fn FrameFunc) {
+	m.onFrame = fn
+}
+
+// SetFrameRate limits the frames each connection may send; frames over the
+// limit are answered with a rate_limited error frame and dropped. It must be
+// called before Run.
+func (m *Manager) SetFrameRate(rate ratelimit.Rate) {
+	m.frameRate = rate
+}
+
+// SetBlockList registers fn to load the users a connecting user blocked. It
+// must be called before connections are handled.
+func (m *Manager) SetBlockList(fn BlockListFunc) {
+	m.blockList = fn
+}
+
+// SetBlocked records that blockerID blocked or unblocked blockedID on the
+// blocker's live connections and refreshes their online users.
+func (m *Manager) SetBlocked(blockerID, blockedID int64, blocked bool) {
+	m.mu.Lock()
+	for client := range m.clients {
+		if client.UserID != blockerID {
+			continue
+		}
+		if client.blocked == nil {
+			client.blocked = map[int64]bool{}
+		}
+		if blocked {
+			client.blocked[blockedID] = true
+		} else {
+			delete(client.blocked, blockedID)
+		}
+	}
+	m.mu.Unlock()
+
+	select {
+	case m.presence <- struct{}{}:
+	default:
+		// A refresh is already pending.
+	}
+}
+
+// SendToUsers delivers data to every connection of the given users.
+func (m *Manager) SendToUsers(userIDs []int64, data []byte) {
+	m.SendEvent(userIDs, "", data)
+}
+
+// SendEvent delivers an event frame of the given type to the connections
+// of the given users that subscribed to it.
+func (m *Manager) SendEvent(userIDs []int64, eventType string, data []byte) {
+	set := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		set[id] = true
+	}
+	m.direct <- envelope{userIDs: set, eventType: eventType, data: data}
+}
+
+// SendConversationEvent is SendEvent for an event of a conversation, which
+// is not delivered to connections whose user was removed from it.
+func (m *Manager) SendConversationEvent(conversationID int64, userIDs []int64, eventType string, data []byte) {
+	set := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		set[id] = true
 	}
+	m.direct <- envelope{userIDs: set, conversationID: conversationID, eventType: eventType, data: data}
 }
 
-func (m *Manager) getOnlineUsers() []string {
+// RemoveFromConversation cuts the live connections of a user off a
+// conversation they were kicked or banned from: events of the conversation
+// already queued for them are dropped and their frames for it are refused.
+// notice, if set, is then delivered to those connections.
+func (m *Manager) RemoveFromConversation(userID, conversationID int64, eventType string, notice []byte) {
+	m.mu.Lock()
+	for client := range m.clients {
+		if client.UserID != userID {
+			continue
+		}
+		if client.removed == nil {
+			client.removed = map[int64]bool{}
+		}
+		client.removed[conversationID] = true
+	}
+	if l := m.history[userID]; l != nil {
+		if l.removed == nil {
+			l.removed = map[int64]bool{}
+		}
+		l.removed[conversationID] = true
+	}
+	m.mu.Unlock()
+
+	if notice != nil {
+		m.SendEvent([]int64{userID}, eventType, notice)
+	}
+}
+
+// AddToConversation undoes RemoveFromConversation when a user is added back
+// to a conversation.
+func (m *Manager) AddToConversation(userID, conversationID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for client := range m.clients {
+		if client.UserID == userID {
+			delete(client.removed, conversationID)
+		}
+	}
+	if l := m.history[userID]; l != nil {
+		delete(l.removed, conversationID)
+	}
+}
+
+// isRemoved reports whether the client was removed from the conversation.
+func (m *Manager) isRemoved(client *Client, conversationID int64) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return client.removed[conversationID]
+}
+
+// SendToClient delivers an event frame to a single connection, regardless
+// of its subscriptions.
+func (m *Manager) SendToClient(client *Client, eventType string, data []byte) {
+	m.direct <- envelope{client: client, eventType: eventType, data: data}
+}
+
+// IsOnline reports whether the user has at least one live connection.
+func (m *Manager) IsOnline(userID int64) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for client := range m.clients {
+		if client.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats counts the live connections of the hub.
+type Stats struct {
+	Connections int `json:"connections"`
+	// Users is the number of distinct authenticated users connected.
+	Users int `json:"users"`
+	Bots  int `json:"bots"`
+	// Anonymous is the number of connections of the legacy endpoint.
+	Anonymous int `json:"anonymous"`
+}
+
+// Stats returns the current connection counts.
+func (m *Manager) Stats() Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	st := Stats{Connections: len(m.clients)}
+	users := make(map[int64]bool, len(m.clients))
+	for client := range m.clients {
+		switch {
+		case client.UserID == 0:
+			st.Anonymous++
+		case client.Events != nil:
+			if !users[client.UserID] {
+				st.Bots++
+			}
+		default:
+			if !users[client.UserID] {
+				st.Users++
+			}
+		}
+		users[client.UserID] = true
+	}
+	return st
+}
+
+// Disconnect closes every live connection of a user with the given close
+// reason, e.g. when their account is banned. The connections unregister as
+// their read loops fail.
+func (m *Manager) Disconnect(userID int64, reason string) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	users := make([]string, 0, len(m.clients))
 	for client := range m.clients {
-		users = append(users, client.Username)
+		if client.UserID != userID {
+			continue
+		}
+		if client.Conn == nil {
+			client.close()
+			continue
+		}
+		// WriteControl and Close may be called concurrently with the write
+		// pump's writes.
+		client.Conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+			time.Now().Add(time.Second))
+		client.Conn.Close()
 	}
-	return users
 }
 
+// broadcastOnlineUsers sends every client the usernames of the connected
+// users, leaving out the users it blocked. It must only be called from the
+// Run goroutine.
 func (m *Manager) broadcastOnlineUsers() {
-	users := m.getOnlineUsers()
-	message := Message{
-		Type:  "online_users",
-		Users: users,
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var shared []byte
+	for client := range m.clients {
+		if !client.wants(EventOnlineUsers) {
+			continue
+		}
+		if len(client.blocked) > 0 {
+			if data := m.onlineUsersFrame(client.blocked); data != nil {
+				m.write(client, data)
+			}
+			continue
+		}
+		if shared == nil {
+			if shared = m.onlineUsersFrame(nil); shared == nil {
+				return
+			}
+		}
+		m.write(client, shared)
 	}
+}
 
-	data, err := json.Marshal(message)
+// onlineUsersFrame encodes the online_users frame without the given users.
+// The caller must hold m.mu.
+func (m *Manager) onlineUsersFrame(hidden map[int64]bool) []byte {
+	users := make([]string, 0, len(m.clients))
+	for client := range m.clients {
+		if client.UserID == 0 || !hidden[client.UserID] {
+			users = append(users, client.Username)
+		}
+	}
+
+	data, err := json.Marshal(Message{Type: EventOnlineUsers, Users: users})
 	if err != nil {
 		log.Printf("error marshaling message: %v", err)
+		return nil
+	}
+	return data
+}
+
+// HandleUserWebSocket upgrades the connection of an already authenticated
+// user. Unlike HandleWebSocket it does not wait for an initial frame carrying
+// the username.
+func (m *Manager) HandleUserWebSocket(w http.ResponseWriter, r *http.Request, userID int64, username string) {
+	m.handleClient(w, r, &Client{UserID: userID, Username: username})
+}
+
+// HandleBotWebSocket upgrades the connection of an authenticated bot, which
+// only receives the given event types.
+func (m *Manager) HandleBotWebSocket(w http.ResponseWriter, r *http.Request, userID int64, username string, events []string) {
+	set := make(map[string]bool, len(events))
+	for _, e := range events {
+		set[e] = true
+	}
+	m.handleClient(w, r, &Client{UserID: userID, Username: username, Events: set})
+}
+
+func (m *Manager) handleClient(w http.ResponseWriter, r *http.Request, client *Client) {
+	conn, err := m.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("error upgrading connection: %v", err)
 		return
 	}
 
-	m.broadcast <- data
+	conn.SetReadLimit(readLimit)
+	client.Conn = conn
+	if m.frameRate.Enabled() {
+		client.frames = ratelimit.NewTokenBucket(m.frameRate)
+	}
+	m.loadBlocked(client)
+	m.register <- client
+
+	go m.readPump(client)
+}
+
+// loadBlocked loads the users a connecting client's user blocked.
+func (m *Manager) loadBlocked(client *Client) {
+	if m.blockList == nil {
+		return
+	}
+	ids, err := m.blockList(client.UserID)
+	if err != nil {
+		log.Printf("error loading blocked users of %d: %v", client.UserID, err)
+	}
+	client.blocked = make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		client.blocked[id] = true
+	}
 }
 
 func (m *Manager) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := m.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("error upgrading connection: %v", err)
 		return
 	}
+	conn.SetReadLimit(readLimit)
 
 	client := &Client{
 		Conn: conn,
 	}
 
 	// Read the initial message to get the username
+	conn.SetReadDeadline(time.Now().Add(helloTimeout))
 	_, message, err := conn.ReadMessage()
 	if err != nil {
 		log.Printf("error reading message: %v", err)
 		conn.Close()
 		return
 	}
+	conn.SetReadDeadline(time.Time{})
 
 	var msg Message
+	if len(message) > MaxFrameBytes {
+		rejectHello(conn, frameError(CodeFrameTooLarge, "frames are limited to %d bytes", MaxFrameBytes))
+		return
+	}
 	if err := json.Unmarshal(message, &msg); err != nil {
-		log.Printf("error unmarshaling message: %v", err)
-		conn.Close()
+		rejectHello(conn, frameError(CodeInvalidFrame, "frame is not a valid JSON object: %v", err))
+		return
+	}
+	if !user.ValidUsername(msg.Username) {
+		rejectHello(conn, frameError(CodeInvalidUsername, "%s", user.ErrInvalidUsername.Error()))
 		return
 	}
 
@@ -105,6 +511,38 @@ func (m *Manager) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	go m.readPump(client)
 }
 
+// rejectHello answers an invalid initial frame with an error frame and
+// closes the connection. The client is not registered yet, so nothing else
+// writes to it.
+func rejectHello(conn *websocket.Conn, ferr *FrameError) {
+	if data, err := errorFrame(0, ferr); err == nil {
+		conn.SetWriteDeadline(time.Now().Add(writeWait))
+		conn.WriteMessage(websocket.TextMessage, data)
+	}
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, ferr.Code),
+		time.Now().Add(time.Second))
+	conn.Close()
+}
+
+// writePump writes the frames queued for a websocket client. It is the
+// single writer of the connection and closes it once the hub closed the
+// client's queue or a write failed, which ends its readPump.
+func (m *Manager) writePump(client *Client) {
+	defer client.Conn.Close()
+
+	for data := range client.send {
+		client.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := client.Conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			log.Printf("error writing to user %d: %v", client.UserID, err)
+			return
+		}
+	}
+	client.Conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+		time.Now().Add(time.Second))
+}
+
 func (m *Manager) readPump(client *Client) {
 	defer func() {
 		m.unregister <- client
@@ -112,14 +550,73 @@ func (m *Manager) readPump(client *Client) {
 	}()
 
 	for {
-		_, _, err := client.Conn.ReadMessage()
+		messageType, data, err := client.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("error: %v", err)
 			}
 			break
 		}
+
+		// Connections of the legacy /websocket endpoint are anonymous and
+		// can only receive.
+		if m.onFrame == nil || client.UserID == 0 {
+			continue
+		}
+		if client.frames != nil {
+			if res := client.frames.Take(time.Now()); !res.Allowed {
+				m.sendRateLimited(client, res.RetryAfter)
+				continue
+			}
+		}
+		if messageType != websocket.TextMessage {
+			m.sendError(client, 0, frameError(CodeInvalidFrame, "frames must be JSON text messages"))
+			continue
+		}
+		frame, ferr := ValidateFrame(data)
+		if ferr != nil {
+			m.sendError(client, frame.ConversationID, ferr)
+			continue
+		}
+		if frame.ConversationID != 0 && m.isRemoved(client, frame.ConversationID) {
+			m.sendError(client, frame.ConversationID, frameError(CodeForbidden, "you were removed from this conversation"))
+			continue
+		}
+		m.onFrame(client, frame)
+	}
+}
+
+// sendRateLimited tells a single connection that its frame was dropped.
+func (m *Manager) sendRateLimited(client *Client, retryAfter time.Duration) {
+	data, err := json.Marshal(Message{Type: EventError, Data: Error{
+		Code:       CodeRateLimited,
+		Message:    "too many frames, slow down",
+		RetryAfter: int(math.Ceil(retryAfter.Seconds())),
+	}})
+	if err != nil {
+		log.Printf("error marshaling message: %v", err)
+		return
 	}
+	m.SendToClient(client, EventError, data)
+}
+
+// sendError tells a single connection that its frame was rejected.
+func (m *Manager) sendError(client *Client, conversationID int64, ferr *FrameError) {
+	data, err := errorFrame(conversationID, ferr)
+	if err != nil {
+		log.Printf("error marshaling message: %v", err)
+		return
+	}
+	m.SendToClient(client, EventError, data)
+}
+
+// errorFrame encodes the error frame reporting ferr.
+func errorFrame(conversationID int64, ferr *FrameError) ([]byte, error) {
+	return json.Marshal(Message{
+		Type:           EventError,
+		ConversationID: conversationID,
+		Data:           Error{Code: ferr.Code, Message: ferr.Message},
+	})
 }
 
 func (m *Manager) Run() {
@@ -127,45 +624,139 @@ func (m *Manager) Run() {
 		select {
 		case client := <-m.register:
 			m.mu.Lock()
+			if client.stream != nil {
+				m.openStream(client)
+			} else {
+				client.send = make(chan []byte, sendBuffer)
+				go m.writePump(client)
+			}
 			m.clients[client] = true
+			first := m.connections(client.UserID) == 1
 			m.mu.Unlock()
-			m.broadcastUserStatus(client.Username, true)
+			if first {
+				m.notifyStatus(client, true)
+			}
+			m.broadcastUserStatus(client, true)
 			m.broadcastOnlineUsers()
 
 		case client := <-m.unregister:
 			m.mu.Lock()
 			if _, ok := m.clients[client]; ok {
 				delete(m.clients, client)
-				client.Conn.Close()
+				client.close()
 			}
+			if client.stream != nil {
+				m.closeStream(client)
+			}
+			last := m.connections(client.UserID) == 0
 			m.mu.Unlock()
-			m.broadcastUserStatus(client.Username, false)
+			if last {
+				m.notifyStatus(client, false)
+			}
+			m.broadcastUserStatus(client, false)
+			m.broadcastOnlineUsers()
+
+		case <-m.presence:
 			m.broadcastOnlineUsers()
 
 		case message := <-m.broadcast:
-			m.mu.RLock()
+			m.writeToAll("", message)
+
+		case env := <-m.direct:
+			m.mu.Lock()
+			if env.client != nil {
+				if m.clients[env.client] {
+					m.write(env.client, env.data)
+				}
+				m.mu.Unlock()
+				continue
+			}
+			// Events are logged once per user for their stream clients.
+			ids := make(map[int64]string)
+			for userID := range env.userIDs {
+				if id := m.record(userID, env.conversationID, env.eventType, env.data); id != "" {
+					ids[userID] = id
+				}
+			}
 			for client := range m.clients {
-				err := client.Conn.WriteMessage(websocket.TextMessage, message)
-				if err != nil {
-					log.Printf("error: %v", err)
-					client.Conn.Close()
-					delete(m.clients, client)
+				if !env.userIDs[client.UserID] || !client.wants(env.eventType) {
+					continue
+				}
+				if env.conversationID != 0 && client.removed[env.conversationID] {
+					continue
 				}
+				if client.stream != nil {
+					m.writeEvent(client, ids[client.UserID], env.data)
+					continue
+				}
+				m.write(client, env.data)
 			}
-			m.mu.RUnlock()
+			m.mu.Unlock()
+		}
+	}
+}
+
+// connections counts the open connections of a user. The caller must hold
+// m.mu.
+func (m *Manager) connections(userID int64) int {
+	n := 0
+	for client := range m.clients {
+		if client.UserID == userID {
+			n++
+		}
+	}
+	return n
+}
+
+// notifyStatus reports a status change of an authenticated user to the
+// registered StatusFunc without blocking the Run loop.
+func (m *Manager) notifyStatus(client *Client, online bool) {
+	if m.onStatus == nil || client.UserID == 0 {
+		return
+	}
+	go m.onStatus(client.UserID, client.Username, online)
+}
+
+// writeToAll sends an event frame to every connected client subscribed to
+// its type. It must only be called from the Run goroutine.
+func (m *Manager) writeToAll(eventType string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for client := range m.clients {
+		if client.wants(eventType) {
+			m.write(client, data)
 		}
 	}
 }
 
-func (m *Manager) broadcastUserStatus(username string, online bool) {
+// write queues data for a single client, dropping the client if it fell
+// too far behind. The caller must hold m.mu.
+func (m *Manager) write(client *Client, data []byte) {
+	if client.stream != nil {
+		m.writeEvent(client, "", data)
+		return
+	}
+	select {
+	case client.send <- data:
+	default:
+		log.Printf("dropping connection of user %d: too far behind", client.UserID)
+		client.close()
+		delete(m.clients, client)
+	}
+}
+
+// broadcastUserStatus tells every client, except those that blocked the
+// user, that the user connected or disconnected.
+func (m *Manager) broadcastUserStatus(subject *Client, online bool) {
 	status := "online"
 	if !online {
 		status = "offline"
 	}
 
 	message := Message{
-		Type:     "user_status",
-		Username: username,
+		Type:     EventUserStatus,
+		Username: subject.Username,
 		Status:   status,
 	}
 
@@ -175,5 +766,12 @@ func (m *Manager) broadcastUserStatus(username string, online bool) {
 		return
 	}
 
-	m.broadcast <- data
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for client := range m.clients {
+		if client.wants(message.Type) && (subject.UserID == 0 || !client.blocked[subject.UserID]) {
+			m.write(client, data)
+		}
+	}
 }
@@ -0,0 +1,401 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"backend/internal/domain/ratelimit"
+)
+
+func newTestHub(t *testing.T) (*Manager, *httptest.Server) {
+	return startTestHub(t, NewManager())
+}
+
+func startTestHub(t *testing.T, m *Manager) (*Manager, *httptest.Server) {
+	go m.Run()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+		m.HandleUserWebSocket(w, r, id, "user"+r.URL.Query().Get("user_id"))
+	}))
+	t.Cleanup(srv.Close)
+	return m, srv
+}
+
+func dial(t *testing.T, srv *httptest.Server, userID string) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?user_id=" + userID
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUntil reads frames until one of the given type arrives.
+func readUntil(t *testing.T, conn *websocket.Conn, frameType string) Message {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", frameType, err)
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Type == frameType {
+			return msg
+		}
+	}
+}
+
+func TestSendToUsers(t *testing.T) {
+	m, srv := newTestHub(t)
+
+	alice := dial(t, srv, "1")
+	readUntil(t, alice, "online_users")
+	bob := dial(t, srv, "2")
+	readUntil(t, bob, "online_users")
+
+	data, _ := json.Marshal(Message{Type: EventMessageEdited, ConversationID: 5})
+	m.SendToUsers([]int64{2}, data)
+
+	if got := readUntil(t, bob, EventMessageEdited); got.ConversationID != 5 {
+		t.Errorf("unexpected conversation id: got %d want %d", got.ConversationID, 5)
+	}
+
+	// Alice only sees the presence update for bob, never the direct frame.
+	readUntil(t, alice, "online_users")
+	alice.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, data, err := alice.ReadMessage(); err == nil {
+		t.Errorf("unexpected frame for alice: %s", data)
+	}
+}
+
+func TestSlowClientDropped(t *testing.T) {
+	m, srv := newTestHub(t)
+
+	// Alice stops reading, so her frames pile up once the socket buffers
+	// are full.
+	dial(t, srv, "1")
+	bob := dial(t, srv, "2")
+	readUntil(t, bob, "online_users")
+
+	big := make([]byte, 64<<10)
+	deadline := time.Now().Add(5 * time.Second)
+	for m.IsOnline(1) {
+		if time.Now().After(deadline) {
+			t.Fatal("slow client was not dropped")
+		}
+		m.SendToUsers([]int64{1}, big)
+	}
+
+	// The hub keeps serving the other clients.
+	data, _ := json.Marshal(Message{Type: EventMessageEdited, ConversationID: 5})
+	m.SendToUsers([]int64{2}, data)
+	readUntil(t, bob, EventMessageEdited)
+}
+
+func TestOnStatusChange(t *testing.T) {
+	type change struct {
+		userID int64
+		online bool
+	}
+	changes := make(chan change, 10)
+	m := NewManager()
+	m.OnStatusChange(func(userID int64, username string, online bool) {
+		changes <- change{userID, online}
+	})
+	_, srv := startTestHub(t, m)
+
+	expect := func(want change) {
+		t.Helper()
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Errorf("status change: got %+v want %+v", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no status change, want %+v", want)
+		}
+	}
+
+	first := dial(t, srv, "1")
+	expect(change{1, true})
+	second := dial(t, srv, "1")
+	readUntil(t, second, "online_users")
+
+	// Closing one of two connections keeps the user online.
+	first.Close()
+	readUntil(t, second, "user_status")
+	second.Close()
+	expect(change{1, false})
+
+	select {
+	case got := <-changes:
+		t.Errorf("unexpected status change %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBotEventSubscriptions(t *testing.T) {
+	m := NewManager()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.HandleBotWebSocket(w, r, 9, "ci_bot", []string{EventMention})
+	}))
+	t.Cleanup(srv.Close)
+	go m.Run()
+
+	bot := dial(t, srv, "9")
+
+	message, _ := json.Marshal(Message{Type: EventMessage})
+	mention, _ := json.Marshal(Message{Type: EventMention})
+	m.SendEvent([]int64{9}, EventMessage, message)
+	m.SendEvent([]int64{9}, EventMention, mention)
+
+	// Presence frames and the unsubscribed message event are skipped, so
+	// the mention is the first frame the bot sees.
+	bot.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := bot.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Message
+	json.Unmarshal(data, &got)
+	if got.Type != EventMention {
+		t.Errorf("first frame: got %s want %s", got.Type, EventMention)
+	}
+}
+
+func TestFrameRateLimit(t *testing.T) {
+	frames := make(chan Frame, 10)
+	m := NewManager()
+	m.OnFrame(func(client *Client, frame Frame) {
+		frames <- frame
+	})
+	m.SetFrameRate(ratelimit.Rate{PerSecond: 0.1, Burst: 2})
+	_, srv := startTestHub(t, m)
+
+	conn := dial(t, srv, "1")
+	for i := 0; i < 3; i++ {
+		frame := Frame{Type: FrameMessage, ConversationID: int64(i + 1), Data: json.RawMessage(`{"content":"hi"}`)}
+		if err := conn.WriteJSON(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	msg := readUntil(t, conn, EventError)
+	var got Error
+	data, _ := json.Marshal(msg.Data)
+	json.Unmarshal(data, &got)
+	if got.Code != CodeRateLimited || got.RetryAfter != 10 {
+		t.Errorf("error frame: got %+v want code %s retry after 10", got, CodeRateLimited)
+	}
+
+	if n := len(frames); n != 2 {
+		t.Errorf("handled frames: got %d want 2", n)
+	}
+}
+
+func TestOriginPolicy(t *testing.T) {
+	m := NewManager()
+	m.SetOriginPolicy(func(origin string) bool { return origin == "https://chatvui.com" })
+	_, srv := startTestHub(t, m)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?user_id=1"
+
+	tests := map[string]bool{
+		"https://chatvui.com": true,
+		"https://evil.com":    false,
+		"":                    true,
+	}
+	for origin, want := range tests {
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial(url, header)
+		if got := err == nil; got != want {
+			t.Errorf("origin %q: got allowed %v want %v (%v)", origin, got, want, err)
+		}
+		if conn != nil {
+			conn.Close()
+		}
+	}
+}
+
+func TestBlockedPresence(t *testing.T) {
+	m := NewManager()
+	m.SetBlockList(func(userID int64) ([]int64, error) {
+		if userID == 1 {
+			return []int64{2}, nil
+		}
+		return nil, nil
+	})
+	_, srv := startTestHub(t, m)
+
+	alice := dial(t, srv, "1")
+	readUntil(t, alice, "online_users")
+	dial(t, srv, "2")
+
+	// Alice blocked bob on connect, so his arrival is not announced to her.
+	got := readUntil(t, alice, "online_users")
+	if len(got.Users) != 1 || got.Users[0] != "user1" {
+		t.Errorf("unexpected online users for alice: got %v want [user1]", got.Users)
+	}
+
+	m.SetBlocked(1, 2, false)
+	got = readUntil(t, alice, "online_users")
+	if len(got.Users) != 2 {
+		t.Errorf("unexpected online users after unblocking: got %v want 2 users", got.Users)
+	}
+}
+
+func TestRemoveFromConversation(t *testing.T) {
+	frames := make(chan Frame, 10)
+	m := NewManager()
+	m.OnFrame(func(client *Client, frame Frame) {
+		frames <- frame
+	})
+	_, srv := startTestHub(t, m)
+
+	conn := dial(t, srv, "1")
+	readUntil(t, conn, "online_users")
+
+	notice, _ := json.Marshal(Message{Type: EventMemberRemoved, ConversationID: 7})
+	m.RemoveFromConversation(1, 7, EventMemberRemoved, notice)
+	readUntil(t, conn, EventMemberRemoved)
+
+	// Events of the conversation published with a stale member list are
+	// dropped, while other conversations are unaffected.
+	stale, _ := json.Marshal(Message{Type: EventMessage, ConversationID: 7})
+	m.SendConversationEvent(7, []int64{1}, EventMessage, stale)
+	other, _ := json.Marshal(Message{Type: EventMessage, ConversationID: 8})
+	m.SendConversationEvent(8, []int64{1}, EventMessage, other)
+	if got := readUntil(t, conn, EventMessage); got.ConversationID != 8 {
+		t.Errorf("delivered conversation: got %d want 8", got.ConversationID)
+	}
+
+	frame := Frame{Type: FrameMessage, ConversationID: 7, Data: json.RawMessage(`{"content":"hi"}`)}
+	if err := conn.WriteJSON(frame); err != nil {
+		t.Fatal(err)
+	}
+	msg := readUntil(t, conn, EventError)
+	var got Error
+	data, _ := json.Marshal(msg.Data)
+	json.Unmarshal(data, &got)
+	if got.Code != CodeForbidden {
+		t.Errorf("error code: got %s want %s", got.Code, CodeForbidden)
+	}
+	if n := len(frames); n != 0 {
+		t.Errorf("handled frames: got %d want 0", n)
+	}
+
+	m.AddToConversation(1, 7)
+	m.SendConversationEvent(7, []int64{1}, EventMessage, stale)
+	if got := readUntil(t, conn, EventMessage); got.ConversationID != 7 {
+		t.Errorf("delivered conversation after re-adding: got %d want 7", got.ConversationID)
+	}
+}
+
+func TestStatsAndDisconnect(t *testing.T) {
+	changes := make(chan bool, 10)
+	m := NewManager()
+	m.OnStatusChange(func(userID int64, username string, online bool) {
+		if userID == 1 {
+			changes <- online
+		}
+	})
+	_, srv := startTestHub(t, m)
+
+	first := dial(t, srv, "1")
+	dial(t, srv, "1")
+	other := dial(t, srv, "2")
+	readUntil(t, other, "online_users")
+	<-changes
+
+	want := Stats{Connections: 3, Users: 2}
+	if got := m.Stats(); got != want {
+		t.Errorf("stats: got %+v want %+v", got, want)
+	}
+
+	m.Disconnect(1, "banned")
+	first.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := first.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+				t.Errorf("close error: got %v want policy violation", err)
+			}
+			break
+		}
+	}
+	select {
+	case online := <-changes:
+		if online {
+			t.Error("status change: got online want offline")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("user 1 still online after Disconnect")
+	}
+
+	want = Stats{Connections: 1, Users: 1}
+	if got := m.Stats(); got != want {
+		t.Errorf("stats after disconnect: got %+v want %+v", got, want)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	m, srv := newTestHub(t)
+
+	stream, _ := m.Subscribe(1, "user1", nil, "")
+	// next returns the next frame of the stream of the given type.
+	next := func(frameType string) Message {
+		t.Helper()
+		for {
+			select {
+			case ev := <-stream.Feed():
+				var msg Message
+				if err := json.Unmarshal(ev.Data, &msg); err != nil {
+					t.Fatal(err)
+				}
+				if msg.Type == frameType {
+					return msg
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("waiting for %s on the stream", frameType)
+			}
+		}
+	}
+	next(EventOnlineUsers)
+
+	// Stream clients see other users' presence and their events.
+	conn := dial(t, srv, "2")
+	readUntil(t, conn, EventOnlineUsers)
+	if got := next(EventUserStatus); got.Username != "user2" {
+		t.Errorf("user_status username: got %q want user2", got.Username)
+	}
+	data, _ := json.Marshal(Message{Type: EventMessage, ConversationID: 3})
+	m.SendEvent([]int64{1}, EventMessage, data)
+	if got := next(EventMessage); got.ConversationID != 3 {
+		t.Errorf("conversation: got %d want 3", got.ConversationID)
+	}
+
+	// Disconnecting the user ends the stream, which then unsubscribes.
+	m.Disconnect(1, "banned")
+	select {
+	case <-stream.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream not ended by Disconnect")
+	}
+	m.Unsubscribe(stream)
+	if got := readUntil(t, conn, EventUserStatus); got.Status != "offline" {
+		t.Errorf("status after unsubscribing: got %q want offline", got.Status)
+	}
+}
@@ -0,0 +1,205 @@
+package websocket
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// streamBuffer is the number of frames a stream client may fall behind
+	// before it is dropped.
+	streamBuffer = 64
+
+	// eventLogSize is the number of recent events kept per user for stream
+	// clients to resume from.
+	eventLogSize = 256
+
+	// resumeWindow is how long the events of a user are kept after their
+	// last stream closed.
+	resumeWindow = 2 * time.Minute
+)
+
+// Event is a frame delivered to a stream client. Events addressed to the
+// user carry an ID a reconnecting client can resume after; presence frames
+// carry none.
+type Event struct {
+	ID   string
+	Data []byte
+}
+
+// stream is the state of a stream client.
+type stream struct {
+	events chan Event
+	done   chan struct{}
+
+	// resume is the ID of the last event the client saw, and resumed
+	// whether every event after it was replayed.
+	resume  string
+	resumed bool
+	ready   chan struct{}
+}
+
+// Feed returns the channel a stream client receives its events on.
+func (c *Client) Feed() <-chan Event {
+	return c.stream.events
+}
+
+// Done is closed when the hub drops a stream client, because it fell
+// behind or its user was disconnected. The client must then unsubscribe.
+func (c *Client) Done() <-chan struct{} {
+	return c.stream.done
+}
+
+// eventLog holds the recent events addressed to a user with stream
+// clients. Events up to floor may have been missed.
+type eventLog struct {
+	entries   []logEntry
+	floor     int64
+	streams   int
+	idleSince time.Time
+	// removed mirrors Client.removed while no stream is connected.
+	removed map[int64]bool
+}
+
+type logEntry struct {
+	seq       int64
+	eventType string
+	data      []byte
+}
+
+// Subscribe registers a stream client of an authenticated user, which
+// receives the events a websocket connection of the user would on its
+// Feed channel, e.g. to relay them as Server-Sent Events. events
+// restricts the event types as for bots; nil receives everything. A client
+// reconnecting with the ID of the last event it saw first receives the
+// events it missed; resumed reports whether all of them were still known.
+// Stream clients cannot send frames and must be unsubscribed when done.
+func (m *Manager) Subscribe(userID int64, username string, events []string, lastEventID string) (client *Client, resumed bool) {
+	client = &Client{
+		UserID:   userID,
+		Username: username,
+		stream: &stream{
+			done:   make(chan struct{}),
+			resume: lastEventID,
+			ready:  make(chan struct{}),
+		},
+	}
+	if events != nil {
+		client.Events = make(map[string]bool, len(events))
+		for _, e := range events {
+			client.Events[e] = true
+		}
+	}
+	m.loadBlocked(client)
+	m.register <- client
+	<-client.stream.ready
+	return client, client.stream.resumed
+}
+
+// Unsubscribe unregisters a stream client.
+func (m *Manager) Unsubscribe(client *Client) {
+	m.unregister <- client
+}
+
+// openStream attaches a registering stream client to its user's event log
+// and queues the events it missed. It must only be called from the Run
+// goroutine, with m.mu held.
+func (m *Manager) openStream(client *Client) {
+	l := m.history[client.UserID]
+	if l == nil {
+		l = &eventLog{floor: m.seq}
+		m.history[client.UserID] = l
+	}
+	l.streams++
+	for id := range l.removed {
+		if client.removed == nil {
+			client.removed = map[int64]bool{}
+		}
+		client.removed[id] = true
+	}
+
+	st := client.stream
+	var missed []logEntry
+	missed, st.resumed = m.since(l, st.resume)
+	st.events = make(chan Event, len(missed)+streamBuffer)
+	for _, e := range missed {
+		if client.wants(e.eventType) {
+			st.events <- Event{ID: m.eventID(e.seq), Data: e.data}
+		}
+	}
+	close(st.ready)
+}
+
+// closeStream detaches an unregistering stream client from its user's
+// event log, and forgets the logs idle for longer than resumeWindow. It
+// must only be called from the Run goroutine, with m.mu held.
+func (m *Manager) closeStream(client *Client) {
+	now := time.Now()
+	if l := m.history[client.UserID]; l != nil {
+		if l.streams--; l.streams == 0 {
+			l.idleSince = now
+		}
+	}
+	for userID, l := range m.history {
+		if l.streams == 0 && now.Sub(l.idleSince) > resumeWindow {
+			delete(m.history, userID)
+		}
+	}
+}
+
+// record appends an event to the log of a user, returning its ID, or ""
+// if the user has no log. The caller must hold m.mu.
+func (m *Manager) record(userID, conversationID int64, eventType string, data []byte) string {
+	l := m.history[userID]
+	if l == nil || (conversationID != 0 && l.removed[conversationID]) {
+		return ""
+	}
+	m.seq++
+	if len(l.entries) == eventLogSize {
+		l.floor = l.entries[0].seq
+		l.entries = append(l.entries[:0], l.entries[1:]...)
+	}
+	l.entries = append(l.entries, logEntry{seq: m.seq, eventType: eventType, data: data})
+	return m.eventID(m.seq)
+}
+
+// since returns the entries of a log after the event with the given ID,
+// and whether none were missed. IDs of an earlier run of the hub are not
+// resumable.
+func (m *Manager) since(l *eventLog, id string) ([]logEntry, bool) {
+	if id == "" {
+		return nil, false
+	}
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != m.epoch {
+		return nil, false
+	}
+	n, err := strconv.ParseInt(seq, 10, 64)
+	if err != nil || n < l.floor || n > m.seq {
+		return nil, false
+	}
+	for i, e := range l.entries {
+		if e.seq > n {
+			return l.entries[i:], true
+		}
+	}
+	return nil, true
+}
+
+func (m *Manager) eventID(seq int64) string {
+	return m.epoch + "-" + strconv.FormatInt(seq, 10)
+}
+
+// writeEvent queues an event for a stream client, dropping the client if
+// it fell too far behind. The caller must hold m.mu.
+func (m *Manager) writeEvent(client *Client, id string, data []byte) {
+	select {
+	case client.stream.events <- Event{ID: id, Data: data}:
+	default:
+		log.Printf("dropping stream of user %d: too far behind", client.UserID)
+		client.close()
+		delete(m.clients, client)
+	}
+}
@@ -0,0 +1,91 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// nextEvent returns the next event of a stream with an ID, skipping
+// presence frames.
+func nextEvent(t *testing.T, stream *Client) Event {
+	t.Helper()
+	for {
+		select {
+		case ev := <-stream.Feed():
+			if ev.ID != "" {
+				return ev
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("waiting for an event on the stream")
+		}
+	}
+}
+
+func TestSubscribeResume(t *testing.T) {
+	m, _ := newTestHub(t)
+	send := func(conversationID int64) {
+		data, _ := json.Marshal(Message{Type: EventMessage, ConversationID: conversationID})
+		m.SendEvent([]int64{1}, EventMessage, data)
+	}
+
+	// A second stream keeps the user's events observable throughout.
+	watcher, _ := m.Subscribe(1, "user1", nil, "")
+	defer m.Unsubscribe(watcher)
+
+	first, resumed := m.Subscribe(1, "user1", nil, "")
+	if resumed {
+		t.Error("resumed without a Last-Event-ID")
+	}
+	send(1)
+	seen := nextEvent(t, first)
+	nextEvent(t, watcher)
+	m.Unsubscribe(first)
+
+	// Events sent while the stream is gone are replayed on resume.
+	send(2)
+	send(3)
+	nextEvent(t, watcher)
+	nextEvent(t, watcher)
+
+	second, resumed := m.Subscribe(1, "user1", nil, seen.ID)
+	defer m.Unsubscribe(second)
+	if !resumed {
+		t.Fatalf("not resumed from %s", seen.ID)
+	}
+	for _, want := range []int64{2, 3} {
+		var msg Message
+		json.Unmarshal(nextEvent(t, second).Data, &msg)
+		if msg.ConversationID != want {
+			t.Errorf("replayed conversation: got %d want %d", msg.ConversationID, want)
+		}
+	}
+
+	for _, id := range []string{"bogus", "0-1", m.epoch + "-999"} {
+		stream, resumed := m.Subscribe(1, "user1", nil, id)
+		m.Unsubscribe(stream)
+		if resumed {
+			t.Errorf("resumed from unknown event %q", id)
+		}
+	}
+}
+
+func TestEventLogOverflow(t *testing.T) {
+	m := NewManager()
+	m.history[1] = &eventLog{}
+
+	first := m.record(1, 0, EventMessage, []byte("{}"))
+	// Evict the first event and the one after it.
+	for i := 0; i < eventLogSize+1; i++ {
+		m.record(1, 0, EventMessage, []byte("{}"))
+	}
+	if _, ok := m.since(m.history[1], first); ok {
+		t.Error("resumable after its next event was evicted")
+	}
+	if missed, ok := m.since(m.history[1], m.eventID(2)); !ok || len(missed) != eventLogSize {
+		t.Errorf("since the last evicted event: got %d events, %v want %d, true", len(missed), ok, eventLogSize)
+	}
+	if id := m.record(2, 0, EventMessage, []byte("{}")); id != "" {
+		t.Errorf("recorded event of a user without streams: %q", id)
+	}
+}
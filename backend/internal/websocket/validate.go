@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"backend/internal/domain/message"
+)
+
+const (
+	// MaxFrameBytes is the largest frame a client may send. It leaves room
+	// for a message of message.MaxContentLength four-byte characters.
+	MaxFrameBytes = 32 << 10
+
+	// readLimit is the frame size at which the connection is closed
+	// instead of answered with an error frame.
+	readLimit = 4 * MaxFrameBytes
+)
+
+// FrameError describes why a client frame was rejected. It is sent back as
+// the data of an error frame.
+type FrameError struct {
+	Code    string
+	Message string
+}
+
+func (e *FrameError) Error() string {
+	return e.Message
+}
+
+func frameError(code, format string, args ...interface{}) *FrameError {
+	return &FrameError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// MessageFrame is the data of a message frame, the websocket equivalent of
+// the body of POST /api/conversations/:id/messages.
+type MessageFrame struct {
+	Content       string  `json:"content"`
+	ParentID      *int64  `json:"parent_id"`
+	AttachmentIDs []int64 `json:"attachment_ids"`
+}
+
+// frameValidators check the fields of each frame type clients may send.
+var frameValidators = map[string]func(frame *Frame) *FrameError{
+	FrameMessage: validateMessageFrame,
+}
+
+// ValidateFrame decodes a frame sent by a client and checks it against the
+// schema of its type.
+func ValidateFrame(data []byte) (Frame, *FrameError) {
+	var frame Frame
+	if len(data) > MaxFrameBytes {
+		return frame, frameError(CodeFrameTooLarge, "frames are limited to %d bytes", MaxFrameBytes)
+	}
+	if !utf8.Valid(data) {
+		return frame, frameError(CodeInvalidFrame, "frame is not valid UTF-8")
+	}
+	if err := decodeStrict(data, &frame); err != nil {
+		return frame, frameError(CodeInvalidFrame, "frame is not a valid JSON object: %v", err)
+	}
+	if frame.Type == "" {
+		return frame, frameError(CodeMissingField, "type is required")
+	}
+
+	validate, ok := frameValidators[frame.Type]
+	if !ok {
+		return frame, frameError(CodeUnknownType, "unknown frame type %q", frame.Type)
+	}
+	return frame, validate(&frame)
+}
+
+func validateMessageFrame(frame *Frame) *FrameError {
+	if frame.ConversationID <= 0 {
+		return frameError(CodeMissingField, "conversation_id is required")
+	}
+	if len(frame.Data) == 0 {
+		return frameError(CodeMissingField, "data is required")
+	}
+
+	var data MessageFrame
+	if err := decodeStrict(frame.Data, &data); err != nil {
+		return frameError(CodeInvalidField, "invalid data: %v", err)
+	}
+	if data.Content == "" && len(data.AttachmentIDs) == 0 {
+		return frameError(CodeMissingField, "data.content is required")
+	}
+	if !message.ValidText(data.Content) {
+		return frameError(CodeInvalidField, "data.content contains control characters")
+	}
+	if utf8.RuneCountInString(data.Content) > message.MaxContentLength {
+		return frameError(CodeInvalidField, "data.content is longer than %d characters", message.MaxContentLength)
+	}
+	if data.ParentID != nil && *data.ParentID <= 0 {
+		return frameError(CodeInvalidField, "data.parent_id must be a message id")
+	}
+	if len(data.AttachmentIDs) > message.MaxAttachments {
+		return frameError(CodeInvalidField, "at most %d attachments are allowed", message.MaxAttachments)
+	}
+	for _, id := range data.AttachmentIDs {
+		if id <= 0 {
+			return frameError(CodeInvalidField, "data.attachment_ids must be attachment ids")
+		}
+	}
+	return nil
+}
+
+// decodeStrict decodes a single JSON value, rejecting unknown fields.
+func decodeStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("unexpected data after the JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"backend/internal/domain/message"
+)
+
+func TestValidateFrame(t *testing.T) {
+	long := strings.Repeat("ă", message.MaxContentLength+1)
+	tests := []struct {
+		name  string
+		frame string
+		code  string
+	}{
+		{"message", `{"type":"message","conversation_id":1,"data":{"content":"xin chào"}}`, ""},
+		{"reply with attachments", `{"type":"message","conversation_id":1,"data":{"content":"","parent_id":2,"attachment_ids":[3]}}`, ""},
+		{"too large", `{"type":"message","data":{"content":"` + strings.Repeat("a", MaxFrameBytes) + `"}}`, CodeFrameTooLarge},
+		{"invalid utf-8", "{\"type\":\"message\",\"data\":{\"content\":\"\xff\"}}", CodeInvalidFrame},
+		{"not json", `hello`, CodeInvalidFrame},
+		{"trailing data", `{"type":"message"} {}`, CodeInvalidFrame},
+		{"unknown field", `{"type":"message","conversation_id":1,"extra":true}`, CodeInvalidFrame},
+		{"missing type", `{"conversation_id":1}`, CodeMissingField},
+		{"unknown type", `{"type":"shout"}`, CodeUnknownType},
+		{"missing conversation", `{"type":"message","data":{"content":"hi"}}`, CodeMissingField},
+		{"missing data", `{"type":"message","conversation_id":1}`, CodeMissingField},
+		{"empty content", `{"type":"message","conversation_id":1,"data":{"content":""}}`, CodeMissingField},
+		{"wrong field type", `{"type":"message","conversation_id":1,"data":{"content":5}}`, CodeInvalidField},
+		{"control characters", `{"type":"message","conversation_id":1,"data":{"content":"a\u0007b"}}`, CodeInvalidField},
+		{"too long", `{"type":"message","conversation_id":1,"data":{"content":"` + long + `"}}`, CodeInvalidField},
+		{"invalid parent", `{"type":"message","conversation_id":1,"data":{"content":"hi","parent_id":0}}`, CodeInvalidField},
+		{"invalid attachment", `{"type":"message","conversation_id":1,"data":{"content":"hi","attachment_ids":[-1]}}`, CodeInvalidField},
+	}
+
+	for _, tt := range tests {
+		_, ferr := ValidateFrame([]byte(tt.frame))
+		code := ""
+		if ferr != nil {
+			code = ferr.Code
+		}
+		if code != tt.code {
+			t.Errorf("%s: got code %q want %q (%v)", tt.name, code, tt.code, ferr)
+		}
+	}
+}
+
+func TestInvalidFrameReply(t *testing.T) {
+	handled := make(chan Frame, 1)
+	m := NewManager()
+	m.OnFrame(func(client *Client, frame Frame) {
+		handled <- frame
+	})
+	_, srv := startTestHub(t, m)
+
+	conn := dial(t, srv, "1")
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"message","conversation_id":4,"data":{}}`))
+
+	msg := readUntil(t, conn, EventError)
+	data, _ := json.Marshal(msg.Data)
+	var got Error
+	json.Unmarshal(data, &got)
+	if got.Code != CodeMissingField || msg.ConversationID != 4 {
+		t.Errorf("error frame: got %+v in conversation %d want code %s in conversation 4", got, msg.ConversationID, CodeMissingField)
+	}
+
+	// The connection stays open for valid frames.
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"message","conversation_id":4,"data":{"content":"hi"}}`))
+	select {
+	case frame := <-handled:
+		if frame.ConversationID != 4 {
+			t.Errorf("handled frame: got conversation %d want 4", frame.ConversationID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("valid frame was not handled")
+	}
+}
+
+func TestLegacyUsernameValidation(t *testing.T) {
+	m := NewManager()
+	go m.Run()
+	srv := httptest.NewServer(http.HandlerFunc(m.HandleWebSocket))
+	t.Cleanup(srv.Close)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(Message{Type: "connect", Username: "a b"})
+	msg := readUntil(t, conn, EventError)
+	data, _ := json.Marshal(msg.Data)
+	var got Error
+	json.Unmarshal(data, &got)
+	if got.Code != CodeInvalidUsername {
+		t.Errorf("error code: got %q want %q", got.Code, CodeInvalidUsername)
+	}
+
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Errorf("close: got %v want policy violation", err)
+	}
+}
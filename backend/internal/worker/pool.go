@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Job is a unit of background work. The context is cancelled when the pool
+// is stopped.
+type Job func(ctx context.Context)
+
+// Pool runs jobs on a fixed number of goroutines fed by a bounded queue, so
+// request handlers can hand off slow work and return immediately.
+type Pool struct {
+	jobs   chan Job
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+func NewPool(workers, queueSize int) *Pool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		jobs:   make(chan Job, queueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.run()
+	}
+	return p
+}
+
+func (p *Pool) run() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		p.do(job)
+	}
+}
+
+func (p *Pool) do(job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("worker: job panicked: %v", r)
+		}
+	}()
+	job(p.ctx)
+}
+
+// Submit queues a job. It returns false without blocking if the queue is
+// full or the pool has been stopped.
+func (p *Pool) Submit(job Job) (ok bool) {
+	defer func() {
+		// Sending on the closed queue of a stopped pool panics.
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop stops accepting jobs, waits for queued jobs to finish and then
+// cancels the context passed to them.
+func (p *Pool) Stop() {
+	p.once.Do(func() {
+		close(p.jobs)
+		p.wg.Wait()
+		p.cancel()
+	})
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPool(t *testing.T) {
+	p := NewPool(4, 100)
+
+	var done int32
+	for i := 0; i < 50; i++ {
+		if !p.Submit(func(ctx context.Context) { atomic.AddInt32(&done, 1) }) {
+			t.Fatal("Submit rejected a job with room in the queue")
+		}
+	}
+	p.Submit(func(ctx context.Context) { panic("boom") })
+	p.Stop()
+
+	if done != 50 {
+		t.Errorf("unexpected number of jobs run: got %d want %d", done, 50)
+	}
+	if p.Submit(func(ctx context.Context) {}) {
+		t.Error("Submit accepted a job after Stop")
+	}
+}
+
+func TestPoolQueueFull(t *testing.T) {
+	p := NewPool(1, 1)
+	defer p.Stop()
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	p.Submit(func(ctx context.Context) {
+		close(started)
+		<-block
+	})
+	<-started
+
+	if !p.Submit(func(ctx context.Context) {}) {
+		t.Fatal("Submit rejected a job with room in the queue")
+	}
+	if p.Submit(func(ctx context.Context) {}) {
+		t.Error("Submit accepted a job with a full queue")
+	}
+	close(block)
+}